@@ -1,7 +1,9 @@
 package egorm
 
 import (
+	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,13 +14,13 @@ import (
 type (
 	// Cond 为字段查询结构体
 	Cond struct {
-		// Op MySQL中查询条件，如like,=,in
+		// Op MySQL中查询条件，如like,=,in,is null
 		Op string
 		// Val 查询条件对应的值
 		Val interface{}
 	}
 
-	// Conds 为Cond类型map，用于定义Where方法参数 map[field.name]interface{}
+	// Conds 为Cond类型map，用于定义Where方法参数 map[field.name]interface{}，按字段名排序后以 AND 连接
 	Conds map[string]interface{}
 
 	// Ups 为更新某一条记录时存放的变更数据集合 map[field.name]field.value
@@ -27,10 +29,13 @@ type (
 
 // assertCond 断言cond基本类型并返回Cond
 // 如果是基本类型，则Cond.Op为"="
+// 如果是nil，则Cond.Op为"is null"
 // 如果是切片类型，则Cond.Op为"in"。NOTICE: 不支持自定义类型切片，比如 type IDs []int
 func assertCond(cond interface{}) Cond {
 	// 先尝试断言为基本类型
 	switch v := cond.(type) {
+	case nil:
+		return Cond{"is null", nil}
 	case Cond:
 		return v
 	case string:
@@ -63,6 +68,8 @@ func assertCond(cond interface{}) Cond {
 		return Cond{"=", v}
 	case time.Duration:
 		return Cond{"=", v}
+	case time.Time:
+		return Cond{"=", v}
 	}
 
 	// 再尝试断言为stringSlice类型
@@ -82,54 +89,293 @@ func assertCond(cond interface{}) Cond {
 	return Cond{}
 }
 
-// BuildQuery 根据conds构建sql和绑定的参数
+// BuildQuery 根据conds构建sql和绑定的参数，字段名使用MySQL的反引号转义，其他数据库请使用 NewQuery(conds).Build(db)
 func BuildQuery(conds Conds) (sql string, binds []interface{}) {
-	sql = "1=1"
-	binds = make([]interface{}, 0, len(conds))
-	for field, cond := range conds {
-		condVal := assertCond(cond)
-
-		// 说明有表的数据
-		if strings.Contains(field, ".") {
-			arr := strings.Split(field, ".")
-			if len(arr) != 2 {
-				return
+	sql, binds = joinConds(backtickQuoter, "AND", conds.conditions())
+	if sql == "" {
+		return "1=1", binds
+	}
+	return "1=1 AND " + sql, binds
+}
+
+// quoter 按照数据库类型转义字段名
+type quoter func(field string) string
+
+// backtickQuoter MySQL、SQLite、ClickHouse 的字段名转义
+func backtickQuoter(field string) string {
+	return "`" + strings.Join(strings.Split(field, "."), "`.`") + "`"
+}
+
+// dialectQuoter 使用 db 的 Dialector 转义字段名
+func dialectQuoter(db *Component) quoter {
+	return func(field string) string {
+		var b strings.Builder
+		db.Dialector.QuoteTo(&b, field)
+		return b.String()
+	}
+}
+
+// Condition 查询条件，可以通过 And、Or 嵌套组合
+type Condition interface {
+	// build 返回条件的sql和绑定的参数，条件被忽略时sql为空
+	build(quote quoter) (string, []interface{})
+}
+
+func (c Conds) build(quote quoter) (string, []interface{}) {
+	return And(c.conditions()...).build(quote)
+}
+
+// conditions 按字段名排序，保证生成的sql顺序确定
+func (c Conds) conditions() []Condition {
+	fields := make([]string, 0, len(c))
+	for field := range c {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	conds := make([]Condition, 0, len(c))
+	for _, field := range fields {
+		cond := assertCond(c[field])
+		conds = append(conds, fieldCond{field: field, op: cond.Op, val: cond.Val})
+	}
+	return conds
+}
+
+type fieldCond struct {
+	field string
+	op    string
+	val   interface{}
+}
+
+func (c fieldCond) build(quote quoter) (string, []interface{}) {
+	field := quote(c.field)
+	switch op := strings.ToLower(c.op); op {
+	case "like", "%like", "like%":
+		// 空字符串不作为查询条件，便于处理搜索表单
+		val := cast.ToString(c.val)
+		if val == "" {
+			return "", nil
+		}
+		if op != "like%" {
+			val = "%" + val
+		}
+		if op != "%like" {
+			val += "%"
+		}
+		return field + " LIKE ?", []interface{}{val}
+	case "in", "not in":
+		return field + " " + strings.ToUpper(op) + " (?)", []interface{}{c.val}
+	case "between":
+		val := cast.ToSlice(c.val)
+		if len(val) != 2 {
+			val = make([]interface{}, 0, 2)
+			for _, v := range cast.ToStringSlice(c.val) {
+				val = append(val, v)
 			}
-			field = "`" + arr[0] + "`.`" + arr[1] + "`"
-		} else {
-			field = "`" + field + "`"
 		}
+		if len(val) != 2 {
+			log.Printf("[BuildQuery] between needs 2 values, field %s, %+v\n", c.field, c.val)
+			return "", nil
+		}
+		return field + " BETWEEN ? AND ?", val
+	case "is null", "is not null":
+		return field + " " + strings.ToUpper(op), nil
+	case "exp":
+		return field + " ?", []interface{}{gorm.Expr(cast.ToString(c.val))}
+	case "":
+		return "", nil
+	default:
+		return field + " " + c.op + " ?", []interface{}{c.val}
+	}
+}
 
-		switch strings.ToLower(condVal.Op) {
-		case "like":
-			if condVal.Val != "" {
-				sql += " AND " + field + " like ?"
-				condVal.Val = "%" + condVal.Val.(string) + "%"
-			}
-		case "%like":
-			if condVal.Val != "" {
-				sql += " AND " + field + " like ?"
-				condVal.Val = "%" + condVal.Val.(string)
-			}
-		case "like%":
-			if condVal.Val != "" {
-				sql += " AND " + field + " like ?"
-				condVal.Val = condVal.Val.(string) + "%"
-			}
-		case "in", "not in":
-			sql += " AND " + field + condVal.Op + " (?) "
-		case "between":
-			sql += " AND " + field + condVal.Op + " ? AND ?"
-			val := cast.ToStringSlice(condVal.Val)
-			binds = append(binds, val[0], val[1])
+type groupCond struct {
+	op    string
+	conds []Condition
+}
+
+func (c groupCond) build(quote quoter) (string, []interface{}) {
+	sqls, binds := buildConds(quote, c.conds)
+	if len(sqls) > 1 {
+		return "(" + strings.Join(sqls, " "+c.op+" ") + ")", binds
+	}
+	return strings.Join(sqls, ""), binds
+}
+
+// joinConds 连接非空的条件
+func joinConds(quote quoter, op string, conds []Condition) (string, []interface{}) {
+	sqls, binds := buildConds(quote, conds)
+	return strings.Join(sqls, " "+op+" "), binds
+}
+
+// buildConds 构建所有条件，忽略sql为空的条件
+func buildConds(quote quoter, conds []Condition) ([]string, []interface{}) {
+	sqls := make([]string, 0, len(conds))
+	binds := make([]interface{}, 0, len(conds))
+	for _, cond := range conds {
+		sql, condBinds := cond.build(quote)
+		if sql == "" {
 			continue
-		case "exp":
-			sql += " AND " + field + " ? "
-			condVal.Val = gorm.Expr(condVal.Val.(string))
-		default:
-			sql += " AND " + field + condVal.Op + " ? "
 		}
-		binds = append(binds, condVal.Val)
+		sqls = append(sqls, sql)
+		binds = append(binds, condBinds...)
+	}
+	return sqls, binds
+}
+
+// And 所有条件都满足
+func And(conds ...Condition) Condition {
+	return groupCond{op: "AND", conds: conds}
+}
+
+// Or 任意一个条件满足
+func Or(conds ...Condition) Condition {
+	return groupCond{op: "OR", conds: conds}
+}
+
+// Eq field = val
+func Eq(field string, val interface{}) Condition {
+	return fieldCond{field: field, op: "=", val: val}
+}
+
+// Ne field <> val
+func Ne(field string, val interface{}) Condition {
+	return fieldCond{field: field, op: "<>", val: val}
+}
+
+// Gt field > val
+func Gt(field string, val interface{}) Condition {
+	return fieldCond{field: field, op: ">", val: val}
+}
+
+// Gte field >= val
+func Gte(field string, val interface{}) Condition {
+	return fieldCond{field: field, op: ">=", val: val}
+}
+
+// Lt field < val
+func Lt(field string, val interface{}) Condition {
+	return fieldCond{field: field, op: "<", val: val}
+}
+
+// Lte field <= val
+func Lte(field string, val interface{}) Condition {
+	return fieldCond{field: field, op: "<=", val: val}
+}
+
+// Like field LIKE %val%，val 为空字符串时忽略该条件
+func Like(field string, val string) Condition {
+	return fieldCond{field: field, op: "like", val: val}
+}
+
+// In field IN (vals)
+func In(field string, vals interface{}) Condition {
+	return fieldCond{field: field, op: "in", val: vals}
+}
+
+// NotIn field NOT IN (vals)
+func NotIn(field string, vals interface{}) Condition {
+	return fieldCond{field: field, op: "not in", val: vals}
+}
+
+// Between field BETWEEN from AND to
+func Between(field string, from, to interface{}) Condition {
+	return fieldCond{field: field, op: "between", val: []interface{}{from, to}}
+}
+
+// IsNull field IS NULL
+func IsNull(field string) Condition {
+	return fieldCond{field: field, op: "is null"}
+}
+
+// IsNotNull field IS NOT NULL
+func IsNotNull(field string) Condition {
+	return fieldCond{field: field, op: "is not null"}
+}
+
+type order struct {
+	field string
+	desc  bool
+}
+
+// Query 查询构造器，字段名按照数据库类型转义，条件之间以 AND 连接
+type Query struct {
+	conds    []Condition
+	orders   []order
+	page     int
+	pageSize int
+}
+
+// NewQuery 创建查询
+func NewQuery(conds ...Condition) *Query {
+	return &Query{conds: conds}
+}
+
+// Where 追加查询条件
+func (q *Query) Where(conds ...Condition) *Query {
+	q.conds = append(q.conds, conds...)
+	return q
+}
+
+// OrderBy 按字段升序排序，多次调用时按调用顺序排序
+func (q *Query) OrderBy(field string) *Query {
+	q.orders = append(q.orders, order{field: field})
+	return q
+}
+
+// OrderByDesc 按字段降序排序
+func (q *Query) OrderByDesc(field string) *Query {
+	q.orders = append(q.orders, order{field: field, desc: true})
+	return q
+}
+
+// Page 分页，page 从 1 开始，pageSize 小于等于 0 时不分页
+func (q *Query) Page(page, pageSize int) *Query {
+	if page < 1 {
+		page = 1
+	}
+	q.page = page
+	q.pageSize = pageSize
+	return q
+}
+
+// Build 根据 db 的数据库类型构建查询条件的sql和绑定的参数，没有条件时sql为空
+func (q *Query) Build(db *Component) (sql string, binds []interface{}) {
+	return joinConds(dialectQuoter(db), "AND", q.conds)
+}
+
+// Scope 添加查询条件、排序以及分页，用于 db.Scopes(query.Scope)
+func (q *Query) Scope(db *Component) *Component {
+	db = q.where(db)
+	quote := dialectQuoter(db)
+	for _, o := range q.orders {
+		if o.desc {
+			db = db.Order(quote(o.field) + " DESC")
+		} else {
+			db = db.Order(quote(o.field))
+		}
+	}
+	if q.pageSize > 0 {
+		db = db.Offset((q.page - 1) * q.pageSize).Limit(q.pageSize)
+	}
+	return db
+}
+
+// Paginate 查询满足条件的总数以及当前页的数据，dest 为结构体切片的指针
+func (q *Query) Paginate(db *Component, dest interface{}) (total int64, err error) {
+	if err = q.where(db.Model(dest)).Count(&total).Error; err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+	if total == 0 || (q.pageSize > 0 && int64((q.page-1)*q.pageSize) >= total) {
+		return total, nil
+	}
+	return total, db.Scopes(q.Scope).Find(dest).Error
+}
+
+func (q *Query) where(db *Component) *Component {
+	sql, binds := q.Build(db)
+	if sql == "" {
+		return db
 	}
-	return
+	return db.Where(sql, binds...)
 }
@@ -0,0 +1,87 @@
+package egorm
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+)
+
+func TestBuildQuery(t *testing.T) {
+	sql, binds := BuildQuery(Conds{
+		"name":         Cond{Op: "like", Val: "ego"},
+		"id":           []int{1, 2},
+		"deleted_at":   nil,
+		"user.age":     Cond{Op: ">", Val: 18},
+		"nickname":     Cond{Op: "like", Val: ""},
+		"created_time": Cond{Op: "between", Val: []string{"1", "2"}},
+	})
+	assert.Equal(t, "1=1 AND `created_time` BETWEEN ? AND ? AND `deleted_at` IS NULL AND `id` IN (?) AND `name` LIKE ? AND `user`.`age` > ?", sql)
+	assert.Equal(t, []interface{}{"1", "2", []int{1, 2}, "%ego%", 18}, binds)
+
+	sql, binds = BuildQuery(Conds{})
+	assert.Equal(t, "1=1", sql)
+	assert.Empty(t, binds)
+}
+
+func TestQueryBuild(t *testing.T) {
+	query := NewQuery(
+		Conds{"status": 1, "type": "a"},
+		Or(IsNull("deleted_at"), Gt("user.deleted_at", 100), And(Lte("age", 10), Like("name", ""))),
+		NotIn("id", []int{1}),
+	)
+	sql, binds := query.Build(&gorm.DB{Config: &gorm.Config{Dialector: mysql.Dialector{}}})
+	assert.Equal(t, "(`status` = ? AND `type` = ?) AND (`deleted_at` IS NULL OR `user`.`deleted_at` > ? OR `age` <= ?) AND `id` NOT IN (?)", sql)
+	assert.Equal(t, []interface{}{1, "a", 100, 10, []int{1}}, binds)
+
+	sql, _ = query.Build(&gorm.DB{Config: &gorm.Config{Dialector: postgres.Dialector{}}})
+	assert.Equal(t, `("status" = ? AND "type" = ?) AND ("deleted_at" IS NULL OR "user"."deleted_at" > ? OR "age" <= ?) AND "id" NOT IN (?)`, sql)
+
+	sql, _ = query.Build(&gorm.DB{Config: &gorm.Config{Dialector: sqlserver.Dialector{}}})
+	assert.Equal(t, `("status" = ? AND "type" = ?) AND ("deleted_at" IS NULL OR "user"."deleted_at" > ? OR "age" <= ?) AND "id" NOT IN (?)`, sql)
+
+	sql, binds = NewQuery(Like("name", "")).Build(&gorm.DB{Config: &gorm.Config{Dialector: mysql.Dialector{}}})
+	assert.Empty(t, sql)
+	assert.Empty(t, binds)
+}
+
+type queryUser struct {
+	ID       int
+	Name     string
+	Nickname *string
+}
+
+func TestQueryPaginate(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:paginate?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&queryUser{}))
+	nickname := "ego"
+	require.NoError(t, db.Create([]queryUser{
+		{Name: "a"}, {Name: "b", Nickname: &nickname}, {Name: "c"}, {Name: "d"}, {Name: "e", Nickname: &nickname},
+	}).Error)
+
+	var users []queryUser
+	total, err := NewQuery(Or(IsNull("nickname"), Eq("name", "e"))).OrderByDesc("id").Page(2, 2).Paginate(db, &users)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	require.Len(t, users, 2)
+	assert.Equal(t, "c", users[0].Name)
+	assert.Equal(t, "a", users[1].Name)
+
+	// 超出总数的页
+	users = nil
+	total, err = NewQuery(IsNotNull("nickname")).OrderBy("id").Page(2, 2).Paginate(db, &users)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Empty(t, users)
+
+	users = nil
+	require.NoError(t, db.Scopes(NewQuery(In("name", []string{"a", "b", "c"})).OrderByDesc("name").Page(1, 2).Scope).Find(&users).Error)
+	require.Len(t, users, 2)
+	assert.Equal(t, "c", users[0].Name)
+}
@@ -2,6 +2,10 @@ package egorm
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
 
 	"github.com/gotomicro/ego-component/egorm/manager"
 	"github.com/gotomicro/ego/core/elog"
@@ -46,12 +50,46 @@ func WithContext(ctx context.Context, db *Component) *Component {
 	return db
 }
 
+// Close 关闭主库以及从库的连接池
+func Close(db *Component) error {
+	var errs []string
+	for _, plugin := range db.Config.Plugins {
+		if closer, ok := plugin.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = sqlDB.Close()
+	}
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
 // newComponent ...
-func newComponent(compName string, dsnParser manager.DSNParser, config *config, elogger *elog.Component) (*Component, error) {
+func newComponent(compName string, dsnParser manager.DSNParser, config *config, elogger *elog.Component) (_ *Component, err error) {
 	db, err := gorm.Open(dsnParser.GetDialector(config.DSN), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
+	// 初始化失败时关闭已经打开的连接池
+	defer func() {
+		if err != nil {
+			_ = Close(db)
+		}
+	}()
+
+	// 事务的开启、提交、回滚经过拦截器
+	if _, err := newTxPool(db, compName, config.dsnCfg, config, elogger); err != nil {
+		return nil, err
+	}
 
 	if config.RawDebug {
 		db = db.Debug()
@@ -62,13 +100,7 @@ func newComponent(compName string, dsnParser manager.DSNParser, config *config,
 		return nil, err
 	}
 
-	// 设置默认连接配置
-	gormDB.SetMaxIdleConns(config.MaxIdleConns)
-	gormDB.SetMaxOpenConns(config.MaxOpenConns)
-
-	if config.ConnMaxLifetime != 0 {
-		gormDB.SetConnMaxLifetime(config.ConnMaxLifetime)
-	}
+	setConnPool(gormDB, config)
 
 	replace := func(processor Processor, callbackName string, interceptors ...Interceptor) {
 		handler := processor.Get(callbackName)
@@ -83,7 +115,39 @@ func newComponent(compName string, dsnParser manager.DSNParser, config *config,
 	replace(db.Callback().Update(), "gorm:update", config.interceptors...)
 	replace(db.Callback().Delete(), "gorm:delete", config.interceptors...)
 	replace(db.Callback().Query(), "gorm:query", config.interceptors...)
-	// replace(db.Callback().Row(), "gorm:row", config.interceptors...)
+	replace(db.Callback().Row(), "gorm:row", config.interceptors...)
 	replace(db.Callback().Raw(), "gorm:raw", config.interceptors...)
+
+	if len(config.Replicas) > 0 {
+		r, err := newResolver(db, dsnParser, config)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.Use(r); err != nil {
+			_ = r.Close()
+			return nil, err
+		}
+	}
+
+	if len(config.ShardingTables) > 0 {
+		s, err := newSharding(db, compName, dsnParser, config, elogger)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.Use(s); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+	}
 	return db, nil
 }
+
+// setConnPool 设置默认连接配置
+func setConnPool(sqlDB *sql.DB, config *config) {
+	sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+
+	if config.ConnMaxLifetime != 0 {
+		sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+}
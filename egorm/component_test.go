@@ -0,0 +1,43 @@
+package egorm
+
+import (
+	"testing"
+
+	"github.com/gotomicro/ego-component/egorm/manager"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type componentUser struct {
+	ID   int
+	Name string
+}
+
+func TestSqliteComponent(t *testing.T) {
+	var ops []string
+	recorder := func(compName string, dsn *manager.DSN, op string, config *config, logger *elog.Component) func(Handler) Handler {
+		return func(next Handler) Handler {
+			return func(db *Component) {
+				next(db)
+				ops = append(ops, op+" "+dsn.DBName+"."+db.Statement.Table)
+			}
+		}
+	}
+
+	// 内存数据库的每个连接都是独立的数据库，使用 cache=shared 共享
+	c := DefaultContainer()
+	c.config.Dialect = "sqlite"
+	c.config.EnableAccessInterceptor = true
+	db := c.Build(WithDSN("file:component?mode=memory&cache=shared"), WithInterceptor(recorder))
+
+	require.NoError(t, db.AutoMigrate(&componentUser{}))
+	require.NoError(t, db.Create(&componentUser{Name: "ego"}).Error)
+	var users []componentUser
+	require.NoError(t, db.Where("name = ?", "ego").Find(&users).Error)
+	assert.Equal(t, []componentUser{{ID: 1, Name: "ego"}}, users)
+	assert.ErrorIs(t, db.Where("name = ?", "gorm").First(&componentUser{}).Error, ErrRecordNotFound)
+
+	assert.Contains(t, ops, "gorm:create component.component_users")
+	assert.Contains(t, ops, "gorm:query component.component_users")
+}
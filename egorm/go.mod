@@ -4,13 +4,16 @@ go 1.16
 
 require (
 	github.com/davecgh/go-spew v1.1.1
+	github.com/glebarez/sqlite v1.3.5
 	github.com/gotomicro/ego v1.0.0
 	github.com/json-iterator/go v1.1.12
 	github.com/spf13/cast v1.3.1
 	github.com/stretchr/testify v1.7.0
 	go.opentelemetry.io/otel v1.4.1
+	go.opentelemetry.io/otel/sdk v1.4.1
 	go.opentelemetry.io/otel/trace v1.4.1
 	google.golang.org/grpc v1.44.0
+	gorm.io/driver/clickhouse v0.2.2
 	gorm.io/driver/mysql v1.2.3
 	gorm.io/driver/postgres v1.2.3
 	gorm.io/driver/sqlserver v1.2.1
@@ -46,13 +46,14 @@ func debugInterceptor(compName string, dsn *manager.DSN, op string, options *con
 			beg := time.Now()
 			next(db)
 			cost := time.Since(beg)
+			_, node := nodeOf(db, dsn)
 			if db.Error != nil {
 				log.Println("[egorm.response]",
-					xdebug.MakeReqResError(compName, fmt.Sprintf("%v", dsn.Addr+"/"+dsn.DBName), cost, logSQL(db, true), db.Error.Error()),
+					xdebug.MakeReqResError(compName, fmt.Sprintf("%v", node.Addr+"/"+node.DBName), cost, logSQL(db, true), db.Error.Error()),
 				)
 			} else {
 				log.Println("[egorm.response]",
-					xdebug.MakeReqResInfo(compName, fmt.Sprintf("%v", dsn.Addr+"/"+dsn.DBName), cost, logSQL(db, true), fmt.Sprintf("%v", db.Statement.Dest)),
+					xdebug.MakeReqResInfo(compName, fmt.Sprintf("%v", node.Addr+"/"+node.DBName), cost, logSQL(db, true), fmt.Sprintf("%v", db.Statement.Dest)),
 				)
 			}
 
@@ -66,13 +67,18 @@ func metricInterceptor(compName string, dsn *manager.DSN, op string, config *con
 			beg := time.Now()
 			next(db)
 			cost := time.Since(beg)
+			nodeName, node := nodeOf(db, dsn)
 
 			loggerKeys := transport.CustomContextKeys()
 
 			var fields = make([]elog.Field, 0, 15+len(loggerKeys))
 			fields = append(fields,
 				elog.FieldMethod(op),
-				elog.FieldName(dsn.DBName+"."+db.Statement.Table), elog.FieldCost(cost))
+				elog.FieldName(node.DBName+"."+db.Statement.Table), elog.FieldCost(cost))
+			// 配置了从库或者分库时，记录执行 SQL 的节点
+			if len(config.Replicas) > 0 || len(config.Shards) > 0 {
+				fields = append(fields, elog.String("node", nodeName), elog.String("nodeAddr", node.Addr))
+			}
 			if config.EnableAccessInterceptorReq {
 				fields = append(fields, elog.String("req", logSQL(db, config.EnableDetailSQL)))
 			}
@@ -93,7 +99,7 @@ func metricInterceptor(compName string, dsn *manager.DSN, op string, config *con
 			}
 
 			// 记录监控耗时
-			emetric.ClientHandleHistogram.WithLabelValues(emetric.TypeGorm, compName, dsn.DBName+"."+db.Statement.Table, dsn.Addr).Observe(cost.Seconds())
+			emetric.ClientHandleHistogram.WithLabelValues(emetric.TypeGorm, compName, node.DBName+"."+db.Statement.Table, node.Addr).Observe(cost.Seconds())
 
 			// 如果有慢日志，就记录
 			if config.SlowLogThreshold > time.Duration(0) && config.SlowLogThreshold < cost {
@@ -105,15 +111,15 @@ func metricInterceptor(compName string, dsn *manager.DSN, op string, config *con
 				fields = append(fields, elog.FieldEvent("error"), elog.FieldErr(db.Error))
 				if errors.Is(db.Error, ErrRecordNotFound) {
 					logger.Warn("access", fields...)
-					emetric.ClientHandleCounter.Inc(emetric.TypeGorm, compName, dsn.DBName+"."+db.Statement.Table, dsn.Addr, "Empty")
+					emetric.ClientHandleCounter.Inc(emetric.TypeGorm, compName, node.DBName+"."+db.Statement.Table, node.Addr, "Empty")
 					return
 				}
 				logger.Error("access", fields...)
-				emetric.ClientHandleCounter.Inc(emetric.TypeGorm, compName, dsn.DBName+"."+db.Statement.Table, dsn.Addr, "Error")
+				emetric.ClientHandleCounter.Inc(emetric.TypeGorm, compName, node.DBName+"."+db.Statement.Table, node.Addr, "Error")
 				return
 			}
 
-			emetric.ClientHandleCounter.Inc(emetric.TypeGorm, compName, dsn.DBName+"."+db.Statement.Table, dsn.Addr, "OK")
+			emetric.ClientHandleCounter.Inc(emetric.TypeGorm, compName, node.DBName+"."+db.Statement.Table, node.Addr, "OK")
 			// 开启了记录日志信息，那么就记录access
 			// event normal和error，代表全部access的请求数
 			if config.EnableAccessInterceptor {
@@ -134,13 +140,6 @@ func logSQL(db *gorm.DB, enableDetailSQL bool) string {
 }
 
 func traceInterceptor(compName string, dsn *manager.DSN, op string, options *config, logger *elog.Component) func(Handler) Handler {
-	ip, port := peerInfo(dsn.Addr)
-	attrs := []attribute.KeyValue{
-		semconv.NetHostIPKey.String(ip),
-		semconv.NetPeerPortKey.Int(port),
-		semconv.NetTransportKey.String(dsn.Net),
-		semconv.DBNameKey.String(dsn.DBName),
-	}
 	tracer := etrace.NewTracer(trace.SpanKindClient)
 	return func(next Handler) Handler {
 		return func(db *gorm.DB) {
@@ -148,10 +147,56 @@ func traceInterceptor(compName string, dsn *manager.DSN, op string, options *con
 				operation := "gorm:"
 				if len(db.Statement.BuildClauses) > 0 {
 					operation += strings.ToLower(db.Statement.BuildClauses[0])
+				} else {
+					// row、raw 以及事务操作没有 BuildClauses
+					operation = op
+				}
+
+				// 执行 SQL 的节点在拦截器之前已经确定
+				nodeName, node := nodeOf(db, dsn)
+				ip, port := peerInfo(node.Addr)
+				attrs := []attribute.KeyValue{
+					semconv.NetHostIPKey.String(ip),
+					semconv.NetPeerPortKey.Int(port),
+					semconv.NetTransportKey.String(node.Net),
+					semconv.DBNameKey.String(node.DBName),
+				}
+				if len(options.Replicas) > 0 || len(options.Shards) > 0 {
+					attrs = append(attrs, attribute.String("db.node", nodeName))
 				}
 
-				_, span := tracer.Start(db.Statement.Context, operation, nil, trace.WithAttributes(attrs...))
+				// 开启事务时创建事务的 span，事务中的 SQL 以及提交、回滚作为它的子 span
+				if op == opBegin {
+					ctx, span := tracer.Start(db.Statement.Context, "gorm:transaction", nil, trace.WithAttributes(attrs...))
+					next(db)
+					if db.Error != nil {
+						span.RecordError(db.Error)
+						span.SetStatus(codes.Error, db.Error.Error())
+						span.End()
+						return
+					}
+					tx, ok := db.Statement.ConnPool.(*txConn)
+					if !ok {
+						span.End()
+						return
+					}
+					tx.ctx = ctx
+					tx.watchSpan(db.Statement.Context)
+					return
+				}
+
+				ctx := db.Statement.Context
+				tx, isTx := db.Statement.ConnPool.(*txConn)
+				if isTx {
+					ctx = tx.ctx
+				}
+				_, span := tracer.Start(ctx, operation, nil, trace.WithAttributes(attrs...))
 				defer span.End()
+				if isTx && (op == opCommit || op == opRollback) {
+					defer func() {
+						tx.endSpan(op, db.Error)
+					}()
+				}
 				// 延迟执行 scope.CombinedConditionSql() 避免sqlVar被重复追加
 				next(db)
 				span.SetAttributes(
@@ -159,7 +204,7 @@ func traceInterceptor(compName string, dsn *manager.DSN, op string, options *con
 					semconv.DBStatementKey.String(logSQL(db, options.EnableDetailSQL)),
 					semconv.DBOperationKey.String(operation),
 					semconv.DBSQLTableKey.String(db.Statement.Table),
-					semconv.NetPeerNameKey.String(dsn.Addr),
+					semconv.NetPeerNameKey.String(node.Addr),
 					attribute.Int64("db.rows_affected", db.RowsAffected),
 				)
 				if db.Error != nil {
@@ -0,0 +1,67 @@
+package dsn
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gotomicro/ego-component/egorm/manager"
+	"gorm.io/gorm"
+)
+
+const sqliteMemory = ":memory:"
+
+var (
+	_ manager.DSNParser = (*SqliteDSNParser)(nil)
+)
+
+// SqliteDSNParser 纯 Go 实现的 SQLite，不依赖 cgo，可用于单元测试中的内存数据库
+type SqliteDSNParser struct {
+}
+
+func init() {
+	manager.Register(&SqliteDSNParser{})
+}
+
+func (p *SqliteDSNParser) Scheme() string {
+	return "sqlite"
+}
+
+func (p *SqliteDSNParser) GetDialector(dsn string) gorm.Dialector {
+	return sqlite.Open(dsn)
+}
+
+// ParseDSN 解析 test.db、file:test.db?_pragma=busy_timeout(5000)、:memory:、file:test?mode=memory&cache=shared 等格式的 DSN，
+// Addr 为数据库文件路径，内存数据库为 :memory:，DBName 为不带扩展名的文件名，匿名的内存数据库为 memory
+func (p *SqliteDSNParser) ParseDSN(dsn string) (cfg *manager.DSN, err error) {
+	cfg = &manager.DSN{Net: "file"}
+	path := strings.TrimPrefix(dsn, "file:")
+	if idx := strings.IndexByte(path, '?'); idx >= 0 {
+		query, err := url.ParseQuery(path[idx+1:])
+		if err != nil {
+			return nil, err
+		}
+		for key := range query {
+			// lazy init
+			if cfg.Params == nil {
+				cfg.Params = make(map[string]string)
+			}
+			cfg.Params[key] = query.Get(key)
+		}
+		path = path[:idx]
+	}
+
+	if path == "" || path == sqliteMemory {
+		cfg.Addr = sqliteMemory
+		cfg.DBName = "memory"
+		return cfg, nil
+	}
+	cfg.Addr = path
+	// 命名的内存数据库 file:name?mode=memory&cache=shared
+	if cfg.Params["mode"] == "memory" {
+		cfg.Addr = sqliteMemory
+	}
+	cfg.DBName = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return cfg, nil
+}
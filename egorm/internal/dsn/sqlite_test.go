@@ -0,0 +1,29 @@
+package dsn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqliteDsnParser_ParseDSN(t *testing.T) {
+	dsnParser := SqliteDSNParser{}
+	cfg, err := dsnParser.ParseDSN("file:/data/ego.db?_pragma=busy_timeout(5000)")
+	assert.NoError(t, err)
+	assert.Equal(t, "file", cfg.Net)
+	assert.Equal(t, "/data/ego.db", cfg.Addr)
+	assert.Equal(t, "ego", cfg.DBName)
+	assert.Equal(t, "busy_timeout(5000)", cfg.Params["_pragma"])
+
+	for _, dsn := range []string{":memory:", "file::memory:?cache=shared"} {
+		cfg, err = dsnParser.ParseDSN(dsn)
+		assert.NoError(t, err)
+		assert.Equal(t, ":memory:", cfg.Addr, dsn)
+		assert.Equal(t, "memory", cfg.DBName, dsn)
+	}
+
+	cfg, err = dsnParser.ParseDSN("file:ego?mode=memory&cache=shared")
+	assert.NoError(t, err)
+	assert.Equal(t, ":memory:", cfg.Addr)
+	assert.Equal(t, "ego", cfg.DBName)
+}
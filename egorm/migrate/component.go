@@ -0,0 +1,235 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/task/ejob"
+	"gorm.io/gorm"
+)
+
+// PackageName 包名
+const PackageName = "component.egorm.migrate"
+
+const (
+	directionUp   = "up"
+	directionDown = "down"
+)
+
+var (
+	// ErrLockTimeout 等待其他实例释放迁移锁超时
+	ErrLockTimeout = errors.New("migrate: lock timeout")
+
+	errNoComponent = errors.New("egorm component is nil, use WithComponent")
+)
+
+// record 已执行的版本
+type record struct {
+	Version   uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	AppliedAt time.Time
+}
+
+// Status 迁移的执行状态
+type Status struct {
+	*Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Component 迁移组件
+type Component struct {
+	name   string
+	config *config
+	logger *elog.Component
+}
+
+func newComponent(name string, config *config, logger *elog.Component) *Component {
+	return &Component{
+		name:   name,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Up 按版本升序执行所有未执行的迁移
+func (c *Component) Up(ctx context.Context) error {
+	return c.run(ctx, directionUp, func(migrations []*Migration, applied map[uint64]record) []*Migration {
+		var plan []*Migration
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; !ok {
+				plan = append(plan, m)
+			}
+		}
+		return plan
+	})
+}
+
+// Down 按版本降序回滚最近执行的 steps 个迁移
+func (c *Component) Down(ctx context.Context, steps int) error {
+	return c.run(ctx, directionDown, func(migrations []*Migration, applied map[uint64]record) []*Migration {
+		var plan []*Migration
+		for i := len(migrations) - 1; i >= 0 && len(plan) < steps; i-- {
+			if _, ok := applied[migrations[i].Version]; ok {
+				plan = append(plan, migrations[i])
+			}
+		}
+		return plan
+	})
+}
+
+// Status 返回所有迁移的执行状态
+func (c *Component) Status(ctx context.Context) ([]Status, error) {
+	migrations, err := loadMigrations(c.config.fsys, c.config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := c.applied(c.session(ctx))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		r, ok := applied[m.Version]
+		list = append(list, Status{Migration: m, Applied: ok, AppliedAt: r.AppliedAt})
+	}
+	return list, nil
+}
+
+// Job 返回执行 Up 的任务，通过 ego.New().Job(m.Job("migrate")) 注册，启动时使用 --job=migrate 执行迁移
+func (c *Component) Job(name string) *ejob.Component {
+	return ejob.Job(name, func(ctx ejob.Context) error {
+		return c.Up(ctx.Ctx)
+	})
+}
+
+// run 在同一个连接上加锁、读取已执行的版本并执行迁移
+func (c *Component) run(ctx context.Context, direction string, plan func([]*Migration, map[uint64]record) []*Migration) error {
+	migrations, err := loadMigrations(c.config.fsys, c.config.Dir)
+	if err != nil {
+		return err
+	}
+
+	// dry run 不加锁，也不创建版本表
+	if c.config.DryRun {
+		applied, err := c.applied(c.session(ctx))
+		if err != nil {
+			return err
+		}
+		return c.print(direction, plan(migrations, applied))
+	}
+
+	sqlDB, err := c.config.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get conn: %w", err)
+	}
+	defer conn.Close()
+	db := c.session(ctx)
+	db.Statement.ConnPool = conn
+
+	unlock, err := c.lock(ctx, db)
+	if err != nil {
+		return fmt.Errorf("lock: %w", err)
+	}
+	defer unlock()
+
+	if err := db.Table(c.config.Table).AutoMigrate(&record{}); err != nil {
+		return fmt.Errorf("create table %s: %w", c.config.Table, err)
+	}
+	applied, err := c.applied(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range plan(migrations, applied) {
+		beg := time.Now()
+		fields := []elog.Field{elog.FieldMethod(direction), elog.FieldName(fmt.Sprintf("%d_%s", m.Version, m.Name))}
+		if err := c.migrate(db, direction, m); err != nil {
+			c.logger.Error("migrate", append(fields, elog.FieldErr(err), elog.FieldCost(time.Since(beg)))...)
+			return fmt.Errorf("migrate %s %d_%s: %w", direction, m.Version, m.Name, err)
+		}
+		c.logger.Info("migrate", append(fields, elog.FieldCost(time.Since(beg)))...)
+	}
+	return nil
+}
+
+// migrate 在事务中执行迁移的 SQL 并记录版本，MySQL 的 DDL 会隐式提交，失败时需要手动处理
+func (c *Component) migrate(db *gorm.DB, direction string, m *Migration) error {
+	sql := m.Up
+	if direction == directionDown {
+		if strings.TrimSpace(m.Down) == "" {
+			return errors.New("no down sql")
+		}
+		sql = m.Down
+	}
+
+	exec := func(tx *gorm.DB) error {
+		for _, statement := range splitStatements(sql) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		if direction == directionUp {
+			return tx.Table(c.config.Table).Create(&record{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		}
+		return tx.Table(c.config.Table).Where("version = ?", m.Version).Delete(&record{}).Error
+	}
+	// clickhouse 不支持事务
+	if db.Dialector.Name() == "clickhouse" {
+		return exec(db)
+	}
+	return db.Transaction(exec)
+}
+
+// applied 返回已执行的版本，版本表不存在时为空
+func (c *Component) applied(db *gorm.DB) (map[uint64]record, error) {
+	applied := make(map[uint64]record)
+	if !db.Migrator().HasTable(c.config.Table) {
+		return applied, nil
+	}
+	var records []record
+	if err := db.Table(c.config.Table).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("read table %s: %w", c.config.Table, err)
+	}
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// print dry run 时输出将要执行的 SQL
+func (c *Component) print(direction string, plan []*Migration) error {
+	for _, m := range plan {
+		sql := m.Up
+		if direction == directionDown {
+			if strings.TrimSpace(m.Down) == "" {
+				return fmt.Errorf("migrate %s %d_%s: no down sql", direction, m.Version, m.Name)
+			}
+			sql = m.Down
+		}
+		if _, err := fmt.Fprintf(c.config.output, "-- %d_%s.%s.sql\n", m.Version, m.Name, direction); err != nil {
+			return err
+		}
+		for _, statement := range splitStatements(sql) {
+			if _, err := fmt.Fprintf(c.config.output, "%s;\n", statement); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(c.config.output); err != nil {
+			return err
+		}
+	}
+	c.logger.Info("dry run", elog.FieldMethod(direction), elog.Int("migrations", len(plan)))
+	return nil
+}
+
+func (c *Component) session(ctx context.Context) *gorm.DB {
+	return c.config.db.Session(&gorm.Session{NewDB: true, Context: ctx})
+}
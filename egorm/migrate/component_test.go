@@ -0,0 +1,107 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego-component/egorm"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T, name string) *egorm.Component {
+	// 内存数据库的每个连接都是独立的数据库，使用 cache=shared 共享
+	conf := `{"sqlite": {"dialect": "sqlite", "dsn": "file:` + name + `?mode=memory&cache=shared"}}`
+	require.NoError(t, econf.LoadFromReader(strings.NewReader(conf), json.Unmarshal))
+	return egorm.Load("sqlite").Build()
+}
+
+func TestUpDown(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, "updown")
+	m := DefaultContainer().Build(WithComponent(db), WithDir("testdata/migrations"))
+
+	require.NoError(t, m.Up(ctx))
+	assert.True(t, db.Migrator().HasColumn("users", "email"))
+	var names []string
+	require.NoError(t, db.Table("users").Pluck("name", &names).Error)
+	assert.Equal(t, []string{"ego"}, names)
+
+	// 已执行的版本不会重复执行
+	require.NoError(t, m.Up(ctx))
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, 2)
+	assert.Equal(t, uint64(2), status[1].Version)
+	assert.Equal(t, "add_email", status[1].Name)
+	assert.True(t, status[1].Applied)
+
+	require.NoError(t, m.Down(ctx, 1))
+	assert.False(t, db.Migrator().HasColumn("users", "email"))
+	status, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, status[0].Applied)
+	assert.False(t, status[1].Applied)
+
+	require.NoError(t, m.Down(ctx, 2))
+	assert.False(t, db.Migrator().HasTable("users"))
+}
+
+func TestFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, "failed")
+	fsys := testFS{
+		"migrations/1_create_users.up.sql": "CREATE TABLE users (id INTEGER PRIMARY KEY);",
+		"migrations/2_broken.up.sql":       "INSERT INTO users (id) VALUES (1); INSERT INTO unknown (id) VALUES (1);",
+	}.MapFS()
+	m := DefaultContainer().Build(WithComponent(db), WithFS(fsys, "migrations"))
+
+	assert.Error(t, m.Up(ctx))
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, status[0].Applied)
+	assert.False(t, status[1].Applied)
+
+	// 失败的迁移在事务中回滚
+	var count int64
+	require.NoError(t, db.Table("users").Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, "dryrun")
+	var output bytes.Buffer
+	m := DefaultContainer().Build(WithComponent(db), WithDir("testdata/migrations"), WithDryRun(&output))
+
+	require.NoError(t, m.Up(ctx))
+	assert.Contains(t, output.String(), "-- 1_create_users.up.sql\nCREATE TABLE users")
+	assert.Contains(t, output.String(), "-- 2_add_email.up.sql\nALTER TABLE users ADD COLUMN email VARCHAR(128);\nCREATE INDEX")
+	assert.False(t, db.Migrator().HasTable("users"))
+	assert.False(t, db.Migrator().HasTable("schema_migrations"))
+}
+
+func TestLockTimeout(t *testing.T) {
+	lockers["sqlite"] = locker{
+		tryLock: func(db *gorm.DB, key string) (bool, error) {
+			return false, nil
+		},
+		unlock: func(db *gorm.DB, key string) error {
+			return nil
+		},
+	}
+	defer delete(lockers, "sqlite")
+
+	db := newTestDB(t, "lock")
+	c := DefaultContainer()
+	c.config.LockTimeout = 200 * time.Millisecond
+	m := c.Build(WithComponent(db), WithDir("testdata/migrations"))
+	assert.ErrorIs(t, m.Up(context.Background()), ErrLockTimeout)
+	assert.False(t, db.Migrator().HasTable("users"))
+}
@@ -0,0 +1,29 @@
+package migrate
+
+import (
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/gotomicro/ego-component/egorm"
+	"github.com/gotomicro/ego/core/util/xtime"
+)
+
+type config struct {
+	Dir         string        // 迁移文件所在目录，使用 WithFS 时为 fs 中的目录，默认 migrations
+	Table       string        // 记录已执行版本的表，默认 schema_migrations
+	LockTimeout time.Duration // 等待其他实例释放迁移锁的最长时间，默认 1m
+	DryRun      bool          // 只输出将要执行的 SQL，不执行，也不记录版本
+	fsys        fs.FS
+	output      io.Writer
+	db          *egorm.Component
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *config {
+	return &config{
+		Dir:         "migrations",
+		Table:       "schema_migrations",
+		LockTimeout: xtime.Duration("1m"),
+	}
+}
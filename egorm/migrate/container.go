@@ -0,0 +1,54 @@
+package migrate
+
+import (
+	"os"
+
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/gotomicro/ego/core/elog"
+)
+
+// Container 容器
+type Container struct {
+	name   string
+	config *config
+	logger *elog.Component
+}
+
+// DefaultContainer 返回默认Container
+func DefaultContainer() *Container {
+	return &Container{
+		config: DefaultConfig(),
+		logger: elog.EgoLogger.With(elog.FieldComponent(PackageName)),
+	}
+}
+
+// Load 载入配置，初始化Container
+func Load(key string) *Container {
+	c := DefaultContainer()
+	if err := econf.UnmarshalKey(key, &c.config); err != nil {
+		c.logger.Panic("parse config error", elog.FieldErr(err), elog.FieldKey(key))
+		return c
+	}
+
+	c.logger = c.logger.With(elog.FieldComponentName(key))
+	c.name = key
+	return c
+}
+
+// Build 构建组件
+func (c *Container) Build(options ...Option) *Component {
+	for _, option := range options {
+		option(c)
+	}
+	if c.config.db == nil {
+		c.logger.Panic("build migrate", elog.FieldErr(errNoComponent))
+	}
+	if c.config.fsys == nil {
+		c.config.fsys = os.DirFS(c.config.Dir)
+		c.config.Dir = "."
+	}
+	if c.config.output == nil {
+		c.config.output = os.Stdout
+	}
+	return newComponent(c.name, c.config, c.logger)
+}
@@ -0,0 +1,95 @@
+package migrate
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"gorm.io/gorm"
+)
+
+// lockRetryInterval 获取锁失败后重试的间隔
+const lockRetryInterval = 100 * time.Millisecond
+
+// locker 数据库级别的锁，锁与连接绑定，加锁、迁移、解锁必须使用同一个连接
+type locker struct {
+	tryLock func(db *gorm.DB, key string) (bool, error)
+	unlock  func(db *gorm.DB, key string) error
+}
+
+// lockers 按 Dialector 名称注册的锁。sqlite 与 clickhouse 没有会话级别的锁，迁移时不加锁
+var lockers = map[string]locker{
+	"mysql": {
+		tryLock: func(db *gorm.DB, key string) (bool, error) {
+			var locked int
+			err := db.Raw("SELECT GET_LOCK(?, 0)", key).Scan(&locked).Error
+			return locked == 1, err
+		},
+		unlock: func(db *gorm.DB, key string) error {
+			var released int
+			return db.Raw("SELECT RELEASE_LOCK(?)", key).Scan(&released).Error
+		},
+	},
+	"postgres": {
+		tryLock: func(db *gorm.DB, key string) (bool, error) {
+			var locked bool
+			err := db.Raw("SELECT pg_try_advisory_lock(?)", advisoryLockID(key)).Scan(&locked).Error
+			return locked, err
+		},
+		unlock: func(db *gorm.DB, key string) error {
+			var released bool
+			return db.Raw("SELECT pg_advisory_unlock(?)", advisoryLockID(key)).Scan(&released).Error
+		},
+	},
+	"sqlserver": {
+		tryLock: func(db *gorm.DB, key string) (bool, error) {
+			var result int
+			err := db.Raw("DECLARE @result int; EXEC @result = sp_getapplock @Resource = ?, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = 0; SELECT @result", key).Scan(&result).Error
+			return result >= 0, err
+		},
+		unlock: func(db *gorm.DB, key string) error {
+			return db.Exec("EXEC sp_releaseapplock @Resource = ?, @LockOwner = 'Session'", key).Error
+		},
+	},
+}
+
+// advisoryLockID postgres 的 advisory lock 只支持整数
+func advisoryLockID(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// lock 获取迁移锁，其他实例持有锁时重试，直到超过 LockTimeout
+func (c *Component) lock(ctx context.Context, db *gorm.DB) (unlock func(), err error) {
+	l, ok := lockers[db.Dialector.Name()]
+	if !ok {
+		c.logger.Warn("database lock not supported", elog.String("dialect", db.Dialector.Name()))
+		return func() {}, nil
+	}
+
+	key := "egorm_migrate:" + c.config.Table
+	deadline := time.Now().Add(c.config.LockTimeout)
+	for {
+		locked, err := l.tryLock(db, key)
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			return func() {
+				if err := l.unlock(db, key); err != nil {
+					c.logger.Error("unlock", elog.FieldErr(err))
+				}
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrLockTimeout
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFS 文件名到内容
+type testFS map[string]string
+
+func (fsys testFS) MapFS() fstest.MapFS {
+	mapFS := fstest.MapFS{}
+	for name, content := range fsys {
+		mapFS[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return mapFS
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations(testFS{
+		"migrations/10_add_email.up.sql":     "ALTER TABLE users ADD COLUMN email VARCHAR(128);",
+		"migrations/2_create_users.up.sql":   "CREATE TABLE users (id INTEGER PRIMARY KEY);",
+		"migrations/2_create_users.down.sql": "DROP TABLE users;",
+		"migrations/README.md":               "",
+		"migrations/nested/1_ignored.up.sql": "",
+	}.MapFS(), "migrations")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, &Migration{Version: 2, Name: "create_users", Up: "CREATE TABLE users (id INTEGER PRIMARY KEY);", Down: "DROP TABLE users;"}, migrations[0])
+	assert.Equal(t, uint64(10), migrations[1].Version)
+	assert.Empty(t, migrations[1].Down)
+
+	_, err = loadMigrations(testFS{
+		"migrations/1_create_users.up.sql": "CREATE TABLE users (id INTEGER PRIMARY KEY);",
+		"migrations/1_create_posts.up.sql": "CREATE TABLE posts (id INTEGER PRIMARY KEY);",
+	}.MapFS(), "migrations")
+	assert.EqualError(t, err, "duplicate migration version 1: create_posts, create_users")
+
+	_, err = loadMigrations(testFS{
+		"migrations/1_create_users.down.sql": "DROP TABLE users;",
+	}.MapFS(), "migrations")
+	assert.EqualError(t, err, "migration 1_create_users has no up sql")
+}
+
+func TestSplitStatements(t *testing.T) {
+	sql := `-- 用户表
+CREATE TABLE users (
+  name VARCHAR(64) DEFAULT ';', /* ; */
+  note TEXT DEFAULT "a;b"
+);
+INSERT INTO users (name) VALUES ('it''s; ok');
+-- 结尾的注释;
+`
+	assert.Equal(t, []string{
+		"-- 用户表\nCREATE TABLE users (\n  name VARCHAR(64) DEFAULT ';', /* ; */\n  note TEXT DEFAULT \"a;b\"\n)",
+		"INSERT INTO users (name) VALUES ('it''s; ok')",
+	}, splitStatements(sql))
+}
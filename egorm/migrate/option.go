@@ -0,0 +1,49 @@
+package migrate
+
+import (
+	"io"
+	"io/fs"
+
+	"github.com/gotomicro/ego-component/egorm"
+)
+
+// Option 可选项
+type Option func(c *Container)
+
+// WithComponent 设置执行迁移的 egorm 组件
+func WithComponent(db *egorm.Component) Option {
+	return func(c *Container) {
+		c.config.db = db
+	}
+}
+
+// WithFS 从 fs 的 dir 目录读取迁移文件，通常为 embed.FS
+func WithFS(fsys fs.FS, dir string) Option {
+	return func(c *Container) {
+		c.config.fsys = fsys
+		c.config.Dir = dir
+	}
+}
+
+// WithDir 从本地目录读取迁移文件
+func WithDir(dir string) Option {
+	return func(c *Container) {
+		c.config.fsys = nil
+		c.config.Dir = dir
+	}
+}
+
+// WithTable 设置记录已执行版本的表
+func WithTable(table string) Option {
+	return func(c *Container) {
+		c.config.Table = table
+	}
+}
+
+// WithDryRun 只输出将要执行的 SQL 到 output，output 为 nil 时输出到标准输出
+func WithDryRun(output io.Writer) Option {
+	return func(c *Container) {
+		c.config.DryRun = true
+		c.config.output = output
+	}
+}
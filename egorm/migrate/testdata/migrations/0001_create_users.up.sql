@@ -0,0 +1,6 @@
+CREATE TABLE users (
+  id INTEGER PRIMARY KEY,
+  name VARCHAR(64) NOT NULL DEFAULT ';'
+);
+-- 初始数据
+INSERT INTO users (name) VALUES ('ego');
@@ -14,6 +14,47 @@ func WithDSN(dsn string) Option {
 	}
 }
 
+// WithReplicas 设置从库dsn
+func WithReplicas(dsns ...string) Option {
+	return func(c *Container) {
+		c.config.Replicas = dsns
+	}
+}
+
+// WithReplicaPolicy 设置自定义从库负载均衡策略，优先于 ReplicaPolicy 配置
+func WithReplicaPolicy(policy ReplicaPolicy) Option {
+	return func(c *Container) {
+		c.config.replicaPolicy = policy
+	}
+}
+
+// WithShards 设置分库dsn
+func WithShards(dsns ...string) Option {
+	return func(c *Container) {
+		c.config.Shards = dsns
+	}
+}
+
+// WithShardingTable 设置分表规则，table 为逻辑表名，key 为分片键的列名，count 为分表数量
+func WithShardingTable(table string, key string, count int) Option {
+	return func(c *Container) {
+		if c.config.ShardingTables == nil {
+			c.config.ShardingTables = make(map[string]ShardingTable)
+		}
+		c.config.ShardingTables[table] = ShardingTable{Key: key, Count: count}
+	}
+}
+
+// WithShardingAlgorithm 设置分表的自定义分片算法，默认整数取模，非数字的字符串使用 crc32 取模
+func WithShardingAlgorithm(table string, algorithm ShardingAlgorithm) Option {
+	return func(c *Container) {
+		if c.config.shardingAlgorithms == nil {
+			c.config.shardingAlgorithms = make(map[string]ShardingAlgorithm)
+		}
+		c.config.shardingAlgorithms[table] = algorithm
+	}
+}
+
 // WithDSNParser 设置自定义dsnParser
 func WithDSNParser(parser manager.DSNParser) Option {
 	return func(c *Container) {
@@ -0,0 +1,256 @@
+package egorm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gotomicro/ego-component/egorm/manager"
+	"gorm.io/gorm"
+)
+
+// 从库负载均衡策略
+const (
+	// ReplicaPolicyRandom 随机选择从库
+	ReplicaPolicyRandom = "random"
+	// ReplicaPolicyRoundRobin 轮询从库
+	ReplicaPolicyRoundRobin = "roundRobin"
+)
+
+// 执行 SQL 的节点，记录在访问日志、监控以及链路中
+const (
+	nodePrimary = "primary"
+	nodeReplica = "replica"
+)
+
+// nodeSettingKey Statement.Settings 中记录执行节点的 key
+const nodeSettingKey = "egorm:node"
+
+// ReplicaPolicy 从库负载均衡策略
+type ReplicaPolicy interface {
+	// Select 返回本次查询使用的从库下标，n 为从库数量，下标超出范围时使用主库
+	Select(n int) int
+}
+
+type randomPolicy struct{}
+
+func (randomPolicy) Select(n int) int {
+	return rand.Intn(n)
+}
+
+type roundRobinPolicy struct {
+	next uint64
+}
+
+func (p *roundRobinPolicy) Select(n int) int {
+	return int((atomic.AddUint64(&p.next, 1) - 1) % uint64(n))
+}
+
+// newReplicaPolicy 返回名字对应的内置策略
+func newReplicaPolicy(name string) (ReplicaPolicy, error) {
+	switch name {
+	case "", ReplicaPolicyRandom:
+		return randomPolicy{}, nil
+	case ReplicaPolicyRoundRobin:
+		return &roundRobinPolicy{}, nil
+	}
+	return nil, fmt.Errorf("invalid replica policy: %s", name)
+}
+
+type primaryContextKey struct{}
+
+// WithPrimary 返回强制使用主库的 context，用于写入后需要立即读到最新数据的查询
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey{}, true)
+}
+
+func isPrimaryContext(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	primary, _ := ctx.Value(primaryContextKey{}).(bool)
+	return primary
+}
+
+// node 数据库节点
+type node struct {
+	name string
+	dsn  *manager.DSN
+	pool gorm.ConnPool
+}
+
+// closeNodes 关闭节点的连接池
+func closeNodes(nodes []*node) error {
+	var errs []string
+	for _, n := range nodes {
+		closer, ok := n.pool.(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s %s: %s", n.name, n.dsn.Addr, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("close %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// resolverPluginName 注册到 gorm 的插件名
+const resolverPluginName = "egorm:resolver"
+
+// resolver 将查询路由到从库，写入、事务、加锁查询以及 WithPrimary 的查询使用主库
+type resolver struct {
+	primary  *node
+	replicas []*node
+	policy   ReplicaPolicy
+}
+
+// newResolver 打开所有从库，从库的连接配置与主库相同，失败时关闭已经打开的从库
+func newResolver(db *gorm.DB, dsnParser manager.DSNParser, config *config) (r *resolver, err error) {
+	policy := config.replicaPolicy
+	if policy == nil {
+		var err error
+		if policy, err = newReplicaPolicy(config.ReplicaPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	r = &resolver{
+		primary: &node{name: nodePrimary, dsn: config.dsnCfg, pool: db.ConnPool},
+		policy:  policy,
+	}
+	defer func() {
+		if err != nil {
+			_ = r.Close()
+		}
+	}()
+	for _, dsn := range config.Replicas {
+		dsnCfg, err := dsnParser.ParseDSN(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("parse replica dsn: %w", err)
+		}
+		replica, err := gorm.Open(dsnParser.GetDialector(dsn), &gorm.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("open replica %s: %w", dsnCfg.Addr, err)
+		}
+		sqlDB, err := replica.DB()
+		if err != nil {
+			return nil, fmt.Errorf("open replica %s: %w", dsnCfg.Addr, err)
+		}
+		r.replicas = append(r.replicas, &node{name: nodeReplica, dsn: dsnCfg, pool: replica.ConnPool})
+		setConnPool(sqlDB, config)
+		if err := sqlDB.Ping(); err != nil {
+			return nil, fmt.Errorf("ping replica %s: %w", dsnCfg.Addr, err)
+		}
+	}
+	return r, nil
+}
+
+// Name 实现 gorm.Plugin
+func (r *resolver) Name() string {
+	return resolverPluginName
+}
+
+// Initialize 实现 gorm.Plugin
+func (r *resolver) Initialize(db *gorm.DB) error {
+	return r.register(db)
+}
+
+// Close 关闭所有从库，由 egorm.Close 调用
+func (r *resolver) Close() error {
+	return closeNodes(r.replicas)
+}
+
+// register 在 gorm 的 callback 之前选择节点，拦截器执行时已经确定了节点
+func (r *resolver) register(db *gorm.DB) error {
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Query().Before("gorm:query").Register("egorm:resolver", r.resolve),
+		callbacks.Row().Before("gorm:row").Register("egorm:resolver", r.resolve),
+		callbacks.Create().Before("gorm:create").Register("egorm:resolver", r.usePrimary),
+		callbacks.Update().Before("gorm:update").Register("egorm:resolver", r.usePrimary),
+		callbacks.Delete().Before("gorm:delete").Register("egorm:resolver", r.usePrimary),
+		callbacks.Raw().Before("gorm:raw").Register("egorm:resolver", r.usePrimary),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve 为查询选择节点
+func (r *resolver) resolve(db *gorm.DB) {
+	if !r.readable(db) {
+		r.usePrimary(db)
+		return
+	}
+	index := r.policy.Select(len(r.replicas))
+	if index < 0 || index >= len(r.replicas) {
+		r.usePrimary(db)
+		return
+	}
+	replica := r.replicas[index]
+	db.Statement.ConnPool = replica.pool
+	db.Statement.Settings.Store(nodeSettingKey, replica)
+}
+
+// usePrimary 使用主库，事务中的 ConnPool 保持不变
+func (r *resolver) usePrimary(db *gorm.DB) {
+	if r.isReplica(db.Statement.ConnPool) {
+		db.Statement.ConnPool = r.primary.pool
+	}
+	db.Statement.Settings.Store(nodeSettingKey, r.primary)
+}
+
+// readable 查询是否可以路由到从库
+func (r *resolver) readable(db *gorm.DB) bool {
+	// 事务或者自定义的 ConnPool
+	if db.Statement.ConnPool != r.primary.pool && !r.isReplica(db.Statement.ConnPool) {
+		return false
+	}
+	if isPrimaryContext(db.Statement.Context) {
+		return false
+	}
+	// SELECT ... FOR UPDATE
+	if _, ok := db.Statement.Clauses["FOR"]; ok {
+		return false
+	}
+	// db.Raw 的 SQL 已经确定
+	if db.Statement.SQL.Len() > 0 {
+		return isReadSQL(db.Statement.SQL.String())
+	}
+	return true
+}
+
+func (r *resolver) isReplica(pool gorm.ConnPool) bool {
+	for _, replica := range r.replicas {
+		if replica.pool == pool {
+			return true
+		}
+	}
+	return false
+}
+
+// isReadSQL 是否是不加锁的 SELECT 语句
+func isReadSQL(sql string) bool {
+	sql = strings.ToLower(strings.TrimSpace(sql))
+	if !strings.HasPrefix(sql, "select") {
+		return false
+	}
+	return !strings.Contains(sql, " for update") && !strings.Contains(sql, " for share") && !strings.Contains(sql, " lock in share mode")
+}
+
+// nodeOf 返回执行 SQL 的节点名称以及 DSN，没有配置从库时为主库
+func nodeOf(db *gorm.DB, primary *manager.DSN) (string, *manager.DSN) {
+	if value, ok := db.Statement.Settings.Load(nodeSettingKey); ok {
+		n := value.(*node)
+		return n.name, n.dsn
+	}
+	return nodePrimary, primary
+}
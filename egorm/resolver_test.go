@@ -0,0 +1,163 @@
+package egorm
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/gotomicro/ego-component/egorm/manager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// fakePool 只用于 DryRun，不会执行 SQL
+type fakePool struct {
+	name   string
+	closed bool
+}
+
+func (p *fakePool) Close() error {
+	p.closed = true
+	return nil
+}
+
+func (p *fakePool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, nil
+}
+
+func (p *fakePool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, nil
+}
+
+func (p *fakePool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, nil
+}
+
+func (p *fakePool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+type resolverUser struct {
+	ID   int
+	Name string
+}
+
+func newResolverDB(t *testing.T) (*gorm.DB, *resolver) {
+	primary := &fakePool{name: "primary"}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: primary, SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true})
+	require.NoError(t, err)
+	r := &resolver{
+		primary: &node{name: nodePrimary, dsn: &manager.DSN{Addr: "primary:3306"}, pool: db.ConnPool},
+		replicas: []*node{
+			{name: nodeReplica, dsn: &manager.DSN{Addr: "replica-0:3306"}, pool: &fakePool{name: "replica-0"}},
+			{name: nodeReplica, dsn: &manager.DSN{Addr: "replica-1:3306"}, pool: &fakePool{name: "replica-1"}},
+		},
+		policy: &roundRobinPolicy{},
+	}
+	require.NoError(t, r.register(db))
+	return db, r
+}
+
+func nodeAddr(db *gorm.DB) string {
+	_, dsn := nodeOf(db, nil)
+	return dsn.Addr
+}
+
+func TestResolver(t *testing.T) {
+	db, _ := newResolverDB(t)
+
+	// 查询轮询从库
+	assert.Equal(t, "replica-0:3306", nodeAddr(db.Find(&[]resolverUser{})))
+	assert.Equal(t, "replica-1:3306", nodeAddr(db.Where("id = ?", 1).First(&resolverUser{})))
+	assert.Equal(t, "replica-0:3306", nodeAddr(db.Raw("SELECT * FROM resolver_users").Scan(&[]resolverUser{})))
+
+	// 写入、加锁以及强制主库
+	assert.Equal(t, "primary:3306", nodeAddr(db.Create(&resolverUser{Name: "ego"})))
+	assert.Equal(t, "primary:3306", nodeAddr(db.Exec("UPDATE resolver_users SET name = ?", "ego")))
+	assert.Equal(t, "primary:3306", nodeAddr(db.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&[]resolverUser{})))
+	assert.Equal(t, "primary:3306", nodeAddr(db.Raw("SELECT * FROM resolver_users FOR UPDATE").Scan(&[]resolverUser{})))
+	assert.Equal(t, "primary:3306", nodeAddr(db.WithContext(WithPrimary(context.Background())).Find(&[]resolverUser{})))
+}
+
+func TestResolverStatementReuse(t *testing.T) {
+	db, r := newResolverDB(t)
+
+	// 同一个 Statement 先查询后写入，写入时切换回主库
+	tx := db.Model(&resolverUser{}).Where("id = ?", 1)
+	tx.Find(&[]resolverUser{})
+	assert.True(t, r.isReplica(tx.Statement.ConnPool))
+	tx.Update("name", "ego")
+	assert.Equal(t, r.primary.pool, tx.Statement.ConnPool)
+	assert.Equal(t, "primary:3306", nodeAddr(tx))
+}
+
+func TestResolverTransaction(t *testing.T) {
+	db, r := newResolverDB(t)
+
+	txPool := &fakePool{name: "tx"}
+	tx := db.Session(&gorm.Session{})
+	tx.Statement.ConnPool = txPool
+	tx = tx.Find(&[]resolverUser{})
+	assert.Equal(t, txPool, tx.Statement.ConnPool)
+	assert.Equal(t, "primary:3306", nodeAddr(tx))
+	assert.False(t, r.isReplica(tx.Statement.ConnPool))
+}
+
+// fixedPolicy 总是返回同一个下标
+type fixedPolicy int
+
+func (p fixedPolicy) Select(n int) int {
+	return int(p)
+}
+
+func TestResolverInvalidPolicy(t *testing.T) {
+	db, r := newResolverDB(t)
+	for _, index := range []int{-1, 2} {
+		r.policy = fixedPolicy(index)
+		tx := db.Find(&[]resolverUser{})
+		require.NoError(t, tx.Error)
+		// 下标超出范围时使用主库
+		assert.Equal(t, "primary:3306", nodeAddr(tx))
+	}
+}
+
+func TestResolverClose(t *testing.T) {
+	db, r := newResolverDB(t)
+	require.NoError(t, db.Use(r))
+	assert.Equal(t, r, db.Config.Plugins[resolverPluginName])
+	require.NoError(t, r.Close())
+	for _, replica := range r.replicas {
+		assert.True(t, replica.pool.(*fakePool).closed)
+	}
+}
+
+func TestReplicaPolicy(t *testing.T) {
+	policy, err := newReplicaPolicy(ReplicaPolicyRoundRobin)
+	require.NoError(t, err)
+	var selected []int
+	for i := 0; i < 4; i++ {
+		selected = append(selected, policy.Select(3))
+	}
+	assert.Equal(t, []int{0, 1, 2, 0}, selected)
+
+	policy, err = newReplicaPolicy(ReplicaPolicyRandom)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		index := policy.Select(3)
+		assert.True(t, index >= 0 && index < 3)
+	}
+
+	_, err = newReplicaPolicy("unknown")
+	assert.EqualError(t, err, "invalid replica policy: unknown")
+}
+
+func TestIsReadSQL(t *testing.T) {
+	assert.True(t, isReadSQL(" SELECT * FROM user"))
+	assert.True(t, isReadSQL("select count(*) from user"))
+	assert.False(t, isReadSQL("UPDATE user SET name = 'ego'"))
+	assert.False(t, isReadSQL("SELECT * FROM user WHERE id = 1 FOR UPDATE"))
+	assert.False(t, isReadSQL("SELECT * FROM user WHERE id = 1 LOCK IN SHARE MODE"))
+}
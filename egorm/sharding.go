@@ -0,0 +1,561 @@
+package egorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gotomicro/ego-component/egorm/manager"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// shardingPluginName 注册到 gorm 的插件名称
+const shardingPluginName = "egorm:sharding"
+
+var (
+	// ErrCrossShard 条件中没有分片键，或者分片键的值分布在多个分片，且没有使用 WithFanOut
+	ErrCrossShard = errors.New("egorm: cross-shard query without fan-out")
+	// ErrCrossShardTransaction 事务所在的分库与 SQL 路由到的分库不同
+	ErrCrossShardTransaction = errors.New("egorm: cross-shard transaction")
+)
+
+// ShardingTable 分表规则
+type ShardingTable struct {
+	Key   string // 分片键的列名，如 user_id
+	Count int    // 分表数量，表名为 {逻辑表名}_{下标}，下标至少两位，如 orders_00 ~ orders_63
+}
+
+// ShardingAlgorithm 根据分片键的值返回分表下标，取值范围 [0, count)
+type ShardingAlgorithm func(value interface{}, count int) (int, error)
+
+// defaultShardingAlgorithm 整数取模，非数字的字符串使用 crc32 取模
+func defaultShardingAlgorithm(value interface{}, count int) (int, error) {
+	if s, ok := value.(string); ok {
+		if _, err := strconv.ParseInt(s, 10, 64); err != nil {
+			return int(crc32.ChecksumIEEE([]byte(s)) % uint32(count)), nil
+		}
+	}
+	n, err := cast.ToInt64E(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sharding value %v: %w", value, err)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("invalid sharding value %d", n)
+	}
+	return int(n % int64(count)), nil
+}
+
+type fanOutContextKey struct{}
+
+// WithFanOut 返回允许跨分片执行的 context，查询、更新、删除在所有命中的分片上依次执行
+func WithFanOut(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fanOutContextKey{}, true)
+}
+
+func isFanOutContext(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	fanOut, _ := ctx.Value(fanOutContextKey{}).(bool)
+	return fanOut
+}
+
+// shardingRule 分表规则以及分表所在的分库
+type shardingRule struct {
+	table     string
+	key       string
+	count     int
+	format    string
+	pattern   *regexp.Regexp
+	algorithm ShardingAlgorithm
+}
+
+// shardTarget SQL 路由到的分表以及分库
+type shardTarget struct {
+	table string
+	node  *node
+}
+
+// sharding 按分片键改写表名并路由到分库，拦截器在每个分片上执行
+type sharding struct {
+	primary *node
+	shards  []*node
+	rules   map[string]*shardingRule
+}
+
+// newSharding 打开所有分库，分库的连接配置以及拦截器与主库相同，失败时关闭已经打开的分库
+func newSharding(db *gorm.DB, compName string, dsnParser manager.DSNParser, config *config, logger *elog.Component) (s *sharding, err error) {
+	if len(config.Replicas) > 0 {
+		return nil, errors.New("sharding does not support replicas")
+	}
+
+	s = &sharding{
+		primary: &node{name: nodePrimary, dsn: config.dsnCfg, pool: db.ConnPool},
+		rules:   make(map[string]*shardingRule, len(config.ShardingTables)),
+	}
+	defer func() {
+		if err != nil {
+			_ = s.Close()
+		}
+	}()
+	for table, t := range config.ShardingTables {
+		if t.Key == "" || t.Count <= 0 {
+			return nil, fmt.Errorf("invalid sharding table %s: key %q, count %d", table, t.Key, t.Count)
+		}
+		if t.Count < len(config.Shards) {
+			return nil, fmt.Errorf("invalid sharding table %s: count %d is less than shards %d", table, t.Count, len(config.Shards))
+		}
+		algorithm := config.shardingAlgorithms[table]
+		if algorithm == nil {
+			algorithm = defaultShardingAlgorithm
+		}
+		width := len(strconv.Itoa(t.Count - 1))
+		if width < 2 {
+			width = 2
+		}
+		s.rules[table] = &shardingRule{table: table, key: t.Key, count: t.Count, format: "%s_%0" + strconv.Itoa(width) + "d", pattern: keyPattern(t.Key), algorithm: algorithm}
+	}
+
+	for i, dsn := range config.Shards {
+		dsnCfg, err := dsnParser.ParseDSN(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("parse shard dsn: %w", err)
+		}
+		shard, err := gorm.Open(dsnParser.GetDialector(dsn), &gorm.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("open shard %s: %w", dsnCfg.Addr, err)
+		}
+		sqlDB, err := shard.DB()
+		if err != nil {
+			return nil, fmt.Errorf("open shard %s: %w", dsnCfg.Addr, err)
+		}
+		setConnPool(sqlDB, config)
+		if err := sqlDB.Ping(); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("ping shard %s: %w", dsnCfg.Addr, err)
+		}
+		// 分库的事务同样经过拦截器
+		pool, err := newTxPool(shard, compName, dsnCfg, config, logger)
+		if err != nil {
+			_ = sqlDB.Close()
+			return nil, err
+		}
+		s.shards = append(s.shards, &node{name: "shard_" + strconv.Itoa(i), dsn: dsnCfg, pool: pool})
+	}
+	return s, nil
+}
+
+// Close 关闭所有分库，由 egorm.Close 调用
+func (s *sharding) Close() error {
+	return closeNodes(s.shards)
+}
+
+// Name 实现 gorm.Plugin
+func (s *sharding) Name() string {
+	return shardingPluginName
+}
+
+// Initialize 实现 gorm.Plugin，在拦截器外层路由，拦截器记录每个分片的日志、监控以及链路
+func (s *sharding) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	// 默认事务在分片键所在的分库上开启
+	for _, err := range []error{
+		callbacks.Create().Before("gorm:begin_transaction").Register(shardingPluginName, s.prepare("gorm:create")),
+		callbacks.Update().Before("gorm:begin_transaction").Register(shardingPluginName, s.prepare("gorm:update")),
+		callbacks.Delete().Before("gorm:begin_transaction").Register(shardingPluginName, s.prepare("gorm:delete")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	for _, p := range []struct {
+		processor Processor
+		name      string
+	}{
+		{callbacks.Create(), "gorm:create"},
+		{callbacks.Query(), "gorm:query"},
+		{callbacks.Update(), "gorm:update"},
+		{callbacks.Delete(), "gorm:delete"},
+		{callbacks.Row(), "gorm:row"},
+	} {
+		if err := p.processor.Replace(p.name, s.wrap(p.name, p.processor.Get(p.name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepare 只命中一个分片时，在开启默认事务之前切换到分库
+func (s *sharding) prepare(op string) Handler {
+	return func(db *gorm.DB) {
+		rule, ok := s.rules[db.Statement.Table]
+		if !ok || db.Error != nil || db.Statement.SQL.Len() > 0 || !s.switchable(db.Statement.ConnPool) {
+			return
+		}
+		if targets, err := s.targets(db, rule, op); err == nil && len(targets) == 1 {
+			db.Statement.ConnPool = targets[0].node.pool
+		}
+	}
+}
+
+func (s *sharding) wrap(op string, handler Handler) Handler {
+	return func(db *gorm.DB) {
+		rule, ok := s.rules[db.Statement.Table]
+		// 原生 SQL 不改写
+		if !ok || db.Error != nil || db.Statement.SQL.Len() > 0 {
+			handler(db)
+			return
+		}
+
+		targets, err := s.targets(db, rule, op)
+		if err != nil {
+			_ = db.AddError(err)
+			return
+		}
+
+		table, tableExpr, pool := db.Statement.Table, db.Statement.TableExpr, db.Statement.ConnPool
+		defer func() {
+			db.Statement.Table, db.Statement.TableExpr, db.Statement.ConnPool = table, tableExpr, pool
+		}()
+		if len(targets) == 1 {
+			if err := s.use(db, pool, targets[0]); err != nil {
+				_ = db.AddError(err)
+				return
+			}
+			handler(db)
+			return
+		}
+		// 写入以及 Rows 无法合并多个分片的结果
+		if op == "gorm:create" || op == "gorm:row" || !isFanOutContext(db.Statement.Context) {
+			_ = db.AddError(ErrCrossShard)
+			return
+		}
+		s.fanOut(db, pool, targets, handler)
+	}
+}
+
+// use 改写表名并切换到分库，事务中不能切换分库。db.Table 指定的表名在 TableExpr 中，同样改写
+func (s *sharding) use(db *gorm.DB, pool gorm.ConnPool, target *shardTarget) error {
+	db.Statement.Table = target.table
+	if db.Statement.TableExpr != nil {
+		db.Statement.TableExpr = &clause.Expr{SQL: db.Statement.Quote(target.table)}
+	}
+	db.Statement.Settings.Store(nodeSettingKey, target.node)
+	switch {
+	case s.switchable(pool):
+		db.Statement.ConnPool = target.node.pool
+	case isTxConn(pool) && pool.(*txConn).pool != target.node.pool:
+		// 跨分片执行时，默认事务所在的分库与分片不同，不使用默认事务
+		if _, ok := db.InstanceGet("gorm:started_transaction"); !ok {
+			return ErrCrossShardTransaction
+		}
+		db.Statement.ConnPool = target.node.pool
+	}
+	return nil
+}
+
+// switchable 不在事务中，可以切换分库
+func (s *sharding) switchable(pool gorm.ConnPool) bool {
+	if pool == s.primary.pool {
+		return true
+	}
+	for _, shard := range s.shards {
+		if shard.pool == pool {
+			return true
+		}
+	}
+	return false
+}
+
+// fanOut 在每个分片上依次执行，合并查询结果以及影响的行数。ORDER BY、LIMIT 只在单个分片内生效
+func (s *sharding) fanOut(db *gorm.DB, pool gorm.ConnPool, targets []*shardTarget, handler Handler) {
+	stmt := db.Statement
+	dest, reflectValue, clauses := stmt.Dest, stmt.ReflectValue, stmt.Clauses
+	kind := reflect.Invalid
+	if len(stmt.BuildClauses) > 0 && stmt.BuildClauses[0] == "SELECT" && reflectValue.IsValid() {
+		kind = reflectValue.Kind()
+	}
+
+	var (
+		merged       reflect.Value
+		rowsAffected int64
+		found        bool
+	)
+	switch {
+	case kind == reflect.Slice:
+		merged = reflect.MakeSlice(reflectValue.Type(), 0, 0)
+	case isNumberKind(kind):
+		merged = reflect.New(reflectValue.Type()).Elem()
+	}
+	for _, target := range targets {
+		// 构建 SQL 时会追加条件，每个分片使用原始的条件
+		stmt.Clauses = make(map[string]clause.Clause, len(clauses))
+		for name, c := range clauses {
+			stmt.Clauses[name] = c
+		}
+		stmt.SQL.Reset()
+		stmt.Vars = nil
+		if merged.IsValid() {
+			ptr := reflect.New(reflectValue.Type())
+			stmt.Dest, stmt.ReflectValue = ptr.Interface(), ptr.Elem()
+		}
+		if err := s.use(db, pool, target); err != nil {
+			_ = db.AddError(err)
+			break
+		}
+		handler(db)
+		// First、Take 在其他分片中继续查找
+		if kind == reflect.Struct && errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			db.Error = nil
+			continue
+		}
+		if db.Error != nil {
+			break
+		}
+		rowsAffected += db.RowsAffected
+		switch {
+		case kind == reflect.Slice:
+			merged = reflect.AppendSlice(merged, stmt.ReflectValue)
+		case isNumberKind(kind):
+			// Count 合并为一行
+			merged.SetInt(merged.Int() + stmt.ReflectValue.Int())
+			rowsAffected = 1
+		case kind == reflect.Struct && db.RowsAffected > 0:
+			found = true
+		}
+		if found {
+			break
+		}
+	}
+
+	stmt.Dest, stmt.ReflectValue, stmt.Clauses = dest, reflectValue, clauses
+	db.RowsAffected = rowsAffected
+	if db.Error != nil {
+		return
+	}
+	if merged.IsValid() {
+		reflectValue.Set(merged)
+	}
+	if kind == reflect.Struct && !found && stmt.RaiseErrorOnNotFound {
+		_ = db.AddError(gorm.ErrRecordNotFound)
+	}
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	return kind == reflect.Int64 || kind == reflect.Int
+}
+
+func isTxConn(pool gorm.ConnPool) bool {
+	_, ok := pool.(*txConn)
+	return ok
+}
+
+// targets 返回 SQL 命中的分片，条件中没有分片键时命中所有分片
+func (s *sharding) targets(db *gorm.DB, rule *shardingRule, op string) ([]*shardTarget, error) {
+	var (
+		values []interface{}
+		ok     bool
+	)
+	if op == "gorm:create" {
+		values, ok = createValues(db.Statement, rule.key)
+	} else {
+		values, ok = whereValues(db.Statement, rule)
+	}
+
+	indexes := make([]int, 0, rule.count)
+	if !ok {
+		for i := 0; i < rule.count; i++ {
+			indexes = append(indexes, i)
+		}
+	} else {
+		seen := make(map[int]bool, len(values))
+		for _, value := range values {
+			index, err := rule.index(value)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[index] {
+				seen[index] = true
+				indexes = append(indexes, index)
+			}
+		}
+	}
+
+	targets := make([]*shardTarget, 0, len(indexes))
+	for _, index := range indexes {
+		targets = append(targets, s.target(rule, index))
+	}
+	return targets, nil
+}
+
+// target 分表下标对应的分表以及分库，分表按顺序平均分布在分库上
+func (s *sharding) target(rule *shardingRule, index int) *shardTarget {
+	n := s.primary
+	if len(s.shards) > 0 {
+		n = s.shards[index*len(s.shards)/rule.count]
+	}
+	return &shardTarget{table: fmt.Sprintf(rule.format, rule.table, index), node: n}
+}
+
+func (r *shardingRule) index(value interface{}) (int, error) {
+	index, err := r.algorithm(value, r.count)
+	if err != nil {
+		return 0, err
+	}
+	if index < 0 || index >= r.count {
+		return 0, fmt.Errorf("sharding table %s: index %d out of range [0, %d)", r.table, index, r.count)
+	}
+	return index, nil
+}
+
+// createValues 从写入的记录中取分片键的值
+func createValues(stmt *gorm.Statement, key string) ([]interface{}, bool) {
+	if stmt.Schema == nil {
+		return nil, false
+	}
+	field := stmt.Schema.LookUpField(key)
+	if field == nil {
+		return nil, false
+	}
+	var values []interface{}
+	switch rv := reflect.Indirect(stmt.ReflectValue); rv.Kind() {
+	case reflect.Struct:
+		value, _ := field.ValueOf(rv)
+		values = append(values, value)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			value, _ := field.ValueOf(reflect.Indirect(rv.Index(i)))
+			values = append(values, value)
+		}
+	default:
+		return nil, false
+	}
+	return values, len(values) > 0
+}
+
+// whereValues 从 AND 连接的 WHERE 条件中取分片键的值，支持 clause.Eq、clause.IN 以及 "key = ?"、"key IN ?" 形式的条件，
+// 包含 Or、Not 条件时命中所有分片
+func whereValues(stmt *gorm.Statement, rule *shardingRule) ([]interface{}, bool) {
+	c, ok := stmt.Clauses["WHERE"]
+	if !ok {
+		return nil, false
+	}
+	where, ok := c.Expression.(clause.Where)
+	if !ok {
+		return nil, false
+	}
+	return exprValues(where.Exprs, rule)
+}
+
+func exprValues(exprs []clause.Expression, rule *shardingRule) ([]interface{}, bool) {
+	for _, expr := range exprs {
+		switch expr.(type) {
+		case clause.OrConditions, clause.NotConditions:
+			return nil, false
+		}
+	}
+	for _, expr := range exprs {
+		switch e := expr.(type) {
+		case clause.Eq:
+			if isColumn(e.Column, rule.key) {
+				return flatten([]interface{}{e.Value}), true
+			}
+		case clause.IN:
+			if isColumn(e.Column, rule.key) {
+				return flatten(e.Values), true
+			}
+		case clause.AndConditions:
+			if values, ok := exprValues(e.Exprs, rule); ok {
+				return values, true
+			}
+		case clause.Expr:
+			if values, ok := sqlValues(e.SQL, e.Vars, rule.pattern); ok {
+				return values, true
+			}
+		}
+	}
+	return nil, false
+}
+
+var (
+	orRegexp  = regexp.MustCompile(`(?i)\bor\b`)
+	andRegexp = regexp.MustCompile(`(?i)\s+and\s+`)
+)
+
+// keyPattern 匹配 "key = ?"、"key IN ?"、"key IN (?)"，列名可以带表名以及引号
+func keyPattern(key string) *regexp.Regexp {
+	return regexp.MustCompile("(?i)^\\(?\\s*(?:[`\"]?\\w+[`\"]?\\.)?[`\"]?" + regexp.QuoteMeta(key) + "[`\"]?\\s*(=|in)\\s*\\(?\\s*\\?\\s*\\)?\\s*\\)?$")
+}
+
+// sqlValues 解析只包含 AND 的条件，如 "user_id = ? AND status = ?"
+func sqlValues(sql string, vars []interface{}, pattern *regexp.Regexp) ([]interface{}, bool) {
+	if orRegexp.MatchString(sql) {
+		return nil, false
+	}
+	index := 0
+	for _, part := range andRegexp.Split(sql, -1) {
+		if pattern.MatchString(strings.TrimSpace(part)) && index < len(vars) {
+			return flatten([]interface{}{vars[index]}), true
+		}
+		index += strings.Count(part, "?")
+	}
+	return nil, false
+}
+
+func isColumn(column interface{}, key string) bool {
+	switch c := column.(type) {
+	case string:
+		return c == key || strings.HasSuffix(c, "."+key)
+	case clause.Column:
+		return c.Name == key
+	}
+	return false
+}
+
+// flatten 展开 IN 的切片参数
+func flatten(values []interface{}) []interface{} {
+	var list []interface{}
+	for _, value := range values {
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+			for i := 0; i < rv.Len(); i++ {
+				list = append(list, rv.Index(i).Interface())
+			}
+			continue
+		}
+		list = append(list, value)
+	}
+	return list
+}
+
+// Shard 返回分片键所在分库的 db，用于在分库上开启事务，如 egorm.Shard(db, "orders", uid).Transaction(...)
+func Shard(db *Component, table string, value interface{}) (*Component, error) {
+	plugin, ok := db.Config.Plugins[shardingPluginName]
+	if !ok {
+		return nil, errors.New("sharding is not configured")
+	}
+	s := plugin.(*sharding)
+	rule, ok := s.rules[table]
+	if !ok {
+		return nil, fmt.Errorf("sharding table %s is not configured", table)
+	}
+	index, err := rule.index(value)
+	if err != nil {
+		return nil, err
+	}
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tx := db.Session(&gorm.Session{Context: ctx})
+	tx.Statement.ConnPool = s.target(rule, index).node.pool
+	return tx, nil
+}
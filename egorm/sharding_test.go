@@ -0,0 +1,223 @@
+package egorm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gotomicro/ego-component/egorm/manager"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type shardingOrder struct {
+	ID     int
+	UserID int
+	Amount int
+}
+
+// newShardingDB 4 张分表分布在 2 个分库，sharding_orders_00、01 在 shard0，02、03 在 shard1
+func newShardingDB(t *testing.T) (*Component, *[]string) {
+	var ops []string
+	recorder := func(compName string, dsn *manager.DSN, op string, config *config, logger *elog.Component) func(Handler) Handler {
+		return func(next Handler) Handler {
+			return func(db *Component) {
+				next(db)
+				_, node := nodeOf(db, dsn)
+				ops = append(ops, op+" "+node.DBName+"."+db.Statement.Table)
+			}
+		}
+	}
+
+	// 内存数据库的每个连接都是独立的数据库，使用 cache=shared 共享
+	shards := []string{"file:shard0?mode=memory&cache=shared", "file:shard1?mode=memory&cache=shared"}
+	for i, dsn := range shards {
+		shard, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+		require.NoError(t, err)
+		for j := i * 2; j < i*2+2; j++ {
+			table := fmt.Sprintf("sharding_orders_%02d", j)
+			require.NoError(t, shard.Migrator().DropTable(table))
+			require.NoError(t, shard.Table(table).AutoMigrate(&shardingOrder{}))
+		}
+	}
+
+	c := DefaultContainer()
+	c.config.Dialect = "sqlite"
+	db := c.Build(
+		WithDSN("file:sharding?mode=memory&cache=shared"),
+		WithShards(shards...),
+		WithShardingTable("sharding_orders", "user_id", 4),
+		WithInterceptor(recorder),
+	)
+	return db, &ops
+}
+
+func TestSharding(t *testing.T) {
+	db, ops := newShardingDB(t)
+
+	// 写入按分片键路由，默认事务在分库上开启
+	require.NoError(t, db.Create(&shardingOrder{ID: 1, UserID: 1, Amount: 10}).Error)
+	assert.Equal(t, []string{"gorm:begin shard0.", "gorm:create shard0.sharding_orders_01", "gorm:commit shard0."}, *ops)
+	require.NoError(t, db.Create(&[]shardingOrder{{ID: 2, UserID: 2, Amount: 20}, {ID: 3, UserID: 6, Amount: 30}}).Error)
+	require.NoError(t, db.Create(&shardingOrder{ID: 4, UserID: 5, Amount: 40}).Error)
+	assert.ErrorIs(t, db.Create(&[]shardingOrder{{ID: 5, UserID: 1}, {ID: 6, UserID: 2}}).Error, ErrCrossShard)
+
+	// 条件中包含分片键
+	var orders []shardingOrder
+	require.NoError(t, db.Where("user_id = ?", 2).Order("id").Find(&orders).Error)
+	assert.Equal(t, []shardingOrder{{ID: 2, UserID: 2, Amount: 20}}, orders)
+	require.NoError(t, db.Where("user_id IN ? AND amount > ?", []int{1, 5}, 0).Order("id").Find(&orders).Error)
+	assert.Equal(t, []int{1, 4}, orderIDs(orders))
+	var order shardingOrder
+	require.NoError(t, db.Where(&shardingOrder{UserID: 6}).First(&order).Error)
+	assert.Equal(t, 3, order.ID)
+
+	require.NoError(t, db.Model(&shardingOrder{}).Where("user_id = ?", 1).Update("amount", 11).Error)
+	var amount int
+	require.NoError(t, db.Model(&shardingOrder{}).Select("amount").Where("user_id = ?", 1).Row().Scan(&amount))
+	assert.Equal(t, 11, amount)
+
+	*ops = (*ops)[:0]
+	require.NoError(t, db.Where("user_id = ?", 2).Find(&orders).Error)
+	assert.Equal(t, []string{"gorm:query shard1.sharding_orders_02"}, *ops)
+}
+
+func TestShardingFanOut(t *testing.T) {
+	db, ops := newShardingDB(t)
+	require.NoError(t, db.Create(&[]shardingOrder{{ID: 1, UserID: 1}, {ID: 5, UserID: 1}}).Error)
+	require.NoError(t, db.Create(&shardingOrder{ID: 2, UserID: 2}).Error)
+	require.NoError(t, db.Create(&shardingOrder{ID: 3, UserID: 3}).Error)
+
+	// 没有分片键或者分片键分布在多个分片
+	var orders []shardingOrder
+	assert.ErrorIs(t, db.Find(&orders).Error, ErrCrossShard)
+	assert.ErrorIs(t, db.Where("user_id IN ?", []int{1, 2}).Find(&orders).Error, ErrCrossShard)
+	assert.ErrorIs(t, db.Where("user_id = ? OR id = ?", 1, 2).Find(&orders).Error, ErrCrossShard)
+	assert.ErrorIs(t, db.Where("user_id = ?", 1).Or("user_id = ?", 2).Find(&orders).Error, ErrCrossShard)
+	assert.ErrorIs(t, db.Where("user_id = ?", 1).Not("id = ?", 2).Find(&orders).Error, ErrCrossShard)
+	_, err := db.Model(&shardingOrder{}).Rows()
+	assert.ErrorIs(t, err, ErrCrossShard)
+
+	ctx := WithFanOut(context.Background())
+	*ops = (*ops)[:0]
+	require.NoError(t, db.WithContext(ctx).Order("id").Find(&orders).Error)
+	assert.Equal(t, []int{1, 5, 2, 3}, orderIDs(orders))
+	assert.Equal(t, []string{
+		"gorm:query shard0.sharding_orders_00",
+		"gorm:query shard0.sharding_orders_01",
+		"gorm:query shard1.sharding_orders_02",
+		"gorm:query shard1.sharding_orders_03",
+	}, *ops)
+
+	// Or 条件命中所有分片
+	require.NoError(t, db.WithContext(ctx).Where("user_id = ?", 1).Or("user_id = ?", 2).Order("id").Find(&orders).Error)
+	assert.Equal(t, []int{1, 5, 2}, orderIDs(orders))
+
+	var count int64
+	require.NoError(t, db.WithContext(ctx).Model(&shardingOrder{}).Count(&count).Error)
+	assert.Equal(t, int64(4), count)
+
+	var order shardingOrder
+	require.NoError(t, db.WithContext(ctx).Where("id = ?", 3).First(&order).Error)
+	assert.Equal(t, 3, order.UserID)
+	assert.ErrorIs(t, db.WithContext(ctx).Where("id = ?", 10).First(&order).Error, ErrRecordNotFound)
+
+	res := db.WithContext(ctx).Where("id > ?", 1).Delete(&shardingOrder{})
+	require.NoError(t, res.Error)
+	assert.Equal(t, int64(3), res.RowsAffected)
+	require.NoError(t, db.WithContext(ctx).Model(&shardingOrder{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestShardingTable(t *testing.T) {
+	db, ops := newShardingDB(t)
+	require.NoError(t, db.Table("sharding_orders").Create(&shardingOrder{ID: 1, UserID: 1, Amount: 10}).Error)
+	require.NoError(t, db.Table("sharding_orders").Create(&shardingOrder{ID: 2, UserID: 2, Amount: 20}).Error)
+
+	// db.Table 指定的表名同样改写
+	*ops = (*ops)[:0]
+	var orders []shardingOrder
+	tx := db.Table("sharding_orders").Where("user_id = ?", 2)
+	require.NoError(t, tx.Find(&orders).Error)
+	assert.Equal(t, []int{2}, orderIDs(orders))
+	assert.Equal(t, []string{"gorm:query shard1.sharding_orders_02"}, *ops)
+	// 执行后恢复逻辑表名
+	assert.Equal(t, "sharding_orders", tx.Statement.Table)
+	assert.Equal(t, "`sharding_orders`", tx.Statement.TableExpr.SQL)
+
+	var count int64
+	require.NoError(t, db.WithContext(WithFanOut(context.Background())).Table("sharding_orders").Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestShardingClose(t *testing.T) {
+	db, _ := newShardingDB(t)
+	s := db.Config.Plugins[shardingPluginName].(*sharding)
+	require.NoError(t, Close(db))
+	for _, shard := range s.shards {
+		assert.Error(t, shard.pool.(*txPool).Ping())
+	}
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	assert.Error(t, sqlDB.Ping())
+}
+
+func TestShardingTransaction(t *testing.T) {
+	db, _ := newShardingDB(t)
+
+	tx, err := Shard(db, "sharding_orders", 2)
+	require.NoError(t, err)
+	err = tx.Transaction(func(tx *Component) error {
+		if err := tx.Create(&shardingOrder{ID: 1, UserID: 2}).Error; err != nil {
+			return err
+		}
+		// sharding_orders_03 与 sharding_orders_02 在同一个分库
+		if err := tx.Create(&shardingOrder{ID: 2, UserID: 3}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&shardingOrder{ID: 3, UserID: 1}).Error
+	})
+	assert.ErrorIs(t, err, ErrCrossShardTransaction)
+
+	var count int64
+	require.NoError(t, db.WithContext(WithFanOut(context.Background())).Model(&shardingOrder{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	_, err = Shard(db, "users", 1)
+	assert.EqualError(t, err, "sharding table users is not configured")
+}
+
+func TestShardingValues(t *testing.T) {
+	values, ok := sqlValues("`orders`.`user_id` = ? AND status IN (?)", []interface{}{1, []int{2}}, keyPattern("user_id"))
+	assert.True(t, ok)
+	assert.Equal(t, []interface{}{1}, values)
+	values, ok = sqlValues("status = ? AND user_id IN ?", []interface{}{1, []int{2, 3}}, keyPattern("user_id"))
+	assert.True(t, ok)
+	assert.Equal(t, []interface{}{2, 3}, values)
+	_, ok = sqlValues("user_id = ? OR status = ?", []interface{}{1, 2}, keyPattern("user_id"))
+	assert.False(t, ok)
+	_, ok = sqlValues("user_id > ?", []interface{}{1}, keyPattern("user_id"))
+	assert.False(t, ok)
+
+	index, err := defaultShardingAlgorithm(int64(65), 64)
+	require.NoError(t, err)
+	assert.Equal(t, 1, index)
+	index, err = defaultShardingAlgorithm("65", 64)
+	require.NoError(t, err)
+	assert.Equal(t, 1, index)
+	_, err = defaultShardingAlgorithm("ego", 64)
+	assert.NoError(t, err)
+	_, err = defaultShardingAlgorithm(-1, 64)
+	assert.Error(t, err)
+}
+
+func orderIDs(orders []shardingOrder) []int {
+	ids := make([]int, 0, len(orders))
+	for _, order := range orders {
+		ids = append(ids, order.ID)
+	}
+	return ids
+}
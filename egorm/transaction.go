@@ -0,0 +1,168 @@
+package egorm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/gotomicro/ego-component/egorm/manager"
+	"github.com/gotomicro/ego/core/elog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 事务的操作，与 callback 一样经过拦截器
+const (
+	opBegin    = "gorm:begin"
+	opCommit   = "gorm:commit"
+	opRollback = "gorm:rollback"
+)
+
+// errTxAbandoned 事务没有提交或者回滚就被回收
+var errTxAbandoned = errors.New("transaction abandoned without commit or rollback")
+
+var (
+	_ gorm.ConnPool         = (*txPool)(nil)
+	_ gorm.ConnPoolBeginner = (*txPool)(nil)
+	_ gorm.GetDBConnector   = (*txPool)(nil)
+	_ gorm.TxCommitter      = (*txConn)(nil)
+)
+
+// txPool 包装主库的连接池，开启、提交、回滚事务时执行拦截器
+type txPool struct {
+	*sql.DB
+	db       *gorm.DB
+	begin    Handler
+	commit   Handler
+	rollback Handler
+}
+
+// newTxPool 替换 db 的连接池
+func newTxPool(db *gorm.DB, compName string, dsn *manager.DSN, config *config, logger *elog.Component) (*txPool, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	pool := &txPool{DB: sqlDB, db: db}
+	chain := func(op string, handler Handler) Handler {
+		for _, interceptor := range config.interceptors {
+			handler = interceptor(compName, dsn, op, config, logger)(handler)
+		}
+		return handler
+	}
+	pool.begin = chain(opBegin, pool.beginTx)
+	pool.commit = chain(opCommit, func(db *gorm.DB) {
+		db.AddError(db.Statement.ConnPool.(*txConn).Tx.Commit())
+	})
+	pool.rollback = chain(opRollback, func(db *gorm.DB) {
+		db.AddError(db.Statement.ConnPool.(*txConn).Tx.Rollback())
+	})
+
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+	return pool, nil
+}
+
+// GetDBConn 用于 db.DB() 返回原始的 *sql.DB
+func (p *txPool) GetDBConn() (*sql.DB, error) {
+	return p.DB, nil
+}
+
+// BeginTx 开启事务，gorm 优先使用返回 ConnPool 的 BeginTx
+func (p *txPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	db := p.statement(ctx, "BEGIN")
+	db.Statement.Dest = opts
+	p.begin(db)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	return db.Statement.ConnPool, nil
+}
+
+func (p *txPool) beginTx(db *gorm.DB) {
+	tx, err := p.DB.BeginTx(db.Statement.Context, db.Statement.Dest.(*sql.TxOptions))
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.Statement.ConnPool = &txConn{Tx: tx, pool: p, ctx: db.Statement.Context}
+}
+
+// statement 事务操作没有 Statement，构造一个只有 SQL 的 Statement 用于拦截器记录日志、监控以及链路
+func (p *txPool) statement(ctx context.Context, sql string) *gorm.DB {
+	db := &gorm.DB{Config: p.db.Config}
+	db.Statement = &gorm.Statement{DB: db, ConnPool: p, Context: ctx, Clauses: map[string]clause.Clause{}}
+	db.Statement.SQL.WriteString(sql)
+	return db
+}
+
+// txConn 事务中的连接
+type txConn struct {
+	*sql.Tx
+	pool *txPool
+	// ctx 事务的 context，开启链路时包含事务的 span，事务中的 SQL 作为它的子 span
+	ctx context.Context
+	// spanOnce 保证事务的 span 只结束一次
+	spanOnce sync.Once
+	spanDone chan struct{}
+}
+
+// Commit 提交事务
+func (t *txConn) Commit() error {
+	return t.finish(t.pool.commit, "COMMIT")
+}
+
+// Rollback 回滚事务
+func (t *txConn) Rollback() error {
+	return t.finish(t.pool.rollback, "ROLLBACK")
+}
+
+// watchSpan 事务没有提交或者回滚时，在开启事务的 context 取消或者 txConn 被回收时结束事务的 span
+func (t *txConn) watchSpan(parent context.Context) {
+	t.spanDone = make(chan struct{})
+	if parent.Done() != nil {
+		go func() {
+			select {
+			case <-parent.Done():
+				// database/sql 在 context 取消时回滚事务
+				t.endSpan(opRollback, parent.Err())
+			case <-t.spanDone:
+			}
+		}()
+	}
+	runtime.SetFinalizer(t, func(t *txConn) {
+		t.endSpan(opRollback, errTxAbandoned)
+	})
+}
+
+// endSpan 提交或者回滚后结束事务的 span
+func (t *txConn) endSpan(op string, err error) {
+	t.spanOnce.Do(func() {
+		if t.spanDone != nil {
+			close(t.spanDone)
+		}
+		runtime.SetFinalizer(t, nil)
+		span := trace.SpanFromContext(t.ctx)
+		span.SetAttributes(attribute.String("db.transaction", strings.TrimPrefix(op, "gorm:")))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetStatus(codes.Ok, "OK")
+		}
+		span.End()
+	})
+}
+
+func (t *txConn) finish(handler Handler, sql string) error {
+	db := t.pool.statement(t.ctx, sql)
+	db.Statement.ConnPool = t
+	handler(db)
+	return db.Error
+}
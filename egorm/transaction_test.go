@@ -0,0 +1,129 @@
+package egorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego-component/egorm/manager"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type txUser struct {
+	ID   int
+	Name string
+}
+
+func newTxDB(t *testing.T, name string) (*Component, *[]string) {
+	var ops []string
+	recorder := func(compName string, dsn *manager.DSN, op string, config *config, logger *elog.Component) func(Handler) Handler {
+		return func(next Handler) Handler {
+			return func(db *Component) {
+				next(db)
+				ops = append(ops, op)
+			}
+		}
+	}
+	c := DefaultContainer()
+	c.config.Dialect = "sqlite"
+	c.config.EnableTraceInterceptor = true
+	db := c.Build(WithDSN("file:"+name+"?mode=memory&cache=shared"), WithInterceptor(recorder))
+	require.NoError(t, db.AutoMigrate(&txUser{}))
+	ops = ops[:0]
+	return db, &ops
+}
+
+func TestTransaction(t *testing.T) {
+	db, ops := newTxDB(t, "transaction")
+
+	// 默认事务以及手动事务都经过拦截器
+	require.NoError(t, db.Create(&txUser{Name: "ego"}).Error)
+	assert.Equal(t, []string{opBegin, "gorm:create", opCommit}, *ops)
+
+	*ops = (*ops)[:0]
+	err := db.Transaction(func(tx *Component) error {
+		if err := tx.Create(&txUser{Name: "gorm"}).Error; err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	assert.EqualError(t, err, "rollback")
+	assert.Equal(t, []string{opBegin, "gorm:create", opRollback}, *ops)
+
+	var count int64
+	require.NoError(t, db.Model(&txUser{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// db.DB() 仍然返回 *sql.DB
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Ping())
+}
+
+func TestRow(t *testing.T) {
+	db, ops := newTxDB(t, "row")
+	require.NoError(t, db.Create(&txUser{Name: "ego"}).Error)
+
+	*ops = (*ops)[:0]
+	var name string
+	require.NoError(t, db.Model(&txUser{}).Select("name").Where("id = ?", 1).Row().Scan(&name))
+	assert.Equal(t, "ego", name)
+	rows, err := db.Model(&txUser{}).Rows()
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+	assert.Equal(t, []string{"gorm:row", "gorm:row"}, *ops)
+}
+
+func TestTransactionSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	db, _ := newTxDB(t, "span")
+	require.NoError(t, db.WithContext(context.Background()).Create(&txUser{Name: "ego"}).Error)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	require.Contains(t, spans, "gorm:transaction")
+	require.Contains(t, spans, "gorm:insert")
+	require.Contains(t, spans, opCommit)
+	transaction := spans["gorm:transaction"].SpanContext()
+	assert.Equal(t, transaction.SpanID(), spans["gorm:insert"].Parent().SpanID())
+	assert.Equal(t, transaction.SpanID(), spans[opCommit].Parent().SpanID())
+}
+
+func TestTransactionSpanAbandoned(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	db, _ := newTxDB(t, "abandoned")
+	ctx, cancel := context.WithCancel(context.Background())
+	tx := db.WithContext(ctx).Begin()
+	require.NoError(t, tx.Error)
+	require.NoError(t, tx.Create(&txUser{Name: "ego"}).Error)
+
+	// 没有提交或者回滚的事务在 context 取消后结束 span
+	cancel()
+	var transaction sdktrace.ReadOnlySpan
+	require.Eventually(t, func() bool {
+		for _, span := range recorder.Ended() {
+			if span.Name() == "gorm:transaction" {
+				transaction = span
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "context canceled", transaction.Status().Description)
+}
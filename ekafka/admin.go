@@ -0,0 +1,488 @@
+package ekafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrGroupNotEmpty 消费组还有活跃的成员，需要先停止消费者才能重置 offset
+var ErrGroupNotEmpty = errors.New("ekafka: consumer group is not empty")
+
+// broker 返回的消费组状态
+const (
+	groupStateEmpty = "Empty"
+	groupStateDead  = "Dead"
+)
+
+// Admin kafka 管理接口，用于管理 topic、分区、配置以及消费组的 offset
+type Admin struct {
+	cmp    *Component
+	client *Client
+}
+
+// Admin 返回 kafka 管理接口，所有请求都经过 Client 的拦截器
+func (cmp *Component) Admin() *Admin {
+	return &Admin{cmp: cmp, client: cmp.Client()}
+}
+
+// TopicSpec 创建 topic 的参数
+type TopicSpec struct {
+	Name string
+	// NumPartitions 分区数量，为 0 时使用 broker 配置的 num.partitions
+	NumPartitions int
+	// ReplicationFactor 副本数量，为 0 时使用 broker 配置的 default.replication.factor
+	ReplicationFactor int
+	// Configs topic 级别的配置，如 retention.ms、cleanup.policy
+	Configs map[string]string
+}
+
+// TopicDescription topic 的分区以及配置
+type TopicDescription struct {
+	Name       string                 `json:"name"`
+	Internal   bool                   `json:"internal"`
+	Partitions []PartitionDescription `json:"partitions"`
+	Configs    []TopicConfigEntry     `json:"configs"`
+}
+
+// PartitionDescription 分区的 leader 以及副本所在的 broker id
+type PartitionDescription struct {
+	ID       int   `json:"id"`
+	Leader   int   `json:"leader"`
+	Replicas []int `json:"replicas"`
+	Isr      []int `json:"isr"`
+}
+
+// TopicConfigEntry topic 的一项配置
+type TopicConfigEntry struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	IsDefault bool   `json:"isDefault"`
+	ReadOnly  bool   `json:"readOnly"`
+	Sensitive bool   `json:"sensitive"`
+}
+
+// GroupDescription broker 中消费组的状态以及成员
+type GroupDescription struct {
+	GroupID string             `json:"groupId"`
+	State   string             `json:"state"`
+	Members []GroupMemberStats `json:"members"`
+}
+
+type offsetResetKind int
+
+const (
+	offsetResetEarliest offsetResetKind = iota
+	offsetResetLatest
+	offsetResetTimestamp
+	offsetResetOffset
+)
+
+// OffsetResetSpec 重置消费组 offset 的目标位置
+type OffsetResetSpec struct {
+	kind      offsetResetKind
+	offset    int64
+	timestamp time.Time
+}
+
+// ResetToEarliest 重置到分区中保留的第一条消息
+func ResetToEarliest() OffsetResetSpec {
+	return OffsetResetSpec{kind: offsetResetEarliest}
+}
+
+// ResetToLatest 重置到分区的末尾，跳过所有未消费的消息
+func ResetToLatest() OffsetResetSpec {
+	return OffsetResetSpec{kind: offsetResetLatest}
+}
+
+// ResetToTimestamp 重置到时间戳大于等于 t 的第一条消息，不存在时重置到分区末尾
+func ResetToTimestamp(t time.Time) OffsetResetSpec {
+	return OffsetResetSpec{kind: offsetResetTimestamp, timestamp: t}
+}
+
+// ResetToOffset 重置到指定的 offset，超出分区范围时使用最近的有效 offset
+func ResetToOffset(offset int64) OffsetResetSpec {
+	return OffsetResetSpec{kind: offsetResetOffset, offset: offset}
+}
+
+// String 用于日志
+func (s OffsetResetSpec) String() string {
+	switch s.kind {
+	case offsetResetEarliest:
+		return "earliest"
+	case offsetResetLatest:
+		return "latest"
+	case offsetResetTimestamp:
+		return "timestamp:" + s.timestamp.Format(time.RFC3339)
+	default:
+		return "offset:" + strconv.FormatInt(s.offset, 10)
+	}
+}
+
+// resolve returns the offset to commit, timeOffset is the offset found by timestamp, -1 if no message is found.
+func (s OffsetResetSpec) resolve(first, last, timeOffset int64) int64 {
+	switch s.kind {
+	case offsetResetEarliest:
+		return first
+	case offsetResetLatest:
+		return last
+	case offsetResetTimestamp:
+		if timeOffset < 0 {
+			return last
+		}
+		return timeOffset
+	default:
+		if s.offset < first {
+			return first
+		}
+		if s.offset > last {
+			return last
+		}
+		return s.offset
+	}
+}
+
+// CreateTopics 创建 topic
+func (a *Admin) CreateTopics(ctx context.Context, topics ...TopicSpec) error {
+	req := &kafka.CreateTopicsRequest{Topics: make([]kafka.TopicConfig, 0, len(topics))}
+	for _, topic := range topics {
+		topicConfig := kafka.TopicConfig{
+			Topic:             topic.Name,
+			NumPartitions:     topic.NumPartitions,
+			ReplicationFactor: topic.ReplicationFactor,
+		}
+		if topicConfig.NumPartitions == 0 {
+			topicConfig.NumPartitions = -1
+		}
+		if topicConfig.ReplicationFactor == 0 {
+			topicConfig.ReplicationFactor = -1
+		}
+		for _, name := range sortedKeys(topic.Configs) {
+			topicConfig.ConfigEntries = append(topicConfig.ConfigEntries, kafka.ConfigEntry{ConfigName: name, ConfigValue: topic.Configs[name]})
+		}
+		req.Topics = append(req.Topics, topicConfig)
+	}
+	res, err := a.client.CreateTopics(ctx, req)
+	if err != nil {
+		return err
+	}
+	return firstError("create topic", res.Errors)
+}
+
+// DeleteTopics 删除 topic
+func (a *Admin) DeleteTopics(ctx context.Context, topics ...string) error {
+	res, err := a.client.DeleteTopics(ctx, &kafka.DeleteTopicsRequest{Topics: topics})
+	if err != nil {
+		return err
+	}
+	return firstError("delete topic", res.Errors)
+}
+
+// ListTopics 返回除内部 topic 之外的所有 topic
+func (a *Admin) ListTopics(ctx context.Context) ([]string, error) {
+	res, err := a.client.Metadata(ctx, &kafka.MetadataRequest{})
+	if err != nil {
+		return nil, err
+	}
+	topics := make([]string, 0, len(res.Topics))
+	for _, topic := range res.Topics {
+		if !topic.Internal {
+			topics = append(topics, topic.Name)
+		}
+	}
+	sort.Strings(topics)
+	return topics, nil
+}
+
+// DescribeTopics 返回 topic 的分区以及配置
+func (a *Admin) DescribeTopics(ctx context.Context, topics ...string) ([]TopicDescription, error) {
+	res, err := a.client.Metadata(ctx, &kafka.MetadataRequest{Topics: topics})
+	if err != nil {
+		return nil, err
+	}
+	descriptions := make([]TopicDescription, 0, len(res.Topics))
+	configReq := &kafka.DescribeConfigsRequest{}
+	for _, topic := range res.Topics {
+		if topic.Error != nil {
+			return nil, fmt.Errorf("describe topic %s: %w", topic.Name, topic.Error)
+		}
+		description := TopicDescription{Name: topic.Name, Internal: topic.Internal, Configs: make([]TopicConfigEntry, 0)}
+		for _, partition := range topic.Partitions {
+			description.Partitions = append(description.Partitions, PartitionDescription{
+				ID:       partition.ID,
+				Leader:   partition.Leader.ID,
+				Replicas: brokerIDs(partition.Replicas),
+				Isr:      brokerIDs(partition.Isr),
+			})
+		}
+		sort.Slice(description.Partitions, func(i, j int) bool {
+			return description.Partitions[i].ID < description.Partitions[j].ID
+		})
+		descriptions = append(descriptions, description)
+		configReq.Resources = append(configReq.Resources, kafka.DescribeConfigRequestResource{
+			ResourceType: kafka.ResourceTypeTopic,
+			ResourceName: topic.Name,
+		})
+	}
+	sort.Slice(descriptions, func(i, j int) bool {
+		return descriptions[i].Name < descriptions[j].Name
+	})
+	if len(configReq.Resources) == 0 {
+		return descriptions, nil
+	}
+
+	configRes, err := a.client.DescribeConfigs(ctx, configReq)
+	if err != nil {
+		return nil, err
+	}
+	configs := make(map[string][]TopicConfigEntry, len(configRes.Resources))
+	for _, resource := range configRes.Resources {
+		if resource.Error != nil {
+			return nil, fmt.Errorf("describe configs of topic %s: %w", resource.ResourceName, resource.Error)
+		}
+		entries := make([]TopicConfigEntry, 0, len(resource.ConfigEntries))
+		for _, entry := range resource.ConfigEntries {
+			entries = append(entries, TopicConfigEntry{
+				Name:      entry.ConfigName,
+				Value:     entry.ConfigValue,
+				IsDefault: entry.IsDefault,
+				ReadOnly:  entry.ReadOnly,
+				Sensitive: entry.IsSensitive,
+			})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name < entries[j].Name
+		})
+		configs[resource.ResourceName] = entries
+	}
+	for i := range descriptions {
+		if entries, ok := configs[descriptions[i].Name]; ok {
+			descriptions[i].Configs = entries
+		}
+	}
+	return descriptions, nil
+}
+
+// AddPartitions 增加 topic 的分区，totalCount 为增加后的分区总数
+func (a *Admin) AddPartitions(ctx context.Context, topic string, totalCount int) error {
+	res, err := a.client.CreatePartitions(ctx, &kafka.CreatePartitionsRequest{
+		Topics: []kafka.TopicPartitionsConfig{{Name: topic, Count: int32(totalCount)}},
+	})
+	if err != nil {
+		return err
+	}
+	return firstError("add partitions to topic", res.Errors)
+}
+
+// AlterTopicConfigs 修改 topic 的配置，configs 中的配置被设置为新的值，deletes 中的配置恢复为默认值，其他配置保持不变
+func (a *Admin) AlterTopicConfigs(ctx context.Context, topic string, configs map[string]string, deletes ...string) error {
+	resource := kafka.IncrementalAlterConfigsRequestResource{
+		ResourceType: kafka.ResourceTypeTopic,
+		ResourceName: topic,
+	}
+	for _, name := range sortedKeys(configs) {
+		resource.Configs = append(resource.Configs, kafka.IncrementalAlterConfigsRequestConfig{
+			Name:            name,
+			Value:           configs[name],
+			ConfigOperation: kafka.ConfigOperationSet,
+		})
+	}
+	for _, name := range deletes {
+		resource.Configs = append(resource.Configs, kafka.IncrementalAlterConfigsRequestConfig{
+			Name:            name,
+			ConfigOperation: kafka.ConfigOperationDelete,
+		})
+	}
+	res, err := a.client.IncrementalAlterConfigs(ctx, &kafka.IncrementalAlterConfigsRequest{
+		Resources: []kafka.IncrementalAlterConfigsRequestResource{resource},
+	})
+	if err != nil {
+		return err
+	}
+	for _, resource := range res.Resources {
+		if resource.Error != nil {
+			return fmt.Errorf("alter configs of topic %s: %w", resource.ResourceName, resource.Error)
+		}
+	}
+	return nil
+}
+
+// ListGroups 返回所有 broker 上的消费组
+func (a *Admin) ListGroups(ctx context.Context) ([]string, error) {
+	res, err := a.client.ListGroups(ctx, &kafka.ListGroupsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	groups := make([]string, 0, len(res.Groups))
+	for _, group := range res.Groups {
+		groups = append(groups, group.GroupID)
+	}
+	sort.Strings(groups)
+	return groups, nil
+}
+
+// DescribeGroups 返回消费组的状态以及成员分配到的分区
+func (a *Admin) DescribeGroups(ctx context.Context, groupIDs ...string) ([]GroupDescription, error) {
+	res, err := a.client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: groupIDs})
+	if err != nil {
+		return nil, err
+	}
+	descriptions := make([]GroupDescription, 0, len(res.Groups))
+	for _, group := range res.Groups {
+		if group.Error != nil {
+			return nil, fmt.Errorf("describe group %s: %w", group.GroupID, group.Error)
+		}
+		descriptions = append(descriptions, GroupDescription{GroupID: group.GroupID, State: group.GroupState, Members: groupMembers(group)})
+	}
+	sort.Slice(descriptions, func(i, j int) bool {
+		return descriptions[i].GroupID < descriptions[j].GroupID
+	})
+	return descriptions, nil
+}
+
+// DescribeGroupOffsets 返回消费组在 topic 各分区上已提交的 offset 以及 lag
+func (a *Admin) DescribeGroupOffsets(ctx context.Context, groupID string, topic string) ([]PartitionStats, error) {
+	s := ConsumerStats{GroupID: groupID, Topic: topic}
+	if err := a.cmp.collectGroupStats(ctx, &s, false, nil); err != nil {
+		return nil, err
+	}
+	return s.Partitions, nil
+}
+
+// ResetGroupOffsets 重置消费组在 topic 上的 offset，partitions 为空时重置所有分区，返回重置后的 offset。
+// 只有消费组没有活跃成员时才能重置，否则返回 ErrGroupNotEmpty
+func (a *Admin) ResetGroupOffsets(ctx context.Context, groupID string, topic string, spec OffsetResetSpec, partitions ...int) ([]TopicPartition, error) {
+	groups, err := a.DescribeGroups(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if group.State != groupStateEmpty && group.State != groupStateDead {
+			return nil, fmt.Errorf("reset offsets of group %s, state %s: %w", groupID, group.State, ErrGroupNotEmpty)
+		}
+	}
+
+	if len(partitions) == 0 {
+		partitions, err = a.cmp.topicPartitions(ctx, topic)
+		if err != nil {
+			return nil, err
+		}
+	}
+	offsets, err := a.listOffsets(ctx, topic, partitions, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make([]kafka.OffsetCommit, 0, len(offsets))
+	for _, offset := range offsets {
+		commits = append(commits, kafka.OffsetCommit{Partition: offset.Partition, Offset: offset.Offset})
+	}
+	res, err := a.client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      groupID,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{topic: commits},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, partition := range res.Topics[topic] {
+		if partition.Error != nil {
+			return nil, fmt.Errorf("reset offset of %s[%d]: %w", topic, partition.Partition, partition.Error)
+		}
+	}
+	return offsets, nil
+}
+
+// listOffsets resolves the offsets of the partitions by the spec.
+func (a *Admin) listOffsets(ctx context.Context, topic string, partitions []int, spec OffsetResetSpec) ([]TopicPartition, error) {
+	requests := make([]kafka.OffsetRequest, 0, 3*len(partitions))
+	for _, partition := range partitions {
+		requests = append(requests, kafka.FirstOffsetOf(partition), kafka.LastOffsetOf(partition))
+		if spec.kind == offsetResetTimestamp {
+			requests = append(requests, kafka.TimeOffsetOf(partition, spec.timestamp))
+		}
+	}
+	res, err := a.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{topic: requests}})
+	if err != nil {
+		return nil, err
+	}
+	watermarks, err := partitionOffsets(topic, res)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make([]TopicPartition, 0, len(partitions))
+	for _, partition := range partitions {
+		watermark, ok := watermarks[partition]
+		if !ok {
+			return nil, fmt.Errorf("offsets of %s[%d] not found", topic, partition)
+		}
+		// 按时间查询的结果，broker 没有找到消息时 offset 为 -1
+		timeOffset := int64(-1)
+		for offset := range watermark.Offsets {
+			if offset >= 0 {
+				timeOffset = offset
+			}
+		}
+		offsets = append(offsets, TopicPartition{
+			Topic:     topic,
+			Partition: partition,
+			Offset:    spec.resolve(watermark.FirstOffset, watermark.LastOffset, timeOffset),
+		})
+	}
+	sort.Slice(offsets, func(i, j int) bool {
+		return offsets[i].Partition < offsets[j].Partition
+	})
+	return offsets, nil
+}
+
+func partitionOffsets(topic string, res *kafka.ListOffsetsResponse) (map[int]kafka.PartitionOffsets, error) {
+	offsets := make(map[int]kafka.PartitionOffsets, len(res.Topics[topic]))
+	for _, partition := range res.Topics[topic] {
+		if partition.Error != nil {
+			return nil, fmt.Errorf("list offsets of %s[%d]: %w", topic, partition.Partition, partition.Error)
+		}
+		offsets[partition.Partition] = partition
+	}
+	return offsets, nil
+}
+
+// firstError returns the error of the first resource in name order.
+func firstError(action string, errs map[string]error) error {
+	names := make([]string, 0, len(errs))
+	for name, err := range errs {
+		if err != nil {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return fmt.Errorf("%s %s: %w", action, names[0], errs[names[0]])
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func brokerIDs(brokers []kafka.Broker) []int {
+	ids := make([]int, 0, len(brokers))
+	for _, broker := range brokers {
+		ids = append(ids, broker.ID)
+	}
+	return ids
+}
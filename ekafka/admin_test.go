@@ -0,0 +1,85 @@
+package ekafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego-component/ekafka/ekafkatest"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffsetResetSpecResolve(t *testing.T) {
+	assert.Equal(t, int64(10), ResetToEarliest().resolve(10, 100, -1))
+	assert.Equal(t, int64(100), ResetToLatest().resolve(10, 100, -1))
+	assert.Equal(t, int64(42), ResetToTimestamp(time.Now()).resolve(10, 100, 42))
+	// 没有找到时间戳之后的消息
+	assert.Equal(t, int64(100), ResetToTimestamp(time.Now()).resolve(10, 100, -1))
+	assert.Equal(t, int64(50), ResetToOffset(50).resolve(10, 100, -1))
+	// 超出分区范围
+	assert.Equal(t, int64(10), ResetToOffset(0).resolve(10, 100, -1))
+	assert.Equal(t, int64(100), ResetToOffset(200).resolve(10, 100, -1))
+}
+
+func TestOffsetResetSpecString(t *testing.T) {
+	assert.Equal(t, "earliest", ResetToEarliest().String())
+	assert.Equal(t, "latest", ResetToLatest().String())
+	assert.Equal(t, "offset:5", ResetToOffset(5).String())
+	assert.Equal(t, "timestamp:2021-01-02T03:04:05Z", ResetToTimestamp(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)).String())
+}
+
+func TestFirstError(t *testing.T) {
+	assert.NoError(t, firstError("create topic", map[string]error{"a": nil}))
+
+	err := firstError("create topic", map[string]error{
+		"b": kafka.TopicAlreadyExists,
+		"a": kafka.InvalidPartitionNumber,
+		"c": nil,
+	})
+	assert.EqualError(t, err, "create topic a: "+kafka.InvalidPartitionNumber.Error())
+	assert.True(t, errors.Is(err, kafka.InvalidPartitionNumber))
+}
+
+func TestAdmin(t *testing.T) {
+	broker, err := ekafkatest.NewBroker()
+	require.NoError(t, err)
+	defer broker.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	admin := DefaultContainer().Build(WithBrokers(broker.Addr()), WithDebug(false)).Admin()
+	require.NoError(t, admin.CreateTopics(ctx, TopicSpec{Name: "test", NumPartitions: 2}))
+	assert.True(t, errors.Is(admin.CreateTopics(ctx, TopicSpec{Name: "test"}), kafka.TopicAlreadyExists))
+	topics, err := admin.ListTopics(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test"}, topics)
+
+	for partition := 0; partition < 2; partition++ {
+		_, err = broker.Produce("test", partition, Message{Value: []byte("1")}, Message{Value: []byte("2")}, Message{Value: []byte("3")})
+		require.NoError(t, err)
+	}
+	offsets, err := admin.ResetGroupOffsets(ctx, "group", "test", ResetToOffset(1))
+	require.NoError(t, err)
+	assert.Len(t, offsets, 2)
+	assert.Equal(t, int64(1), broker.CommittedOffset("group", "test", 1))
+
+	partitions, err := admin.DescribeGroupOffsets(ctx, "group", "test")
+	require.NoError(t, err)
+	require.Len(t, partitions, 2)
+	for _, partition := range partitions {
+		assert.Equal(t, int64(1), partition.CommittedOffset)
+		assert.Equal(t, int64(3), partition.HighWatermark)
+		assert.Equal(t, int64(2), partition.Lag)
+	}
+
+	require.NoError(t, admin.AddPartitions(ctx, "test", 3))
+	_, err = broker.Produce("test", 2, Message{Value: []byte("1")})
+	assert.NoError(t, err)
+
+	// kafka-go 会缓存 metadata，删除后直接检查 broker
+	require.NoError(t, admin.DeleteTopics(ctx, "test"))
+	assert.Nil(t, broker.Messages("test", 0))
+}
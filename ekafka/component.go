@@ -20,18 +20,36 @@ type Component struct {
 	client          *Client
 	consumers       map[string]*Consumer
 	producers       map[string]*Producer
+	txnProducers    map[string]*TxnProducer
 	consumerGroups  map[string]*ConsumerGroup
 	clientOnce      sync.Once
 	consumerMu      sync.RWMutex
 	producerMu      sync.RWMutex
+	txnProducerMu   sync.Mutex
 	consumerGroupMu sync.RWMutex
 	compName        string
+	lagCollector    *lagCollector
+	closeOnce       sync.Once
 }
 
 func (cmp *Component) GetCompName() string {
 	return cmp.compName
 }
 
+// Close 停止 lag 监控的上报，并从 /debug/kafka/stats 中移除组件。Producer、Consumer 需要各自调用 Close 关闭
+func (cmp *Component) Close() error {
+	cmp.closeOnce.Do(func() {
+		// 同名组件重新 Build 后，不能删除新的组件
+		if val, ok := instances.Load(cmp.compName); ok && val == cmp {
+			instances.Delete(cmp.compName)
+		}
+		if cmp.lagCollector != nil {
+			cmp.lagCollector.Close()
+		}
+	})
+	return nil
+}
+
 func (cmp *Component) interceptorClientChain() func(oldProcess clientProcessFn) clientProcessFn {
 	return InterceptorClientChain(cmp.config.clientInterceptors...)
 }
@@ -75,7 +93,7 @@ func (cmp *Component) Producer(name string) *Producer {
 		))
 	}
 
-	mechanism, err := NewMechanism(cmp.config.SASLMechanism, cmp.config.SASLUserName, cmp.config.SASLPassword)
+	mechanism, err := NewMechanism(cmp.config.SASLMechanism, cmp.config.SASLUserName, cmp.config.SASLPassword, cmp.config.mechanismOptions()...)
 	if err != nil {
 		cmp.producerMu.Unlock()
 		cmp.logger.Panic(
@@ -86,12 +104,13 @@ func (cmp *Component) Producer(name string) *Producer {
 	}
 
 	var transport kafka.RoundTripper
-	if mechanism != nil {
+	if mechanism != nil || cmp.config.tlsConfig != nil {
 		cmp.logger.Debug(
 			"new transport with sasl mechanism",
 			elog.String("mechanism", cmp.config.SASLMechanism),
 			elog.String("username", cmp.config.SASLUserName),
 			elog.String("password", cmp.config.SASLPassword),
+			elog.Any("tls", cmp.config.tlsConfig != nil),
 		)
 		transport = cmp.newProducerSASLTransport(mechanism)
 	}
@@ -130,6 +149,76 @@ func (cmp *Component) Producer(name string) *Producer {
 	return cmp.producers[name]
 }
 
+// TxnProducer 返回指定名称的幂等或事务 Producer，配置了 transactionalID 时为事务 Producer
+func (cmp *Component) TxnProducer(name string) *TxnProducer {
+	cmp.txnProducerMu.Lock()
+	defer cmp.txnProducerMu.Unlock()
+
+	if producer, ok := cmp.txnProducers[name]; ok {
+		return producer
+	}
+
+	config, ok := cmp.config.Producers[name]
+	if !ok {
+		cmp.logger.Panic("producer config not exists", elog.String("name", name))
+	}
+	if config.TransactionalID == "" && !config.Idempotent {
+		cmp.logger.Panic("producer is neither idempotent nor transactional", elog.String("name", name))
+	}
+	if config.Balancer == "" {
+		config.Balancer = balancerRoundRobin
+	}
+	balancer, ok := cmp.config.balancers[config.Balancer]
+	if !ok {
+		cmp.logger.Panic("producer.Balancer is not in registered balancers", elog.String("name", name), elog.String("balancer", config.Balancer))
+	}
+	mechanism, err := NewMechanism(cmp.config.SASLMechanism, cmp.config.SASLUserName, cmp.config.SASLPassword, cmp.config.mechanismOptions()...)
+	if err != nil {
+		cmp.logger.Panic("create mechanism error", elog.String("mechanism", cmp.config.SASLMechanism), elog.String("errorDetail", err.Error()))
+	}
+
+	// 幂等写入要求 acks=all，请求由 TxnProducer 自行编码，不使用 kafka.Writer
+	transport := kafka.DefaultTransport
+	if mechanism != nil || cmp.config.tlsConfig != nil {
+		transport = cmp.newProducerSASLTransport(mechanism)
+	}
+	producer := &TxnProducer{
+		topic:           config.Topic,
+		transactionalID: config.TransactionalID,
+		txnTimeout:      config.TransactionTimeout,
+		writeTimeout:    config.WriteTimeout,
+		maxAttempts:     config.MaxAttempts,
+		batchSize:       config.BatchSize,
+		balancer:        balancer,
+		compression:     kafka.Compression(config.Compression),
+		addr:            kafka.TCP(cmp.config.Brokers...),
+		transport:       transport,
+		client: &kafka.Client{
+			Addr:      kafka.TCP(cmp.config.Brokers...),
+			Timeout:   cmp.config.Client.Timeout,
+			Transport: transport,
+		},
+		logMode:       cmp.config.Debug,
+		producerID:    -1,
+		producerEpoch: -1,
+	}
+	if producer.txnTimeout <= 0 {
+		producer.txnTimeout = defaultTransactionTimeout
+	}
+	if producer.writeTimeout <= 0 {
+		producer.writeTimeout = defaultTxnWriteTimeout
+	}
+	if producer.maxAttempts <= 0 {
+		producer.maxAttempts = defaultTxnMaxAttempts
+	}
+	if producer.batchSize <= 0 {
+		producer.batchSize = defaultTxnBatchSize
+	}
+	producer.setProcessor(cmp.interceptorClientChain())
+	cmp.txnProducers[name] = producer
+	return producer
+}
+
 // Consumer 返回指定名称的kafka Consumer
 func (cmp *Component) Consumer(name string) *Consumer {
 	cmp.consumerMu.RLock()
@@ -154,7 +243,7 @@ func (cmp *Component) Consumer(name string) *Consumer {
 	}
 	logger := newKafkaLogger(cmp.logger)
 	errorLogger := newKafkaErrorLogger(cmp.logger)
-	mechanism, err := NewMechanism(cmp.config.SASLMechanism, cmp.config.SASLUserName, cmp.config.SASLPassword)
+	mechanism, err := NewMechanism(cmp.config.SASLMechanism, cmp.config.SASLUserName, cmp.config.SASLPassword, cmp.config.mechanismOptions()...)
 	if err != nil {
 		cmp.consumerMu.Unlock()
 		cmp.logger.Panic("create mechanism error", elog.String("mechanism", cmp.config.SASLMechanism), elog.String("errorDetail", err.Error()))
@@ -184,13 +273,10 @@ func (cmp *Component) Consumer(name string) *Consumer {
 		ReadBackoffMax:         config.ReadBackoffMax,
 	}
 
-	if mechanism != nil {
-		dialer := &kafka.Dialer{
-			DualStack:     true,
-			SASLMechanism: mechanism,
-		}
-		readerConfig.Dialer = dialer
+	if config.ReadCommitted {
+		readerConfig.IsolationLevel = kafka.ReadCommitted
 	}
+	readerConfig.Dialer = newDialer(mechanism, cmp.config.tlsConfig)
 
 	consumer := &Consumer{
 		r: kafka.NewReader(readerConfig),
@@ -247,6 +333,8 @@ func (cmp *Component) ConsumerGroup(name string) *ConsumerGroup {
 		SASLMechanism:          cmp.config.SASLMechanism,
 		SASLUserName:           cmp.config.SASLUserName,
 		SASLPassword:           cmp.config.SASLPassword,
+		TLS:                    cmp.config.tlsConfig,
+		SASLOptions:            cmp.config.mechanismOptions(),
 		Reader: readerOptions{
 			MinBytes:        config.MinBytes,
 			MaxBytes:        config.MaxBytes,
@@ -255,6 +343,7 @@ func (cmp *Component) ConsumerGroup(name string) *ConsumerGroup {
 			CommitInterval:  config.CommitInterval,
 			ReadBackoffMin:  config.ReadBackoffMin,
 			ReadBackoffMax:  config.ReadBackoffMax,
+			ReadCommitted:   config.ReadCommitted,
 		},
 		logMode: cmp.config.Debug,
 	})
@@ -272,12 +361,12 @@ func (cmp *Component) ConsumerGroup(name string) *ConsumerGroup {
 // Client 返回kafka Client
 func (cmp *Component) Client() *Client {
 	cmp.clientOnce.Do(func() {
-		mechanism, err := NewMechanism(cmp.config.SASLMechanism, cmp.config.SASLUserName, cmp.config.SASLPassword)
+		mechanism, err := NewMechanism(cmp.config.SASLMechanism, cmp.config.SASLUserName, cmp.config.SASLPassword, cmp.config.mechanismOptions()...)
 		if err != nil {
 			cmp.logger.Panic("create mechanism error", elog.String("mechanism", cmp.config.SASLMechanism), elog.String("errorDetail", err.Error()))
 		}
 		var transport kafka.RoundTripper
-		if mechanism != nil {
+		if mechanism != nil || cmp.config.tlsConfig != nil {
 			transport = cmp.newProducerSASLTransport(mechanism)
 		}
 		cmp.client = &Client{
@@ -296,12 +385,13 @@ func (cmp *Component) Client() *Client {
 	return cmp.client
 }
 
-// newProducerSASLTransport make transport with SASL mechanism
+// newProducerSASLTransport make transport with SASL mechanism and TLS config, both are optional
 func (cmp *Component) newProducerSASLTransport(m sasl.Mechanism) kafka.RoundTripper {
 	// Upgrade kafka-go >= v0.4.31 fixed "WriteMessages got unexpected EOF" issue
 	// Reference:
 	// https://github.com/segmentio/kafka-go/pull/869
 	return &kafka.Transport{
 		SASL: m,
+		TLS:  cmp.config.tlsConfig,
 	}
 }
@@ -1,6 +1,7 @@
 package ekafka
 
 import (
+	"crypto/tls"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -31,6 +32,41 @@ type config struct {
 	EnableAccessInterceptorRes bool // 是否开启记录响应参数，默认不开启
 	EnableMetricInterceptor    bool // 是否开启监控，默认开启
 
+	// EnableTLS 是否使用 TLS 连接 brokers，配置了 TLSCaFile 或 TLSCertFile 时默认开启
+	EnableTLS bool `json:"enableTLS" toml:"enableTLS"`
+	// TLSCaFile 校验 brokers 证书的 CA 证书，为空时使用系统 CA
+	TLSCaFile string `json:"tlsCaFile" toml:"tlsCaFile"`
+	// TLSCertFile 客户端证书，用于 mTLS 双向认证
+	TLSCertFile string `json:"tlsCertFile" toml:"tlsCertFile"`
+	// TLSKeyFile 客户端证书私钥
+	TLSKeyFile string `json:"tlsKeyFile" toml:"tlsKeyFile"`
+	// TLSServerName 校验证书使用的域名，为空时使用 broker 地址中的域名
+	TLSServerName string `json:"tlsServerName" toml:"tlsServerName"`
+	// TLSInsecureSkipVerify 是否跳过 brokers 证书校验，仅用于测试环境
+	TLSInsecureSkipVerify bool `json:"tlsInsecureSkipVerify" toml:"tlsInsecureSkipVerify"`
+	tlsConfig             *tls.Config
+
+	// SASLOAuth saslMechanism 为 OAUTHBEARER 时，使用 client credentials 获取 token 的配置
+	SASLOAuth saslOAuthConfig `json:"saslOAuth" toml:"saslOAuth"`
+	// SASLAWSRegion saslMechanism 为 AWS_MSK_IAM 时 MSK 集群所在的 region，为空时使用环境变量 AWS_REGION
+	SASLAWSRegion    string `json:"saslAWSRegion" toml:"saslAWSRegion"`
+	oauthTokenSource OAuthBearerTokenSource
+	awsCredentials   AWSCredentialsProvider
+
+	// EnableLagMetric 是否定期上报 Consumer、ConsumerGroup 各分区的 lag、已提交 offset、high watermark 以及分配情况，默认不开启
+	EnableLagMetric bool `json:"enableLagMetric" toml:"enableLagMetric"`
+	// LagMetricInterval 上报 lag 监控的周期，默认30s
+	LagMetricInterval time.Duration `json:"lagMetricInterval" toml:"lagMetricInterval"`
+}
+
+type saslOAuthConfig struct {
+	// TokenURL 获取 token 的地址
+	TokenURL     string   `json:"tokenURL" toml:"tokenURL"`
+	ClientID     string   `json:"clientID" toml:"clientID"`
+	ClientSecret string   `json:"clientSecret" toml:"clientSecret"`
+	Scopes       []string `json:"scopes" toml:"scopes"`
+	// Extensions SASL 扩展参数，如 Confluent Cloud 的 logicalCluster、identityPoolId
+	Extensions map[string]string `json:"extensions" toml:"extensions"`
 }
 
 type clientConfig struct {
@@ -68,6 +104,12 @@ type producerConfig struct {
 	// compress.Lz4 (3)
 	// compress.Zstd (4)
 	Compression int `json:"compression"  toml:"compression"`
+	// Idempotent 幂等写入，只对 TxnProducer 生效，写入一个分区的请求重试时不会产生重复消息
+	Idempotent bool `json:"idempotent" toml:"idempotent"`
+	// TransactionalID 事务ID，配置后 TxnProducer 为事务 Producer，同时开启幂等写入
+	TransactionalID string `json:"transactionalID" toml:"transactionalID"`
+	// TransactionTimeout 事务超时时间，超时未提交的事务会被 coordinator 回滚，默认60s
+	TransactionTimeout time.Duration `json:"transactionTimeout" toml:"transactionTimeout"`
 }
 
 type consumerConfig struct {
@@ -99,6 +141,8 @@ type consumerConfig struct {
 	StartOffset       int64         `json:"startOffset" toml:"startOffset"`
 	ReadBackoffMin    time.Duration `json:"readBackoffMin" toml:"readBackoffMin"`
 	ReadBackoffMax    time.Duration `json:"readBackoffMax" toml:"readBackoffMax"`
+	// ReadCommitted 只读取已提交事务的消息
+	ReadCommitted bool `json:"readCommitted" toml:"readCommitted"`
 }
 
 type consumerGroupConfig struct {
@@ -122,6 +166,8 @@ type consumerGroupConfig struct {
 	CommitInterval  time.Duration `json:"commitInterval" toml:"commitInterval"`
 	ReadBackoffMin  time.Duration `json:"readBackoffMin" toml:"readBackoffMin"`
 	ReadBackoffMax  time.Duration `json:"readBackoffMax" toml:"readBackoffMax"`
+	// ReadCommitted 只读取已提交事务的消息
+	ReadCommitted bool `json:"readCommitted" toml:"readCommitted"`
 }
 
 const (
@@ -129,12 +175,22 @@ const (
 	balancerRoundRobin = "roundRobin"
 )
 
+// mechanismOptions returns the options of NewMechanism.
+func (c *config) mechanismOptions() []MechanismOption {
+	return []MechanismOption{
+		WithMechanismTokenSource(c.oauthTokenSource),
+		WithMechanismAWSRegion(c.SASLAWSRegion),
+		WithMechanismAWSCredentials(c.awsCredentials),
+	}
+}
+
 // DefaultConfig 返回默认配置
 func DefaultConfig() *config {
 	return &config{
 		Debug:                   true,
 		EnableTraceInterceptor:  true,
 		EnableMetricInterceptor: true,
+		LagMetricInterval:       30 * time.Second,
 		balancers: map[string]Balancer{
 			balancerHash:       &kafka.Hash{},
 			balancerRoundRobin: &kafka.RoundRobin{},
@@ -2,9 +2,11 @@ package ekafka
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"sync"
 	"time"
 
@@ -26,12 +28,30 @@ type RevokedPartitions struct {
 	Partitions []TopicPartition
 }
 
+// 当前实例在消费组中的状态
+const (
+	ConsumerGroupStateJoining     = "joining"
+	ConsumerGroupStateStable      = "stable"
+	ConsumerGroupStateRebalancing = "rebalancing"
+	ConsumerGroupStateClosed      = "closed"
+	ConsumerGroupStateFailed      = "failed"
+)
+
+// ConsumerGroupStatus 当前实例在消费组中的状态以及分配到的分区
+type ConsumerGroupStatus struct {
+	State        string           `json:"state"`
+	GenerationID int32            `json:"generationId"`
+	MemberID     string           `json:"memberId"`
+	Assignments  []TopicPartition `json:"assignments"`
+}
+
 type ConsumerGroup struct {
 	logger     *elog.Component
 	group      *kafka.ConsumerGroup
 	events     chan interface{}
 	options    *ConsumerGroupOptions
 	currentGen *kafka.Generation
+	state      string
 	genMu      sync.RWMutex
 	readerWg   sync.WaitGroup
 	processor  ClientInterceptor
@@ -59,6 +79,8 @@ type readerOptions struct {
 	CommitInterval  time.Duration
 	ReadBackoffMin  time.Duration
 	ReadBackoffMax  time.Duration
+	// ReadCommitted 只读取已提交事务的消息
+	ReadCommitted bool
 }
 
 type ConsumerGroupOptions struct {
@@ -80,12 +102,16 @@ type ConsumerGroupOptions struct {
 	SASLUserName           string
 	SASLPassword           string
 	SASLMechanism          string
+	// TLS 为空时不使用 TLS 连接
+	TLS *tls.Config
+	// SASLOptions OAUTHBEARER、AWS_MSK_IAM 认证所需的参数
+	SASLOptions []MechanismOption
 }
 
 func NewConsumerGroup(options ConsumerGroupOptions) (*ConsumerGroup, error) {
 	logger := newKafkaLogger(options.Logger)
 	errorLogger := newKafkaErrorLogger(options.Logger)
-	mechanism, err := NewMechanism(options.SASLMechanism, options.SASLUserName, options.SASLPassword)
+	mechanism, err := NewMechanism(options.SASLMechanism, options.SASLUserName, options.SASLPassword, options.SASLOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -107,13 +133,7 @@ func NewConsumerGroup(options ConsumerGroupOptions) (*ConsumerGroup, error) {
 		ErrorLogger:            errorLogger,
 	}
 
-	if mechanism != nil {
-		dialer := &kafka.Dialer{
-			DualStack:     true,
-			SASLMechanism: mechanism,
-		}
-		readerConfig.Dialer = dialer
-	}
+	readerConfig.Dialer = newDialer(mechanism, options.TLS)
 	group, err := kafka.NewConsumerGroup(readerConfig)
 	if err != nil {
 		return nil, err
@@ -125,6 +145,7 @@ func NewConsumerGroup(options ConsumerGroupOptions) (*ConsumerGroup, error) {
 		events: make(chan interface{}, 100),
 		//processor: defaultProcessor,
 		options: &options,
+		state:   ConsumerGroupStateJoining,
 	}
 	go cg.run()
 
@@ -143,6 +164,14 @@ func (cg *ConsumerGroup) run() {
 		gen, err := cg.group.Next(context.TODO())
 		cg.genMu.Lock()
 		cg.currentGen = gen
+		switch {
+		case err == nil:
+			cg.state = ConsumerGroupStateStable
+		case errors.Is(err, kafka.ErrGroupClosed):
+			cg.state = ConsumerGroupStateClosed
+		default:
+			cg.state = ConsumerGroupStateFailed
+		}
 		cg.genMu.Unlock()
 		if err != nil {
 			if errors.Is(err, kafka.ErrGroupClosed) {
@@ -176,7 +205,7 @@ func (cg *ConsumerGroup) run() {
 
 			logger := newKafkaLogger(cg.logger)
 			errorLogger := newKafkaErrorLogger(cg.logger)
-			mechanism, err := NewMechanism(cg.options.SASLMechanism, cg.options.SASLUserName, cg.options.SASLPassword)
+			mechanism, err := NewMechanism(cg.options.SASLMechanism, cg.options.SASLUserName, cg.options.SASLPassword, cg.options.SASLOptions...)
 			if err != nil {
 				logger.Panic("create mechanism error", elog.String("mechanism", cg.options.SASLMechanism), elog.String("errorDetail", err.Error()))
 			}
@@ -196,13 +225,10 @@ func (cg *ConsumerGroup) run() {
 				ReadBackoffMax:  cg.options.Reader.ReadBackoffMax,
 			}
 
-			if mechanism != nil {
-				dialer := &kafka.Dialer{
-					DualStack:     true,
-					SASLMechanism: mechanism,
-				}
-				readerConfig.Dialer = dialer
+			if cg.options.Reader.ReadCommitted {
+				readerConfig.IsolationLevel = kafka.ReadCommitted
 			}
+			readerConfig.Dialer = newDialer(mechanism, cg.options.TLS)
 			gen.Start(func(ctx context.Context) {
 				reader := kafka.NewReader(readerConfig)
 				defer reader.Close()
@@ -218,6 +244,7 @@ func (cg *ConsumerGroup) run() {
 					case kafka.ErrGenerationEnded:
 						// emit RevokedPartitions event
 						revokeOnce.Do(func() {
+							cg.setState(ConsumerGroupStateRebalancing)
 							cg.events <- RevokedPartitions{
 								Partitions: topicPartitions,
 							}
@@ -239,6 +266,31 @@ func (cg *ConsumerGroup) run() {
 	}
 }
 
+func (cg *ConsumerGroup) setState(state string) {
+	cg.genMu.Lock()
+	cg.state = state
+	cg.genMu.Unlock()
+}
+
+// Status returns the state of the group member, and the partitions assigned to it in the current generation.
+func (cg *ConsumerGroup) Status() ConsumerGroupStatus {
+	cg.genMu.RLock()
+	defer cg.genMu.RUnlock()
+	status := ConsumerGroupStatus{State: cg.state, Assignments: make([]TopicPartition, 0)}
+	if cg.currentGen != nil && cg.state == ConsumerGroupStateStable {
+		status.GenerationID = cg.currentGen.ID
+		status.MemberID = cg.currentGen.MemberID
+		status.Assignments = createTopicPartitionsFromGenAssignments(cg.currentGen.Assignments)
+		sort.Slice(status.Assignments, func(i, j int) bool {
+			if status.Assignments[i].Topic != status.Assignments[j].Topic {
+				return status.Assignments[i].Topic < status.Assignments[j].Topic
+			}
+			return status.Assignments[i].Partition < status.Assignments[j].Partition
+		})
+	}
+	return status
+}
+
 func (cg *ConsumerGroup) Poll(ctx context.Context) (msg interface{}, err error) {
 	err = cg.processor(func(ctx context.Context, msgs Messages, c *cmd) error {
 		select {
@@ -300,6 +352,7 @@ func (cg *ConsumerGroup) Close() error {
 
 		err := cg.group.Close()
 		cg.readerWg.Wait()
+		cg.setState(ConsumerGroupStateClosed)
 		close(cg.events)
 		return err
 	})(context.Background(), nil, &cmd{})
@@ -0,0 +1,233 @@
+package consumerserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gotomicro/ego-component/ekafka"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/gotomicro/ego/core/etrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// batchTracer 为每一批消息创建 span，关联批次中每条消息的链路
+var batchTracer = etrace.NewTracer(trace.SpanKindConsumer)
+
+func (cmp *Component) launchOnConsumerEachBatch() error {
+	if cmp.onEachBatchHandler == nil {
+		return errors.New("you must define a BatchHandler first")
+	}
+	return cmp.launchConsumers(cmp.consumeEachBatch)
+}
+
+// consumeEachBatch fetches messages in batches, a batch is committed with a single commit after it is handled,
+// messages of retry topics are handled after the delay of their tiers.
+func (cmp *Component) consumeEachBatch(consumer *ekafka.Consumer, delayed bool, unrecoverableError chan<- error) {
+	var (
+		compNameTopic = fmt.Sprintf("%s.%s", cmp.ekafkaComponent.GetCompName(), consumer.Config.Topic)
+		brokers       = strings.Join(consumer.Brokers, ",")
+	)
+
+	// fetchFailed reports a fetch error, returns true if consuming should stop.
+	fetchFailed := func(err error) bool {
+		cmp.consumptionErrors <- err
+		cmp.logger.Error("encountered an error while fetching message", elog.FieldErr(err))
+
+		// If this error is unrecoverable, stop consuming.
+		if isErrorUnrecoverable(err) {
+			unrecoverableError <- err
+			return true
+		}
+		// Otherwise, try to fetch message again.
+		return false
+	}
+
+	for {
+		if cmp.ServerCtx.Err() != nil {
+			return
+		}
+		// The beginning of time monitoring point in time
+		now := time.Now()
+		messages, fetchCtx, fetchErr := cmp.fetchBatch(consumer)
+		if len(messages) == 0 {
+			if fetchFailed(fetchErr) {
+				return
+			}
+			continue
+		}
+
+		if delayed {
+			// 重试 topic 中的消息按写入顺序排列，等待每条消息到期即等待最晚的一条到期
+			for _, message := range messages {
+				if !cmp.waitRetryAt(message) {
+					return
+				}
+			}
+		}
+
+		batchCtx, span := startBatchSpan(fetchCtx, consumer.Config.Topic, messages)
+		if err := cmp.handleEachBatch(batchCtx, messages, compNameTopic, brokers, now); err != nil {
+			span.End()
+			unrecoverableError <- err
+			return
+		}
+
+		offsets := make([]*ekafka.Message, len(messages))
+		for i := range messages {
+			offsets[i] = &messages[i]
+		}
+		err := cmp.commit(batchCtx, consumer, compNameTopic, brokers, now, offsets...)
+		span.End()
+		if err != nil {
+			if cmp.ServerCtx.Err() == nil {
+				unrecoverableError <- err
+			}
+			return
+		}
+
+		// 拉取中途出错时，已拉取的消息处理并提交后再报告错误
+		if fetchErr != nil && fetchFailed(fetchErr) {
+			return
+		}
+	}
+}
+
+// messageFetcher fetches messages one by one, implemented by *ekafka.Consumer.
+type messageFetcher interface {
+	FetchMessage(ctx context.Context) (ekafka.Message, context.Context, error)
+}
+
+// fetchBatch blocks until the first message arrives, then fetches until BatchSize messages are fetched
+// or BatchMaxWait elapses. An error after the first message ends the batch early,
+// it is returned together with the messages fetched before it.
+// The returned ctx is the ctx of the first message, it carries the values of the message headers.
+func (cmp *Component) fetchBatch(consumer messageFetcher) ([]ekafka.Message, context.Context, error) {
+	message, fetchCtx, err := consumer.FetchMessage(cmp.ServerCtx)
+	if err != nil {
+		return nil, cmp.ServerCtx, err
+	}
+	messages := []ekafka.Message{message}
+
+	ctx, cancel := context.WithTimeout(cmp.ServerCtx, cmp.config.BatchMaxWait)
+	defer cancel()
+	for len(messages) < cmp.config.BatchSize {
+		message, _, err := consumer.FetchMessage(ctx)
+		if err != nil {
+			// 等待凑满一批超时或服务停止不是错误
+			if ctx.Err() != nil {
+				break
+			}
+			return messages, fetchCtx, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, fetchCtx, nil
+}
+
+// startBatchSpan starts the span of a batch, the span links the traces propagated in the headers of the messages.
+func startBatchSpan(ctx context.Context, topic string, messages []ekafka.Message) (context.Context, trace.Span) {
+	return batchTracer.Start(ctx, "kafka batch", nil,
+		trace.WithLinks(messageLinks(messages)...),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("kafka"),
+			semconv.MessagingDestinationKindKey.String(topic),
+			attribute.Int("messaging.batch.message_count", len(messages)),
+		),
+	)
+}
+
+// messageLinks returns the span contexts propagated in the headers of the messages by the producers.
+func messageLinks(messages []ekafka.Message) []trace.Link {
+	propagator := propagation.TraceContext{}
+	links := make([]trace.Link, 0, len(messages))
+	for _, message := range messages {
+		carrier := propagation.MapCarrier{}
+		for _, header := range message.Headers {
+			carrier[header.Key] = string(header.Value)
+		}
+		if sc := trace.SpanContextFromContext(propagator.Extract(context.Background(), carrier)); sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc})
+		}
+	}
+	return links
+}
+
+// handleEachBatch runs the batch handler, failed messages are forwarded to the retry pipeline,
+// the returned error stops consuming.
+func (cmp *Component) handleEachBatch(ctx context.Context, messages []ekafka.Message, compNameTopic, brokers string, now time.Time) error {
+	retryCount := 0
+
+HANDLER:
+
+	err := cmp.onEachBatchHandler(ctx, messages)
+	// 不包含任何失败消息的 BatchError 视为成功
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) && batchErr.Len() == 0 {
+		err = nil
+	}
+	// Record the redis time-consuming
+	emetric.ClientHandleHistogram.WithLabelValues("kafka", compNameTopic, "BATCH_HANDLER", brokers).Observe(time.Since(now).Seconds())
+	if err != nil {
+		emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "BATCH_HANDLER", brokers, "Error")
+	} else {
+		emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "BATCH_HANDLER", brokers, "OK")
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	cmp.logger.Error("encountered an error while handling batch", elog.FieldErr(err), elog.Int("size", len(messages)))
+	cmp.consumptionErrors <- err
+
+	// If it's a retryable error of the whole batch, we should execute the handler again.
+	if batchErr == nil && errors.Is(err, ErrRecoverableError) && retryCount < maxOnEachMessageHandlerRetryCount {
+		retryCount++
+		goto HANDLER
+	}
+	// Otherwise park the failed messages to the retry pipeline if configured, or it is considered as an
+	// unrecoverable error, developers should write their own retry logic in the handler.
+	if !cmp.hasRetryPipeline() {
+		return err
+	}
+	for _, failed := range failedMessages(messages, err) {
+		if forwardErr := cmp.forward(ctx, failed.message, failed.err); forwardErr != nil {
+			emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "FORWARD", brokers, "Error")
+			return fmt.Errorf("forward message failed: %w, handler error: %s", forwardErr, failed.err)
+		}
+		emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "FORWARD", brokers, "OK")
+	}
+	return nil
+}
+
+type failedMessage struct {
+	message ekafka.Message
+	err     error
+}
+
+// failedMessages returns the failed messages of a batch in order, all messages fail with err unless it is a *BatchError.
+func failedMessages(messages []ekafka.Message, err error) []failedMessage {
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		failed := make([]failedMessage, len(messages))
+		for i, message := range messages {
+			failed[i] = failedMessage{message: message, err: err}
+		}
+		return failed
+	}
+	failed := make([]failedMessage, 0, batchErr.Len())
+	for _, index := range batchErr.indexes() {
+		if index < 0 || index >= len(messages) {
+			continue
+		}
+		failed = append(failed, failedMessage{message: messages[index], err: batchErr.Errors[index]})
+	}
+	return failed
+}
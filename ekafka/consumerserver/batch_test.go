@@ -0,0 +1,108 @@
+package consumerserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego-component/ekafka"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailedMessages(t *testing.T) {
+	messages := []ekafka.Message{{Offset: 1}, {Offset: 2}, {Offset: 3}}
+
+	errBatch := errors.New("batch failed")
+	failed := failedMessages(messages, errBatch)
+	assert.Len(t, failed, 3)
+	for i, f := range failed {
+		assert.Equal(t, messages[i].Offset, f.message.Offset)
+		assert.Equal(t, errBatch, f.err)
+	}
+
+	err1 := errors.New("message 1 failed")
+	err3 := errors.New("message 3 failed")
+	batchErr := NewBatchError().Add(2, err3).Add(0, err1).Add(5, err1)
+	failed = failedMessages(messages, batchErr)
+	assert.Len(t, failed, 2)
+	assert.Equal(t, int64(1), failed[0].message.Offset)
+	assert.Equal(t, err1, failed[0].err)
+	assert.Equal(t, int64(3), failed[1].message.Offset)
+	assert.Equal(t, err3, failed[1].err)
+
+	// 包装后的 BatchError 同样能识别
+	failed = failedMessages(messages, fmt.Errorf("sink: %w", batchErr))
+	assert.Len(t, failed, 2)
+}
+
+func TestBatchError(t *testing.T) {
+	batchErr := NewBatchError()
+	assert.Equal(t, 0, batchErr.Len())
+	batchErr.Add(3, errors.New("b")).Add(1, errors.New("a"))
+	assert.Equal(t, 2, batchErr.Len())
+	assert.Equal(t, "2 messages of batch failed, first error at index 1: a", batchErr.Error())
+}
+
+type fakeFetcher struct {
+	messages []ekafka.Message
+	err      error
+}
+
+func (f *fakeFetcher) FetchMessage(ctx context.Context) (ekafka.Message, context.Context, error) {
+	if len(f.messages) == 0 {
+		if f.err != nil {
+			return ekafka.Message{}, ctx, f.err
+		}
+		<-ctx.Done()
+		return ekafka.Message{}, ctx, ctx.Err()
+	}
+	message := f.messages[0]
+	f.messages = f.messages[1:]
+	return message, context.WithValue(ctx, offsetContextKey{}, message.Offset), nil
+}
+
+// offsetContextKey 模拟 FetchMessage 从消息头中解析的 ctx 值
+type offsetContextKey struct{}
+
+func TestFetchBatch(t *testing.T) {
+	cmp := &Component{ServerCtx: context.Background(), config: DefaultConfig()}
+	cmp.config.BatchSize = 2
+	cmp.config.BatchMaxWait = 10 * time.Millisecond
+
+	fetcher := &fakeFetcher{messages: []ekafka.Message{{Offset: 1}, {Offset: 2}, {Offset: 3}}}
+	messages, ctx, err := cmp.fetchBatch(fetcher)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	// 返回第一条消息的 ctx
+	assert.Equal(t, int64(1), ctx.Value(offsetContextKey{}))
+	// 等待凑满一批超时
+	messages, _, err = cmp.fetchBatch(fetcher)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	// 中途出错时返回已拉取的消息和错误
+	errFetch := errors.New("fetch failed")
+	fetcher = &fakeFetcher{messages: []ekafka.Message{{Offset: 4}}, err: errFetch}
+	messages, _, err = cmp.fetchBatch(fetcher)
+	assert.ErrorIs(t, err, errFetch)
+	assert.Len(t, messages, 1)
+	messages, _, err = cmp.fetchBatch(fetcher)
+	assert.ErrorIs(t, err, errFetch)
+	assert.Len(t, messages, 0)
+}
+
+func TestMessageLinks(t *testing.T) {
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	messages := []ekafka.Message{
+		{Headers: []kafka.Header{{Key: "traceparent", Value: []byte(traceparent)}}},
+		// 没有链路信息的消息不关联
+		{},
+	}
+	links := messageLinks(messages)
+	assert.Len(t, links, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", links[0].SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", links[0].SpanContext.SpanID().String())
+}
@@ -30,6 +30,7 @@ const (
 	consumptionModeOnConsumerStart consumptionMode = iota + 1
 	consumptionModeOnConsumerEachMessage
 	consumptionModeOnConsumerGroupStart
+	consumptionModeOnConsumerEachBatch
 )
 
 // Component starts an Ego server for message consuming.
@@ -42,6 +43,7 @@ type Component struct {
 	logger                      *elog.Component
 	mode                        consumptionMode
 	onEachMessageHandler        OnEachMessageHandler
+	onEachBatchHandler          OnEachBatchHandler
 	onConsumerStartHandler      OnStartHandler
 	onConsumerGroupStartHandler OnConsumerGroupStartHandler
 	consumptionErrors           chan<- error
@@ -91,6 +93,8 @@ func (cmp *Component) Start() error {
 		return cmp.launchOnConsumerGroupStart()
 	case consumptionModeOnConsumerEachMessage:
 		return cmp.launchOnConsumerEachMessage()
+	case consumptionModeOnConsumerEachBatch:
+		return cmp.launchOnConsumerEachBatch()
 	default:
 		return fmt.Errorf("undefined consumption mode: %v", cmp.mode)
 	}
@@ -114,6 +118,14 @@ func (cmp *Component) OnEachMessage(consumptionErrors chan<- error, handler OnEa
 	return nil
 }
 
+// OnEachBatch registers a handler receiving up to BatchSize messages, or the messages fetched within BatchMaxWait.
+func (cmp *Component) OnEachBatch(consumptionErrors chan<- error, handler OnEachBatchHandler) error {
+	cmp.consumptionErrors = consumptionErrors
+	cmp.mode = consumptionModeOnConsumerEachBatch
+	cmp.onEachBatchHandler = handler
+	return nil
+}
+
 // OnStart ...
 func (cmp *Component) OnStart(handler OnStartHandler) error {
 	cmp.mode = consumptionModeOnConsumerStart
@@ -230,105 +242,35 @@ func (cmp *Component) launchOnConsumerStart() error {
 }
 
 func (cmp *Component) launchOnConsumerEachMessage() error {
-	consumer := cmp.Consumer()
 	if cmp.onEachMessageHandler == nil {
 		return errors.New("you must define a MessageHandler first")
 	}
+	return cmp.launchConsumers(cmp.consumeEachMessage)
+}
 
-	var (
-		compNameTopic = fmt.Sprintf("%s.%s", cmp.ekafkaComponent.GetCompName(), consumer.Config.Topic)
-		brokers       = strings.Join(consumer.Brokers, ",")
-	)
-
-	unrecoverableError := make(chan error)
-	go func() {
-		for {
-			if cmp.ServerCtx.Err() != nil {
-				return
-			}
-			// The beginning of time monitoring point in time
-			now := time.Now()
-			message, fetchCtx, err := consumer.FetchMessage(cmp.ServerCtx)
-			if err != nil {
-				cmp.consumptionErrors <- err
-				cmp.logger.Error("encountered an error while fetching message", elog.FieldErr(err))
-
-				// If this error is unrecoverable, stop consuming.
-				if isErrorUnrecoverable(err) {
-					unrecoverableError <- err
-					return
-				}
-				// Otherwise, try to fetch message again.
-				continue
-			}
-			retryCount := 0
-
-		HANDLER:
-
-			err = cmp.onEachMessageHandler(fetchCtx, message)
-			cmp.PackageName()
-			// Record the redis time-consuming
-			emetric.ClientHandleHistogram.WithLabelValues("kafka", compNameTopic, "HANDLER", brokers).Observe(time.Since(now).Seconds())
-			if err != nil {
-				emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "HANDLER", brokers, "Error")
-			} else {
-				emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "HANDLER", brokers, "OK")
-			}
-
-			if err != nil {
-				cmp.logger.Error("encountered an error while handling message", elog.FieldErr(err))
-				cmp.consumptionErrors <- err
-
-				// If it's a retryable error, we should execute the handler again.
-				if errors.Is(err, ErrRecoverableError) && retryCount < maxOnEachMessageHandlerRetryCount {
-					retryCount++
-					goto HANDLER
-				}
-				// Otherwise should be considered as an unrecoverable
-				// error, developers should write their own retry logic in the handler.
-				unrecoverableError <- err
-				return
-			}
-
-		COMMIT:
-			err = consumer.CommitMessages(fetchCtx, &message)
-
-			// Record the redis time-consuming
-			emetric.ClientHandleHistogram.WithLabelValues("kafka", compNameTopic, "COMMIT", brokers).Observe(time.Since(now).Seconds())
-			if err != nil {
-				emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "COMMIT", brokers, "Error")
-			} else {
-				emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "COMMIT", brokers, "OK")
-			}
-
-			if err != nil {
-				cmp.consumptionErrors <- err
-				cmp.logger.Error("encountered an error while committing message", elog.FieldErr(err))
-
-				// If this error is unrecoverable, stop retry and consuming.
-				if isErrorUnrecoverable(err) {
-					unrecoverableError <- err
-					return
-				}
-
-				if cmp.ServerCtx.Err() != nil {
-					return
-				}
-
-				// Try to commit this message again.
-				cmp.logger.Debug("try to commit message again")
-				goto COMMIT
-			}
+// launchConsumers runs consume with the default Consumer and the consumers of retry topics, until the server stops
+// or an unrecoverable error occurs.
+func (cmp *Component) launchConsumers(consume func(consumer *ekafka.Consumer, delayed bool, unrecoverableError chan<- error)) error {
+	consumer := cmp.Consumer()
+	consumers := []*ekafka.Consumer{consumer}
+	unrecoverableError := make(chan error, 1+len(cmp.config.RetryTiers))
+	go consume(consumer, false, unrecoverableError)
+	// 消费各级重试 topic
+	for _, tier := range cmp.config.RetryTiers {
+		if tier.ConsumerName == "" {
+			continue
 		}
-	}()
+		retryConsumer := cmp.ekafkaComponent.Consumer(tier.ConsumerName)
+		consumers = append(consumers, retryConsumer)
+		go consume(retryConsumer, true, unrecoverableError)
+	}
 
 	select {
 	case <-cmp.ServerCtx.Done():
 		rootErr := cmp.ServerCtx.Err()
 		cmp.logger.Error("terminating consumer because a context error", elog.FieldErr(rootErr))
 
-		err := cmp.closeConsumer(consumer)
-		if err != nil {
+		if err := cmp.closeConsumers(consumers); err != nil {
 			return fmt.Errorf("encountered an error while closing consumer: %w", err)
 		}
 
@@ -342,17 +284,139 @@ func (cmp *Component) launchOnConsumerEachMessage() error {
 			panic("unrecoverableError should receive an error instead of nil")
 		}
 
-		cmp.logger.Fatal("stopping server because of an unrecoverable error", elog.FieldErr(originErr))
+		cmp.logger.Error("stopping server because of an unrecoverable error", elog.FieldErr(originErr))
 		cmp.Stop()
 
-		err := cmp.closeConsumer(consumer)
-		if err != nil {
+		if err := cmp.closeConsumers(consumers); err != nil {
 			return fmt.Errorf("exiting due to an unrecoverable error, but encountered an error while closing consumer: %w", err)
 		}
 		return originErr
 	}
 }
 
+// consumeEachMessage fetches, handles and commits messages one by one,
+// messages of retry topics are handled after the delay of their tiers.
+func (cmp *Component) consumeEachMessage(consumer *ekafka.Consumer, delayed bool, unrecoverableError chan<- error) {
+	if cmp.config.Concurrency > 1 {
+		cmp.consumeConcurrently(consumer, delayed, unrecoverableError)
+		return
+	}
+
+	var (
+		compNameTopic = fmt.Sprintf("%s.%s", cmp.ekafkaComponent.GetCompName(), consumer.Config.Topic)
+		brokers       = strings.Join(consumer.Brokers, ",")
+	)
+
+	for {
+		if cmp.ServerCtx.Err() != nil {
+			return
+		}
+		// The beginning of time monitoring point in time
+		now := time.Now()
+		message, fetchCtx, err := consumer.FetchMessage(cmp.ServerCtx)
+		if err != nil {
+			cmp.consumptionErrors <- err
+			cmp.logger.Error("encountered an error while fetching message", elog.FieldErr(err))
+
+			// If this error is unrecoverable, stop consuming.
+			if isErrorUnrecoverable(err) {
+				unrecoverableError <- err
+				return
+			}
+			// Otherwise, try to fetch message again.
+			continue
+		}
+
+		if delayed && !cmp.waitRetryAt(message) {
+			return
+		}
+
+		if err := cmp.handleEachMessage(fetchCtx, message, compNameTopic, brokers, now); err != nil {
+			unrecoverableError <- err
+			return
+		}
+
+		if err := cmp.commit(fetchCtx, consumer, compNameTopic, brokers, now, &message); err != nil {
+			if cmp.ServerCtx.Err() == nil {
+				unrecoverableError <- err
+			}
+			return
+		}
+	}
+}
+
+// commit commits the message until it succeeds, returns an unrecoverable error or the error of the stopped server.
+func (cmp *Component) commit(ctx context.Context, consumer *ekafka.Consumer, compNameTopic, brokers string, now time.Time, messages ...*ekafka.Message) error {
+	for {
+		err := consumer.CommitMessages(ctx, messages...)
+
+		// Record the redis time-consuming
+		emetric.ClientHandleHistogram.WithLabelValues("kafka", compNameTopic, "COMMIT", brokers).Observe(time.Since(now).Seconds())
+		if err != nil {
+			emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "COMMIT", brokers, "Error")
+		} else {
+			emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "COMMIT", brokers, "OK")
+			return nil
+		}
+
+		cmp.consumptionErrors <- err
+		cmp.logger.Error("encountered an error while committing message", elog.FieldErr(err))
+
+		// If this error is unrecoverable, stop retry and consuming.
+		if isErrorUnrecoverable(err) {
+			return err
+		}
+
+		if cmp.ServerCtx.Err() != nil {
+			return cmp.ServerCtx.Err()
+		}
+
+		// Try to commit this message again.
+		cmp.logger.Debug("try to commit message again")
+	}
+}
+
+// handleEachMessage runs the handler, the message failed with an unrecoverable error is forwarded to
+// the retry pipeline, the returned error stops consuming.
+func (cmp *Component) handleEachMessage(ctx context.Context, message ekafka.Message, compNameTopic, brokers string, now time.Time) error {
+	retryCount := 0
+
+HANDLER:
+
+	err := cmp.onEachMessageHandler(ctx, message)
+	// Record the redis time-consuming
+	emetric.ClientHandleHistogram.WithLabelValues("kafka", compNameTopic, "HANDLER", brokers).Observe(time.Since(now).Seconds())
+	if err != nil {
+		emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "HANDLER", brokers, "Error")
+	} else {
+		emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "HANDLER", brokers, "OK")
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	cmp.logger.Error("encountered an error while handling message", elog.FieldErr(err))
+	cmp.consumptionErrors <- err
+
+	// If it's a retryable error, we should execute the handler again.
+	if errors.Is(err, ErrRecoverableError) && retryCount < maxOnEachMessageHandlerRetryCount {
+		retryCount++
+		goto HANDLER
+	}
+	// Otherwise park the message to the retry pipeline if configured, or it is considered as an unrecoverable
+	// error, developers should write their own retry logic in the handler.
+	if !cmp.hasRetryPipeline() {
+		return err
+	}
+	if forwardErr := cmp.forward(ctx, message, err); forwardErr != nil {
+		emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "FORWARD", brokers, "Error")
+		return fmt.Errorf("forward message failed: %w, handler error: %s", forwardErr, err)
+	}
+	emetric.ClientHandleCounter.Inc("kafka", compNameTopic, "FORWARD", brokers, "OK")
+	return nil
+}
+
 func (cmp *Component) closeConsumer(consumer *ekafka.Consumer) error {
 	if err := consumer.Close(); err != nil {
 		cmp.logger.Fatal("failed to close Consumer", elog.FieldErr(err))
@@ -362,6 +426,16 @@ func (cmp *Component) closeConsumer(consumer *ekafka.Consumer) error {
 	return nil
 }
 
+func (cmp *Component) closeConsumers(consumers []*ekafka.Consumer) error {
+	var firstErr error
+	for _, consumer := range consumers {
+		if err := cmp.closeConsumer(consumer); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 func (cmp *Component) closeConsumerGroup(consumerGroup *ekafka.ConsumerGroup) error {
 	if err := consumerGroup.Close(); err != nil {
 		cmp.logger.Fatal("failed to close ConsumerGroup", elog.FieldErr(err))
@@ -0,0 +1,87 @@
+package consumerserver
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gotomicro/ego-component/ekafka"
+	"github.com/gotomicro/ego-component/ekafka/ekafkatest"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnEachMessage(t *testing.T) {
+	broker, err := ekafkatest.NewBroker(ekafkatest.WithTopic("test", 2))
+	require.NoError(t, err)
+	defer broker.Close()
+
+	conf := `
+[kafka]
+	debug=false
+	[kafka.producers.p1]
+		topic="test"
+		requiredAcks=-1
+		batchTimeout="10ms"
+	[kafka.consumers.c1]
+		topic="test"
+		groupID="group"
+		heartbeatInterval="100ms"
+		maxWait="100ms"
+		startOffset=-2
+[kafkaConsumerServers.s1]
+	debug=false
+	consumerName="c1"
+`
+	require.NoError(t, econf.LoadFromReader(strings.NewReader(conf), toml.Unmarshal))
+	ekafkaComponent := ekafka.Load("kafka").Build(ekafka.WithBrokers(broker.Addr()))
+	require.NoError(t, ekafkaComponent.Producer("p1").WriteMessages(context.Background(),
+		&ekafka.Message{Key: []byte("a"), Value: []byte("1")},
+		&ekafka.Message{Key: []byte("b"), Value: []byte("2")},
+		&ekafka.Message{Key: []byte("c"), Value: []byte("3")},
+	))
+
+	cmp := Load("kafkaConsumerServers.s1").Build(WithEkafka(ekafkaComponent))
+	var (
+		mu     sync.Mutex
+		values []string
+	)
+	consumptionErrors := make(chan error, 16)
+	require.NoError(t, cmp.OnEachMessage(consumptionErrors, func(ctx context.Context, message ekafka.Message) error {
+		mu.Lock()
+		values = append(values, string(message.Value))
+		mu.Unlock()
+		return nil
+	}))
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- cmp.Start()
+	}()
+
+	// 所有消息处理完成后，offset 已提交
+	require.Eventually(t, func() bool {
+		committed := int64(0)
+		for partition := 0; partition < 2; partition++ {
+			if offset := broker.CommittedOffset("group", "test", partition); offset > 0 {
+				committed += offset
+			}
+		}
+		return committed == 3
+	}, 10*time.Second, 50*time.Millisecond)
+	mu.Lock()
+	assert.ElementsMatch(t, []string{"1", "2", "3"}, values)
+	mu.Unlock()
+	assert.Equal(t, ekafkatest.GroupStateStable, broker.Group("group").State)
+
+	require.NoError(t, cmp.Stop())
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("server is not stopped")
+	}
+}
@@ -0,0 +1,221 @@
+package consumerserver
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/ego-component/ekafka"
+	"github.com/gotomicro/ego/core/elog"
+)
+
+const (
+	// OrderByPartition 同一分区的消息按顺序处理
+	OrderByPartition = "partition"
+	// OrderByKey 同一 key 的消息按顺序处理，不同 key 的消息可以并发处理
+	OrderByKey = "key"
+)
+
+// workerQueueSize 每个 worker 等待处理的消息数量上限，超过后暂停拉取消息
+const workerQueueSize = 64
+
+// consumeConcurrently fetches messages on one goroutine and handles them on Concurrency workers.
+// Messages with the same partition (or key) are always handled by the same worker to keep their order,
+// offsets are committed only up to the highest contiguous handled offset of each partition.
+func (cmp *Component) consumeConcurrently(consumer *ekafka.Consumer, delayed bool, unrecoverableError chan<- error) {
+	var (
+		compNameTopic = fmt.Sprintf("%s.%s", cmp.ekafkaComponent.GetCompName(), consumer.Config.Topic)
+		brokers       = strings.Join(consumer.Brokers, ",")
+		tracker       = newOffsetTracker()
+		workers       = make([]chan *trackedOffset, cmp.config.Concurrency)
+		commitSignal  = make(chan struct{}, 1)
+		// 任意 goroutine 出现不可恢复错误时，停止拉取和处理
+		ctx, cancel = context.WithCancel(cmp.ServerCtx)
+		failOnce    sync.Once
+		wg          sync.WaitGroup
+	)
+	defer cancel()
+	fail := func(err error) {
+		failOnce.Do(func() {
+			if cmp.ServerCtx.Err() == nil {
+				unrecoverableError <- err
+			}
+			cancel()
+		})
+	}
+
+	for i := range workers {
+		workers[i] = make(chan *trackedOffset, workerQueueSize)
+		wg.Add(1)
+		go func(queue <-chan *trackedOffset) {
+			defer wg.Done()
+			for offset := range queue {
+				// 停止后不再处理排队中的消息，未提交的消息会被重新消费
+				if ctx.Err() != nil {
+					continue
+				}
+				if delayed && !cmp.waitRetryAt(offset.message) {
+					continue
+				}
+				if err := cmp.handleEachMessage(offset.ctx, offset.message, compNameTopic, brokers, offset.fetchedAt); err != nil {
+					fail(err)
+					continue
+				}
+				if tracker.done(offset) {
+					select {
+					case commitSignal <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}(workers[i])
+	}
+
+	// 单独的 goroutine 提交 offset，保证同一分区的 offset 只会递增
+	committerDone := make(chan struct{})
+	go func() {
+		defer close(committerDone)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-commitSignal:
+			}
+			for _, message := range tracker.committable() {
+				message := message
+				if err := cmp.commit(ctx, consumer, compNameTopic, brokers, time.Now(), &message); err != nil {
+					fail(err)
+					return
+				}
+			}
+		}
+	}()
+
+	for ctx.Err() == nil {
+		// The beginning of time monitoring point in time
+		now := time.Now()
+		message, fetchCtx, err := consumer.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			cmp.consumptionErrors <- err
+			cmp.logger.Error("encountered an error while fetching message", elog.FieldErr(err))
+
+			// If this error is unrecoverable, stop consuming.
+			if isErrorUnrecoverable(err) {
+				fail(err)
+				break
+			}
+			// Otherwise, try to fetch message again.
+			continue
+		}
+		offset := tracker.track(message)
+		offset.ctx, offset.fetchedAt = fetchCtx, now
+		select {
+		case workers[cmp.workerIndex(message)] <- offset:
+		case <-ctx.Done():
+		}
+	}
+
+	for _, queue := range workers {
+		close(queue)
+	}
+	wg.Wait()
+	<-committerDone
+
+	// 服务停止时提交已经处理完成的 offset
+	for _, message := range tracker.committable() {
+		message := message
+		if err := consumer.CommitMessages(context.Background(), &message); err != nil {
+			cmp.logger.Error("encountered an error while committing message on stop", elog.FieldErr(err))
+		}
+	}
+}
+
+// workerIndex returns the worker handling the message by the OrderBy config.
+func (cmp *Component) workerIndex(message ekafka.Message) int {
+	if cmp.config.OrderBy == OrderByKey && len(message.Key) > 0 {
+		h := fnv.New32a()
+		_, _ = h.Write(message.Key)
+		return int(h.Sum32() % uint32(cmp.config.Concurrency))
+	}
+	return message.Partition % cmp.config.Concurrency
+}
+
+type partitionKey struct {
+	topic     string
+	partition int
+}
+
+type trackedOffset struct {
+	ctx       context.Context
+	fetchedAt time.Time
+	message   ekafka.Message
+	handled   bool
+}
+
+// offsetTracker tracks in-flight offsets of every partition, and finds the highest contiguous handled offsets.
+type offsetTracker struct {
+	mu         sync.Mutex
+	inFlight   map[partitionKey][]*trackedOffset
+	toCommit   map[partitionKey]ekafka.Message
+	lastOffset map[partitionKey]int64
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		inFlight:   make(map[partitionKey][]*trackedOffset),
+		toCommit:   make(map[partitionKey]ekafka.Message),
+		lastOffset: make(map[partitionKey]int64),
+	}
+}
+
+// track records a fetched message, offsets of a partition must be tracked in the fetching order.
+func (t *offsetTracker) track(message ekafka.Message) *trackedOffset {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := partitionKey{topic: message.Topic, partition: message.Partition}
+	if last, ok := t.lastOffset[key]; ok && message.Offset <= last {
+		// rebalance 后从已提交的 offset 重新消费，之前在途的 offset 不再提交
+		delete(t.inFlight, key)
+		delete(t.toCommit, key)
+	}
+	t.lastOffset[key] = message.Offset
+	offset := &trackedOffset{message: message}
+	t.inFlight[key] = append(t.inFlight[key], offset)
+	return offset
+}
+
+// done marks the offset handled, returns true if the committable offset of the partition moves forward.
+func (t *offsetTracker) done(offset *trackedOffset) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	offset.handled = true
+	key := partitionKey{topic: offset.message.Topic, partition: offset.message.Partition}
+	queue := t.inFlight[key]
+	i := 0
+	for ; i < len(queue) && queue[i].handled; i++ {
+		t.toCommit[key] = queue[i].message
+	}
+	if i == 0 {
+		return false
+	}
+	t.inFlight[key] = queue[i:]
+	return true
+}
+
+// committable returns and clears the highest contiguous handled message of every partition.
+func (t *offsetTracker) committable() []ekafka.Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	messages := make([]ekafka.Message, 0, len(t.toCommit))
+	for key, message := range t.toCommit {
+		messages = append(messages, message)
+		delete(t.toCommit, key)
+	}
+	return messages
+}
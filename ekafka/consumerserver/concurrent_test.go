@@ -0,0 +1,64 @@
+package consumerserver
+
+import (
+	"testing"
+
+	"github.com/gotomicro/ego-component/ekafka"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	tracker := newOffsetTracker()
+	offsets := make([]*trackedOffset, 0)
+	for i := int64(0); i < 4; i++ {
+		offsets = append(offsets, tracker.track(ekafka.Message{Topic: "t", Partition: 0, Offset: i}))
+	}
+	other := tracker.track(ekafka.Message{Topic: "t", Partition: 1, Offset: 10})
+
+	// offset 1、2 先完成，0 未完成时不能提交
+	assert.False(t, tracker.done(offsets[1]))
+	assert.False(t, tracker.done(offsets[2]))
+	assert.Empty(t, tracker.committable())
+
+	// 0 完成后可以提交到 2
+	assert.True(t, tracker.done(offsets[0]))
+	assert.True(t, tracker.done(other))
+	committable := tracker.committable()
+	assert.Len(t, committable, 2)
+	for _, message := range committable {
+		if message.Partition == 0 {
+			assert.Equal(t, int64(2), message.Offset)
+		} else {
+			assert.Equal(t, int64(10), message.Offset)
+		}
+	}
+	assert.Empty(t, tracker.committable())
+
+	// rebalance 后重新消费，之前在途的 offset 被丢弃
+	again := tracker.track(ekafka.Message{Topic: "t", Partition: 0, Offset: 3})
+	assert.False(t, tracker.done(offsets[3]))
+	assert.True(t, tracker.done(again))
+	committable = tracker.committable()
+	assert.Len(t, committable, 1)
+	assert.Equal(t, int64(3), committable[0].Offset)
+}
+
+func TestWithOrderBy(t *testing.T) {
+	cmp := DefaultContainer().Build(WithConcurrency(4), WithOrderBy(OrderByKey))
+	assert.Equal(t, OrderByKey, cmp.config.OrderBy)
+	assert.Panics(t, func() {
+		DefaultContainer().Build(WithOrderBy("offset"))
+	})
+}
+
+func TestWorkerIndex(t *testing.T) {
+	cmp := &Component{config: DefaultConfig()}
+	cmp.config.Concurrency = 4
+	assert.Equal(t, 1, cmp.workerIndex(ekafka.Message{Partition: 5, Key: []byte("a")}))
+
+	cmp.config.OrderBy = OrderByKey
+	first := cmp.workerIndex(ekafka.Message{Partition: 0, Key: []byte("user-1")})
+	assert.Equal(t, first, cmp.workerIndex(ekafka.Message{Partition: 3, Key: []byte("user-1")}))
+	// 没有 key 的消息按分区分配
+	assert.Equal(t, 2, cmp.workerIndex(ekafka.Message{Partition: 2}))
+}
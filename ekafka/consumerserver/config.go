@@ -1,12 +1,37 @@
 package consumerserver
 
-import "github.com/gotomicro/ego-component/ekafka"
+import (
+	"time"
+
+	"github.com/gotomicro/ego-component/ekafka"
+)
 
 type config struct {
 	Debug             bool   `json:"debug" toml:"debug"`
 	ConsumerName      string `json:"consumerName" toml:"consumerName"`
 	ConsumerGroupName string `json:"consumerGroupName" toml:"consumerGroupName"`
-	ekafkaComponent   *ekafka.Component
+	// Concurrency 逐条消费时处理消息的 worker 数量，默认为 1，即拉取、处理、提交在同一个 goroutine 中串行执行
+	Concurrency int `json:"concurrency" toml:"concurrency"`
+	// OrderBy 并发处理时保证顺序的维度 partition|key，默认 partition
+	OrderBy string `json:"orderBy" toml:"orderBy"`
+	// BatchSize 批量消费时每批消息的最大数量，默认 100
+	BatchSize int `json:"batchSize" toml:"batchSize"`
+	// BatchMaxWait 批量消费时拉取到第一条消息后等待凑满一批的最长时间，默认 1s
+	BatchMaxWait time.Duration `json:"batchMaxWait" toml:"batchMaxWait"`
+	// RetryTiers 逐条或批量消费时，处理失败的消息依次转发到的重试 topic，每一级 topic 的消息延迟 Delay 后重新处理
+	RetryTiers []retryTierConfig `json:"retryTiers" toml:"retryTiers"`
+	// DeadLetterProducerName 重试耗尽后，消息通过该 producer 写入死信 topic，对应 `kafka.producers.[name]` 配置项
+	DeadLetterProducerName string `json:"deadLetterProducerName" toml:"deadLetterProducerName"`
+	ekafkaComponent        *ekafka.Component
+}
+
+type retryTierConfig struct {
+	// ProducerName 写入该级重试 topic 的 producer，对应 `kafka.producers.[name]` 配置项
+	ProducerName string `json:"producerName" toml:"producerName"`
+	// ConsumerName 消费该级重试 topic 的 consumer，对应 `kafka.consumers.[name]` 配置项，为空时由其他服务消费
+	ConsumerName string `json:"consumerName" toml:"consumerName"`
+	// Delay 消息写入该级重试 topic 后，延迟多久重新处理
+	Delay time.Duration `json:"delay" toml:"delay"`
 }
 
 // DefaultConfig returns a default config.
@@ -15,5 +40,9 @@ func DefaultConfig() *config {
 		Debug:             true,
 		ConsumerName:      "default",
 		ConsumerGroupName: "default",
+		Concurrency:       1,
+		OrderBy:           OrderByPartition,
+		BatchSize:         100,
+		BatchMaxWait:      time.Second,
 	}
 }
@@ -39,6 +39,9 @@ func (c *Container) Build(options ...Option) *Component {
 	for _, option := range options {
 		option(c)
 	}
+	if c.config.OrderBy != OrderByPartition && c.config.OrderBy != OrderByKey {
+		c.logger.Panic(`invalid "orderBy" config, must be "partition" or "key"`, elog.String("orderBy", c.config.OrderBy))
+	}
 
 	cmp := NewConsumerServerComponent(
 		c.name,
@@ -1,5 +1,49 @@
 package consumerserver
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
 
 var ErrRecoverableError error = errors.New("recoverable error is retryable")
+
+// BatchError reports the failed messages of a batch, keyed by their index in the batch,
+// the other messages of the batch are considered as handled.
+type BatchError struct {
+	Errors map[int]error
+}
+
+// NewBatchError creates an empty BatchError.
+func NewBatchError() *BatchError {
+	return &BatchError{Errors: make(map[int]error)}
+}
+
+// Add marks the message at index of the batch as failed.
+func (e *BatchError) Add(index int, err error) *BatchError {
+	e.Errors[index] = err
+	return e
+}
+
+// Len returns the number of failed messages.
+func (e *BatchError) Len() int {
+	return len(e.Errors)
+}
+
+func (e *BatchError) Error() string {
+	indexes := e.indexes()
+	if len(indexes) == 0 {
+		return "batch failed with no message error"
+	}
+	return fmt.Sprintf("%d messages of batch failed, first error at index %d: %s", len(indexes), indexes[0], e.Errors[indexes[0]])
+}
+
+// indexes returns the indexes of the failed messages in ascending order.
+func (e *BatchError) indexes() []int {
+	indexes := make([]int, 0, len(e.Errors))
+	for index := range e.Errors {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
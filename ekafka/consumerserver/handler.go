@@ -14,3 +14,7 @@ type OnStartHandler = func(ctx context.Context, consumer *ekafka.Consumer) error
 
 // OnConsumerGroupStartHandler ...
 type OnConsumerGroupStartHandler = func(ctx context.Context, consumerGroup *ekafka.ConsumerGroup) error
+
+// OnEachBatchHandler handles a batch of messages, returns a *BatchError to report the failed messages of the batch,
+// any other error fails the whole batch.
+type OnEachBatchHandler = func(ctx context.Context, messages []kafka.Message) error
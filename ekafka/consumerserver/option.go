@@ -1,6 +1,8 @@
 package consumerserver
 
 import (
+	"time"
+
 	"github.com/gotomicro/ego-component/ekafka"
 )
 
@@ -17,3 +19,25 @@ func WithDebug(debug bool) Option {
 		c.config.Debug = debug
 	}
 }
+
+// WithConcurrency sets the number of workers handling messages concurrently.
+func WithConcurrency(concurrency int) Option {
+	return func(c *Container) {
+		c.config.Concurrency = concurrency
+	}
+}
+
+// WithOrderBy sets the dimension keeping the order of concurrently handled messages, OrderByPartition or OrderByKey.
+func WithOrderBy(orderBy string) Option {
+	return func(c *Container) {
+		c.config.OrderBy = orderBy
+	}
+}
+
+// WithBatch sets the max size of a batch, and the max wait to fill a batch after its first message is fetched.
+func WithBatch(size int, maxWait time.Duration) Option {
+	return func(c *Container) {
+		c.config.BatchSize = size
+		c.config.BatchMaxWait = maxWait
+	}
+}
@@ -0,0 +1,156 @@
+package consumerserver
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gotomicro/ego-component/ekafka"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/segmentio/kafka-go"
+)
+
+// 转发到重试 topic、死信 topic 的消息会带上以下 header
+const (
+	// HeaderRetryCount 消息已经经过的重试 topic 数量
+	HeaderRetryCount = "x-ego-retry-count"
+	// HeaderRetryAt 消息可以被重试的时间，unix 毫秒
+	HeaderRetryAt = "x-ego-retry-at"
+	// HeaderError 最后一次处理失败的错误信息
+	HeaderError = "x-ego-error"
+	// HeaderFailedAt 最后一次处理失败的时间，RFC3339 格式
+	HeaderFailedAt = "x-ego-failed-at"
+	// HeaderOriginalTopic 消息最初所在的 topic
+	HeaderOriginalTopic = "x-ego-original-topic"
+	// HeaderOriginalPartition 消息最初所在的分区
+	HeaderOriginalPartition = "x-ego-original-partition"
+	// HeaderOriginalOffset 消息最初的 offset
+	HeaderOriginalOffset = "x-ego-original-offset"
+)
+
+// forwardRetryInterval 转发消息失败后再次尝试的间隔
+const forwardRetryInterval = time.Second
+
+// errNoRetryTarget 未配置可用的重试 topic 和死信 topic
+var errNoRetryTarget = errors.New("no retry topic or dead-letter topic left")
+
+// hasRetryPipeline reports whether failed messages are forwarded to retry topics or the dead-letter topic.
+func (cmp *Component) hasRetryPipeline() bool {
+	return len(cmp.config.RetryTiers) > 0 || cmp.config.DeadLetterProducerName != ""
+}
+
+// forward parks a message failed with handleErr to the next retry topic, or the dead-letter topic after all retry topics.
+// It keeps trying until the message is written or the server stops.
+func (cmp *Component) forward(ctx context.Context, message ekafka.Message, handleErr error) error {
+	retryCount := headerInt(message, HeaderRetryCount)
+	var (
+		producerName string
+		retryAt      time.Time
+		now          = time.Now()
+	)
+	switch {
+	case int(retryCount) < len(cmp.config.RetryTiers):
+		tier := cmp.config.RetryTiers[retryCount]
+		producerName = tier.ProducerName
+		retryAt = now.Add(tier.Delay)
+		retryCount++
+	case cmp.config.DeadLetterProducerName != "":
+		producerName = cmp.config.DeadLetterProducerName
+	default:
+		return errNoRetryTarget
+	}
+
+	forwarded := forwardMessage(message, handleErr, retryCount, retryAt, now)
+	producer := cmp.ekafkaComponent.Producer(producerName)
+	for {
+		err := producer.WriteMessages(ctx, &forwarded)
+		if err == nil {
+			cmp.logger.Warn("message forwarded",
+				elog.String("producer", producerName),
+				elog.String("topic", message.Topic),
+				elog.Int("partition", message.Partition),
+				elog.Int64("offset", message.Offset),
+				elog.FieldErr(handleErr),
+			)
+			return nil
+		}
+		cmp.logger.Error("encountered an error while forwarding message", elog.FieldErr(err), elog.String("producer", producerName))
+		select {
+		case <-cmp.ServerCtx.Done():
+			return cmp.ServerCtx.Err()
+		case <-time.After(forwardRetryInterval):
+		}
+	}
+}
+
+// forwardMessage copies message with the failure headers, the topic is left to the producer.
+func forwardMessage(message ekafka.Message, handleErr error, retryCount int64, retryAt, now time.Time) ekafka.Message {
+	headers := make([]kafka.Header, 0, len(message.Headers)+7)
+	for _, header := range message.Headers {
+		switch header.Key {
+		case HeaderRetryCount, HeaderRetryAt, HeaderError, HeaderFailedAt:
+			continue
+		}
+		headers = append(headers, header)
+	}
+	// 多次重试时保留最初的位置
+	if _, ok := headerValue(message, HeaderOriginalTopic); !ok {
+		headers = append(headers,
+			kafka.Header{Key: HeaderOriginalTopic, Value: []byte(message.Topic)},
+			kafka.Header{Key: HeaderOriginalPartition, Value: []byte(strconv.Itoa(message.Partition))},
+			kafka.Header{Key: HeaderOriginalOffset, Value: []byte(strconv.FormatInt(message.Offset, 10))},
+		)
+	}
+	headers = append(headers,
+		kafka.Header{Key: HeaderRetryCount, Value: []byte(strconv.FormatInt(retryCount, 10))},
+		kafka.Header{Key: HeaderError, Value: []byte(handleErr.Error())},
+		kafka.Header{Key: HeaderFailedAt, Value: []byte(now.Format(time.RFC3339))},
+	)
+	if !retryAt.IsZero() {
+		headers = append(headers, kafka.Header{Key: HeaderRetryAt, Value: []byte(strconv.FormatInt(retryAt.UnixNano()/int64(time.Millisecond), 10))})
+	}
+	return ekafka.Message{
+		Key:     message.Key,
+		Value:   message.Value,
+		Headers: headers,
+	}
+}
+
+// waitRetryAt blocks until the message of a retry topic is due, returns false if the server stops first.
+func (cmp *Component) waitRetryAt(message ekafka.Message) bool {
+	retryAt := headerInt(message, HeaderRetryAt)
+	if retryAt <= 0 {
+		return true
+	}
+	delay := time.Until(time.Unix(0, retryAt*int64(time.Millisecond)))
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-cmp.ServerCtx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func headerValue(message ekafka.Message, key string) (string, bool) {
+	for _, header := range message.Headers {
+		if header.Key == key {
+			return string(header.Value), true
+		}
+	}
+	return "", false
+}
+
+func headerInt(message ekafka.Message, key string) int64 {
+	value, ok := headerValue(message, key)
+	if !ok {
+		return 0
+	}
+	i, _ := strconv.ParseInt(value, 10, 64)
+	return i
+}
@@ -0,0 +1,50 @@
+package consumerserver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego-component/ekafka"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForwardMessage(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	message := ekafka.Message{
+		Topic:     "orders",
+		Partition: 2,
+		Offset:    42,
+		Key:       []byte("k"),
+		Value:     []byte("v"),
+		Headers:   []kafka.Header{{Key: "x-biz", Value: []byte("1")}},
+	}
+
+	retried := forwardMessage(message, errors.New("boom"), 1, now.Add(time.Minute), now)
+	assert.Equal(t, "", retried.Topic)
+	assert.Equal(t, []byte("k"), retried.Key)
+	assert.Equal(t, []byte("v"), retried.Value)
+	biz, _ := headerValue(retried, "x-biz")
+	assert.Equal(t, "1", biz)
+	topic, _ := headerValue(retried, HeaderOriginalTopic)
+	assert.Equal(t, "orders", topic)
+	assert.Equal(t, int64(2), headerInt(retried, HeaderOriginalPartition))
+	assert.Equal(t, int64(42), headerInt(retried, HeaderOriginalOffset))
+	assert.Equal(t, int64(1), headerInt(retried, HeaderRetryCount))
+	assert.Equal(t, now.Add(time.Minute).UnixNano()/int64(time.Millisecond), headerInt(retried, HeaderRetryAt))
+	errMsg, _ := headerValue(retried, HeaderError)
+	assert.Equal(t, "boom", errMsg)
+
+	// 从重试 topic 再次转发时，保留最初的位置，覆盖重试信息
+	retried.Topic, retried.Partition, retried.Offset = "orders-retry-1m", 0, 7
+	dead := forwardMessage(retried, errors.New("boom again"), 1, time.Time{}, now)
+	topic, _ = headerValue(dead, HeaderOriginalTopic)
+	assert.Equal(t, "orders", topic)
+	assert.Equal(t, int64(42), headerInt(dead, HeaderOriginalOffset))
+	_, ok := headerValue(dead, HeaderRetryAt)
+	assert.False(t, ok)
+	errMsg, _ = headerValue(dead, HeaderError)
+	assert.Equal(t, "boom again", errMsg)
+	assert.Len(t, dead.Headers, 7)
+}
@@ -58,14 +58,37 @@ func (c *Container) Build(options ...Option) *Component {
 	}
 
 	c.logger = c.logger.With(elog.FieldAddr(fmt.Sprintf("%s", c.config.Brokers)))
+	if c.config.tlsConfig == nil {
+		tlsConfig, err := c.config.newTLSConfig()
+		if err != nil {
+			c.logger.Panic("create tls config error", elog.FieldErr(err))
+		}
+		c.config.tlsConfig = tlsConfig
+	}
+	// 所有连接共用一个 token 来源，避免重复获取 token
+	if c.config.oauthTokenSource == nil && c.config.SASLOAuth.TokenURL != "" {
+		c.config.oauthTokenSource = &ClientCredentialsTokenSource{
+			TokenURL:     c.config.SASLOAuth.TokenURL,
+			ClientID:     c.config.SASLOAuth.ClientID,
+			ClientSecret: c.config.SASLOAuth.ClientSecret,
+			Scopes:       c.config.SASLOAuth.Scopes,
+			Extensions:   c.config.SASLOAuth.Extensions,
+		}
+	}
 	cmp := &Component{
 		config:         c.config,
 		logger:         c.logger,
 		producers:      make(map[string]*Producer),
+		txnProducers:   make(map[string]*TxnProducer),
 		consumers:      make(map[string]*Consumer),
 		consumerGroups: make(map[string]*ConsumerGroup),
 		compName:       c.name,
 	}
+	instances.Store(c.name, cmp)
+	if c.config.EnableLagMetric && c.config.LagMetricInterval > 0 {
+		cmp.lagCollector = newLagCollector(cmp, c.config.LagMetricInterval)
+		go cmp.lagCollector.run()
+	}
 
 	return cmp
 }
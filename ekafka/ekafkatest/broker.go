@@ -0,0 +1,266 @@
+// Package ekafkatest provides an in-process Kafka broker for unit tests.
+//
+// The broker speaks the Kafka wire protocol on a local TCP port, so Producer, Consumer, ConsumerGroup
+// and Client of ekafka work against it without any change, only brokers need to point to Broker.Addr().
+// Messages, committed offsets and consumer groups are kept in memory, there is no replication,
+// retention, compaction, transaction or authentication. Idempotent producers are supported, duplicated batches
+// of a producer are dropped like kafka.
+package ekafkatest
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+// nodeID the broker is the only node of the cluster, controller, leader of all partitions and coordinator of all groups.
+const nodeID = 1
+
+// Option 设置 Broker 的选项
+type Option func(b *Broker)
+
+// WithTopic 启动时创建 topic
+func WithTopic(topic string, partitions int) Option {
+	return func(b *Broker) {
+		b.topics[topic] = newTopic(partitions)
+	}
+}
+
+// WithAutoCreateTopics 读写不存在的 topic 时自动创建，partitions 为分区数量，默认不自动创建
+func WithAutoCreateTopics(partitions int) Option {
+	return func(b *Broker) {
+		b.autoCreatePartitions = partitions
+	}
+}
+
+// WithInitialRebalanceDelay 新的消费组等待成员加入的时间，与 broker 的 group.initial.rebalance.delay.ms 相同，
+// 多个成员同时启动时，可以避免第一个成员加入后马上进行一次 rebalance，默认为 0
+func WithInitialRebalanceDelay(delay time.Duration) Option {
+	return func(b *Broker) {
+		b.initialRebalanceDelay = delay
+	}
+}
+
+// Broker in-process Kafka broker
+type Broker struct {
+	listener              net.Listener
+	host                  string
+	port                  int32
+	autoCreatePartitions  int
+	initialRebalanceDelay time.Duration
+
+	mu     sync.Mutex
+	topics map[string]*topic
+	groups map[string]*group
+	// produced 写入消息后关闭并替换，用于唤醒等待消息的 fetch 请求
+	produced chan struct{}
+	// producers 幂等 producer 在每个分区写入的 sequence
+	producers            map[producerKey]*producerState
+	lastProducerID       int64
+	lostProduceResponses int
+	memberID             int
+
+	conns   map[net.Conn]struct{}
+	closed  chan struct{}
+	closing sync.Once
+	wg      sync.WaitGroup
+}
+
+// NewBroker 在 127.0.0.1 的随机端口上启动 Broker，使用完毕后需要调用 Close
+func NewBroker(options ...Option) (*Broker, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	b := &Broker{
+		listener:  listener,
+		host:      host,
+		port:      int32(portNum),
+		topics:    make(map[string]*topic),
+		groups:    make(map[string]*group),
+		produced:  make(chan struct{}),
+		producers: make(map[producerKey]*producerState),
+		conns:     make(map[net.Conn]struct{}),
+		closed:    make(chan struct{}),
+	}
+	for _, option := range options {
+		option(b)
+	}
+
+	b.wg.Add(2)
+	go b.accept()
+	go b.expireMembers()
+	return b, nil
+}
+
+// Addr 返回 broker 的地址，用于 ekafka 的 brokers 配置
+func (b *Broker) Addr() string {
+	return b.listener.Addr().String()
+}
+
+// Close 关闭 broker 以及所有连接
+func (b *Broker) Close() error {
+	var err error
+	b.closing.Do(func() {
+		close(b.closed)
+		err = b.listener.Close()
+		b.mu.Lock()
+		for conn := range b.conns {
+			_ = conn.Close()
+		}
+		b.mu.Unlock()
+		b.wg.Wait()
+	})
+	return err
+}
+
+func (b *Broker) accept() {
+	defer b.wg.Done()
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		select {
+		case <-b.closed:
+			b.mu.Unlock()
+			_ = conn.Close()
+			return
+		default:
+		}
+		b.conns[conn] = struct{}{}
+		b.wg.Add(1)
+		b.mu.Unlock()
+		go b.serve(conn)
+	}
+}
+
+// serve handles the requests of a connection one by one, the responses are in the same order as the requests like kafka.
+func (b *Broker) serve(conn net.Conn) {
+	defer b.wg.Done()
+	defer func() {
+		b.mu.Lock()
+		delete(b.conns, conn)
+		b.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		apiVersion, correlationID, clientID, req, err := protocol.ReadRequest(r)
+		if err != nil {
+			return
+		}
+		res, err := b.handle(request{version: apiVersion, clientID: clientID, clientHost: conn.RemoteAddr().String(), msg: req})
+		if err != nil {
+			return
+		}
+		if res == nil {
+			continue
+		}
+		if fetchRes, ok := res.(*fetchResponse); ok {
+			err = fetchRes.writeTo(w, apiVersion, correlationID)
+		} else {
+			err = protocol.WriteResponse(w, apiVersion, correlationID, res)
+		}
+		if err != nil {
+			return
+		}
+		if err = w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+type request struct {
+	version    int16
+	clientID   string
+	clientHost string
+	msg        protocol.Message
+}
+
+var errClosed = errors.New("ekafkatest: broker closed")
+
+// handle returns the response of the request, nil if no response is expected.
+func (b *Broker) handle(req request) (protocol.Message, error) {
+	switch req.msg.ApiKey() {
+	case protocol.ApiVersions:
+		return b.apiVersions(), nil
+	case protocol.Metadata:
+		return b.metadata(req), nil
+	case protocol.CreateTopics:
+		return b.createTopics(req), nil
+	case protocol.DeleteTopics:
+		return b.deleteTopics(req), nil
+	case protocol.CreatePartitions:
+		return b.createPartitions(req), nil
+	case protocol.Produce:
+		return b.produce(req)
+	case protocol.InitProducerId:
+		return b.initProducerID(req), nil
+	case protocol.Fetch:
+		return b.fetch(req)
+	case protocol.ListOffsets:
+		return b.listOffsets(req), nil
+	case protocol.FindCoordinator:
+		return b.findCoordinator(), nil
+	case protocol.JoinGroup:
+		return b.joinGroup(req)
+	case protocol.SyncGroup:
+		return b.syncGroup(req)
+	case protocol.Heartbeat:
+		return b.heartbeat(req), nil
+	case protocol.LeaveGroup:
+		return b.leaveGroup(req), nil
+	case protocol.OffsetCommit:
+		return b.offsetCommit(req), nil
+	case protocol.OffsetFetch:
+		return b.offsetFetch(req), nil
+	case protocol.DescribeGroups:
+		return b.describeGroups(req), nil
+	case protocol.ListGroups:
+		return b.listGroups(), nil
+	}
+	// ApiVersions 中没有的请求，客户端不会发送
+	return nil, errors.New("ekafkatest: unsupported api " + req.msg.ApiKey().String())
+}
+
+// maxVersions the supported apis and their max versions, flexible versions are not supported.
+var maxVersions = map[protocol.ApiKey]int16{
+	protocol.Produce:          8,
+	protocol.Fetch:            11,
+	protocol.ListOffsets:      5,
+	protocol.Metadata:         8,
+	protocol.OffsetCommit:     7,
+	protocol.OffsetFetch:      5,
+	protocol.FindCoordinator:  2,
+	protocol.JoinGroup:        5,
+	protocol.Heartbeat:        3,
+	protocol.LeaveGroup:       2,
+	protocol.SyncGroup:        3,
+	protocol.DescribeGroups:   4,
+	protocol.ListGroups:       2,
+	protocol.ApiVersions:      2,
+	protocol.CreateTopics:     4,
+	protocol.DeleteTopics:     3,
+	protocol.CreatePartitions: 1,
+	protocol.InitProducerId:   1,
+}
@@ -0,0 +1,165 @@
+package ekafkatest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBroker(t *testing.T, options ...Option) *Broker {
+	b, err := NewBroker(options...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = b.Close() })
+	return b
+}
+
+func TestProduceFetch(t *testing.T) {
+	b := newTestBroker(t, WithTopic("test", 2))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	w := &kafka.Writer{Addr: kafka.TCP(b.Addr()), Topic: "test", Balancer: &kafka.Hash{}, BatchTimeout: time.Millisecond, RequiredAcks: kafka.RequireAll}
+	defer w.Close()
+	require.NoError(t, w.WriteMessages(ctx,
+		kafka.Message{Key: []byte("a"), Value: []byte("1"), Headers: []kafka.Header{{Key: "h", Value: []byte("v")}}},
+		kafka.Message{Key: []byte("a"), Value: []byte("2")},
+		kafka.Message{Key: []byte("a"), Value: []byte("3")},
+	))
+
+	var partition int
+	for p := 0; p < 2; p++ {
+		if len(b.Messages("test", p)) > 0 {
+			partition = p
+		}
+	}
+	messages := b.Messages("test", partition)
+	require.Len(t, messages, 3)
+	assert.Equal(t, int64(2), messages[2].Offset)
+
+	r := kafka.NewReader(kafka.ReaderConfig{Brokers: []string{b.Addr()}, Topic: "test", Partition: partition, MaxWait: 100 * time.Millisecond})
+	defer r.Close()
+	require.NoError(t, r.SetOffset(1))
+	msg, err := r.ReadMessage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), msg.Offset)
+	assert.Equal(t, "2", string(msg.Value))
+
+	// 读取时写入的消息
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = b.Produce("test", partition, kafka.Message{Value: []byte("4")})
+	}()
+	msg, err = r.ReadMessage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "3", string(msg.Value))
+	msg, err = r.ReadMessage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), msg.Offset)
+	assert.Equal(t, "4", string(msg.Value))
+
+	require.NoError(t, r.SetOffset(0))
+	msg, err = r.ReadMessage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []kafka.Header{{Key: "h", Value: []byte("v")}}, msg.Headers)
+}
+
+func TestClientOffsets(t *testing.T) {
+	b := newTestBroker(t, WithAutoCreateTopics(1))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < 3; i++ {
+		_, err := b.Produce("test", 0, kafka.Message{Value: []byte(fmt.Sprint(i)), Time: start.Add(time.Duration(i) * time.Minute)})
+		require.NoError(t, err)
+	}
+
+	client := &kafka.Client{Addr: kafka.TCP(b.Addr())}
+	res, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{
+		"test": {kafka.FirstOffsetOf(0), kafka.LastOffsetOf(0), kafka.TimeOffsetOf(0, start.Add(time.Minute))},
+	}})
+	require.NoError(t, err)
+	require.Len(t, res.Topics["test"], 1)
+	offsets := res.Topics["test"][0]
+	assert.Equal(t, int64(0), offsets.FirstOffset)
+	assert.Equal(t, int64(3), offsets.LastOffset)
+	assert.Contains(t, offsets.Offsets, int64(1))
+
+	_, err = client.CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: []kafka.TopicConfig{{Topic: "other", NumPartitions: 3, ReplicationFactor: 1}}})
+	require.NoError(t, err)
+	assert.EqualError(t, b.CreateTopic("other", 1), kafka.TopicAlreadyExists.Error())
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{"other"}})
+	require.NoError(t, err)
+	require.Len(t, meta.Topics, 1)
+	assert.Len(t, meta.Topics[0].Partitions, 3)
+}
+
+func TestConsumerGroupRebalance(t *testing.T) {
+	b := newTestBroker(t, WithTopic("test", 4))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	for p := 0; p < 4; p++ {
+		_, err := b.Produce("test", p, kafka.Message{Value: []byte(fmt.Sprint(p))})
+		require.NoError(t, err)
+	}
+
+	newReader := func() *kafka.Reader {
+		return kafka.NewReader(kafka.ReaderConfig{
+			Brokers:           []string{b.Addr()},
+			GroupID:           "group",
+			Topic:             "test",
+			MaxWait:           100 * time.Millisecond,
+			HeartbeatInterval: 100 * time.Millisecond,
+			SessionTimeout:    time.Second,
+			RebalanceTimeout:  time.Second,
+			JoinGroupBackoff:  100 * time.Millisecond,
+			StartOffset:       kafka.FirstOffset,
+		})
+	}
+
+	r1 := newReader()
+	defer r1.Close()
+	values := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		msg, err := r1.FetchMessage(ctx)
+		require.NoError(t, err)
+		require.NoError(t, r1.CommitMessages(ctx, msg))
+		values[string(msg.Value)] = true
+	}
+	assert.Len(t, values, 4)
+	for p := 0; p < 4; p++ {
+		assert.Equal(t, int64(1), b.CommittedOffset("group", "test", p))
+	}
+	state := b.Group("group")
+	assert.Equal(t, GroupStateStable, state.State)
+	assert.Len(t, state.Assignments, 1)
+
+	// 第二个成员加入后，分区分配给两个成员
+	r2 := newReader()
+	defer r2.Close()
+	go func() {
+		_, _ = r2.FetchMessage(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		state = b.Group("group")
+		return state.State == GroupStateStable && len(state.Assignments) == 2
+	}, 10*time.Second, 50*time.Millisecond)
+	for _, assignment := range state.Assignments {
+		assert.Len(t, assignment["test"], 2)
+	}
+
+	// 成员离开后，分区重新分配给剩下的成员
+	require.NoError(t, r2.Close())
+	require.Eventually(t, func() bool {
+		state = b.Group("group")
+		return state.State == GroupStateStable && len(state.Assignments) == 1
+	}, 10*time.Second, 50*time.Millisecond)
+	for _, assignment := range state.Assignments {
+		assert.Len(t, assignment["test"], 4)
+	}
+}
@@ -0,0 +1,205 @@
+package ekafkatest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/fetch"
+)
+
+// fetchResponse is encoded by hand, protocol.RecordSet always writes the base offset of a batch as 0.
+type fetchResponse struct {
+	topics []fetchTopic
+}
+
+type fetchTopic struct {
+	name       string
+	partitions []fetchPartition
+}
+
+type fetchPartition struct {
+	partition     int32
+	errorCode     int16
+	highWatermark int64
+	records       []record
+}
+
+func (b *Broker) fetch(req request) (protocol.Message, error) {
+	msg := req.msg.(*fetch.Request)
+	deadline := time.Now().Add(time.Duration(msg.MaxWaitTime) * time.Millisecond)
+	for {
+		b.mu.Lock()
+		res, size, failed := b.readPartitions(msg)
+		produced := b.produced
+		b.mu.Unlock()
+
+		// 没有新消息时等待 MaxWaitTime，与 broker 的 long polling 相同，有消息时不等待凑满 MinBytes
+		wait := time.Until(deadline)
+		if failed || size > 0 || msg.MinBytes <= 0 || wait <= 0 {
+			return res, nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-produced:
+			timer.Stop()
+		case <-timer.C:
+		case <-b.closed:
+			timer.Stop()
+			return nil, errClosed
+		}
+	}
+}
+
+// readPartitions reads the records of the partitions, failed is true if any partition has error, the lock must be held.
+func (b *Broker) readPartitions(msg *fetch.Request) (res *fetchResponse, size int, failed bool) {
+	res = &fetchResponse{}
+	for _, t := range msg.Topics {
+		topicRes := fetchTopic{name: t.Topic}
+		existing := b.topic(t.Topic)
+		for _, p := range t.Partitions {
+			partitionRes := fetchPartition{partition: p.Partition}
+			if existing == nil || p.Partition < 0 || int(p.Partition) >= len(existing.partitions) {
+				partitionRes.errorCode = int16(kafka.UnknownTopicOrPartition)
+				topicRes.partitions = append(topicRes.partitions, partitionRes)
+				failed = true
+				continue
+			}
+			log := existing.partitions[p.Partition]
+			partitionRes.highWatermark = int64(len(log))
+			if p.FetchOffset < 0 || p.FetchOffset > int64(len(log)) {
+				partitionRes.errorCode = int16(kafka.OffsetOutOfRange)
+				topicRes.partitions = append(topicRes.partitions, partitionRes)
+				failed = true
+				continue
+			}
+			// 至少返回一条消息，避免消息大于 PartitionMaxBytes 时无法消费
+			partitionSize := 0
+			for _, r := range log[p.FetchOffset:] {
+				if len(partitionRes.records) > 0 && partitionSize+r.size() > int(p.PartitionMaxBytes) {
+					break
+				}
+				partitionRes.records = append(partitionRes.records, r)
+				partitionSize += r.size()
+			}
+			size += partitionSize
+			topicRes.partitions = append(topicRes.partitions, partitionRes)
+		}
+		res.topics = append(res.topics, topicRes)
+	}
+	return res, size, failed
+}
+
+func (r record) size() int {
+	size := len(r.key) + len(r.value) + 20
+	for _, header := range r.headers {
+		size += len(header.Key) + len(header.Value)
+	}
+	return size
+}
+
+// ApiKey implements protocol.Message.
+func (res *fetchResponse) ApiKey() protocol.ApiKey { return protocol.Fetch }
+
+func (res *fetchResponse) writeTo(w *bufio.Writer, version int16, correlationID int32) error {
+	e := &encoder{}
+	e.int32(correlationID)
+	if version >= 1 {
+		e.int32(0) // throttle time
+	}
+	if version >= 7 {
+		e.int16(0) // error code
+		e.int32(0) // session id
+	}
+	e.int32(int32(len(res.topics)))
+	for _, t := range res.topics {
+		e.string(t.name)
+		e.int32(int32(len(t.partitions)))
+		for _, p := range t.partitions {
+			e.int32(p.partition)
+			e.int16(p.errorCode)
+			e.int64(p.highWatermark)
+			if version >= 4 {
+				e.int64(p.highWatermark) // last stable offset
+			}
+			if version >= 5 {
+				e.int64(0) // log start offset
+			}
+			if version >= 4 {
+				e.int32(0) // aborted transactions
+			}
+			if version >= 11 {
+				e.int32(-1) // preferred read replica
+			}
+			if err := e.records(p.records); err != nil {
+				return err
+			}
+		}
+	}
+
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(e.buf.Len()))
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(e.buf.Bytes())
+	return err
+}
+
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) int16(v int16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(v))
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) int32(v int32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(v))
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) int64(v int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) string(v string) {
+	e.int16(int16(len(v)))
+	e.buf.WriteString(v)
+}
+
+// records writes the records as a size prefixed record batch of version 2.
+func (e *encoder) records(records []record) error {
+	if len(records) == 0 {
+		e.int32(0)
+		return nil
+	}
+	protocolRecords := make([]protocol.Record, 0, len(records))
+	for _, r := range records {
+		protocolRecords = append(protocolRecords, protocol.Record{
+			Offset:  r.offset,
+			Time:    r.time,
+			Key:     protocol.NewBytes(r.key),
+			Value:   protocol.NewBytes(r.value),
+			Headers: r.headers,
+		})
+	}
+	var batch bytes.Buffer
+	rs := protocol.RecordSet{Version: 2, Records: protocol.NewRecordReader(protocolRecords...)}
+	if _, err := rs.WriteTo(&batch); err != nil {
+		return err
+	}
+	// 4 字节的长度之后是 batch 的 base offset，不在 crc 的计算范围内
+	data := batch.Bytes()
+	binary.BigEndian.PutUint64(data[4:12], uint64(records[0].offset))
+	e.buf.Write(data)
+	return nil
+}
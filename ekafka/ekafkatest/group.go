@@ -0,0 +1,577 @@
+package ekafkatest
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/consumer"
+	"github.com/segmentio/kafka-go/protocol/describegroups"
+	"github.com/segmentio/kafka-go/protocol/findcoordinator"
+	"github.com/segmentio/kafka-go/protocol/heartbeat"
+	"github.com/segmentio/kafka-go/protocol/joingroup"
+	"github.com/segmentio/kafka-go/protocol/leavegroup"
+	"github.com/segmentio/kafka-go/protocol/listgroups"
+	"github.com/segmentio/kafka-go/protocol/offsetcommit"
+	"github.com/segmentio/kafka-go/protocol/offsetfetch"
+	"github.com/segmentio/kafka-go/protocol/syncgroup"
+)
+
+// 消费组的状态，与 broker 返回的状态相同
+const (
+	GroupStateEmpty               = "Empty"
+	GroupStatePreparingRebalance  = "PreparingRebalance"
+	GroupStateCompletingRebalance = "CompletingRebalance"
+	GroupStateStable              = "Stable"
+	GroupStateDead                = "Dead"
+)
+
+// expireInterval how often the session timeout of members is checked.
+const expireInterval = 100 * time.Millisecond
+
+type group struct {
+	id           string
+	state        string
+	protocolType string
+	protocol     string
+	generation   int32
+	leader       string
+	members      map[string]*member
+	offsets      map[string]map[int32]int64
+	// rebalance 每次开始 rebalance 时加一，用于忽略过期的 timer
+	rebalance int
+	// delaying 新的消费组在等待 initial rebalance delay
+	delaying bool
+	timer    *time.Timer
+}
+
+type member struct {
+	id               string
+	clientID         string
+	clientHost       string
+	sessionTimeout   time.Duration
+	rebalanceTimeout time.Duration
+	protocols        []joingroup.RequestProtocol
+	assignment       []byte
+	lastHeartbeat    time.Time
+	// joining 等待 rebalance 完成的 JoinGroup 请求
+	joining chan *joingroup.Response
+	// syncing 等待 leader 分配分区的 SyncGroup 请求
+	syncing chan *syncgroup.Response
+}
+
+// GroupState 消费组的状态、成员以及成员分配到的分区
+type GroupState struct {
+	State        string
+	GenerationID int
+	Leader       string
+	// Assignments 成员 id 到分配的 topic、分区
+	Assignments map[string]map[string][]int
+}
+
+// Group 返回消费组的状态，消费组不存在时 State 为 GroupStateDead
+func (b *Broker) Group(groupID string) GroupState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g, ok := b.groups[groupID]
+	if !ok {
+		return GroupState{State: GroupStateDead, Assignments: map[string]map[string][]int{}}
+	}
+	state := GroupState{State: g.state, GenerationID: int(g.generation), Leader: g.leader, Assignments: make(map[string]map[string][]int, len(g.members))}
+	for id, m := range g.members {
+		assignments := make(map[string][]int)
+		var assignment consumer.Assignment
+		if len(m.assignment) > 0 && protocol.Unmarshal(m.assignment, 0, &assignment) == nil {
+			for _, tp := range assignment.AssignedPartitions {
+				for _, partition := range tp.Partitions {
+					assignments[tp.Topic] = append(assignments[tp.Topic], int(partition))
+				}
+				sort.Ints(assignments[tp.Topic])
+			}
+		}
+		state.Assignments[id] = assignments
+	}
+	return state
+}
+
+// CommittedOffset 返回消费组在分区上提交的 offset，没有提交过时返回 -1
+func (b *Broker) CommittedOffset(groupID string, topic string, partition int) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if g, ok := b.groups[groupID]; ok {
+		if offset, ok := g.offsets[topic][int32(partition)]; ok {
+			return offset
+		}
+	}
+	return -1
+}
+
+func (b *Broker) group(groupID string) *group {
+	g, ok := b.groups[groupID]
+	if !ok {
+		g = &group{id: groupID, state: GroupStateEmpty, members: make(map[string]*member), offsets: make(map[string]map[int32]int64)}
+		b.groups[groupID] = g
+	}
+	return g
+}
+
+func (b *Broker) findCoordinator() protocol.Message {
+	return &findcoordinator.Response{NodeID: nodeID, Host: b.host, Port: b.port}
+}
+
+func (b *Broker) joinGroup(req request) (protocol.Message, error) {
+	msg := req.msg.(*joingroup.Request)
+	if msg.GroupID == "" {
+		return &joingroup.Response{ErrorCode: int16(kafka.InvalidGroupId), GenerationID: -1}, nil
+	}
+
+	b.mu.Lock()
+	g := b.group(msg.GroupID)
+	m, ok := g.members[msg.MemberID]
+	switch {
+	case msg.MemberID == "":
+		b.memberID++
+		m = &member{id: req.clientID + "-" + strconv.Itoa(b.memberID), clientID: req.clientID, clientHost: req.clientHost}
+		g.members[m.id] = m
+	case !ok:
+		b.mu.Unlock()
+		return &joingroup.Response{ErrorCode: int16(kafka.UnknownMemberId), GenerationID: -1}, nil
+	}
+	m.sessionTimeout = time.Duration(msg.SessionTimeoutMS) * time.Millisecond
+	m.rebalanceTimeout = time.Duration(msg.RebalanceTimeoutMS) * time.Millisecond
+	if m.rebalanceTimeout == 0 {
+		// v0 没有 rebalance timeout，与 session timeout 相同
+		m.rebalanceTimeout = m.sessionTimeout
+	}
+	m.protocols = msg.Protocols
+	m.lastHeartbeat = time.Now()
+	joining := make(chan *joingroup.Response, 1)
+	m.joining = joining
+	g.protocolType = msg.ProtocolType
+
+	b.prepareRebalance(g)
+	b.tryCompleteJoin(g)
+	b.mu.Unlock()
+
+	select {
+	case res := <-joining:
+		return res, nil
+	case <-b.closed:
+		return nil, errClosed
+	}
+}
+
+// prepareRebalance waits for all members to rejoin, members learn it from the heartbeat, the lock must be held.
+func (b *Broker) prepareRebalance(g *group) {
+	if g.state == GroupStatePreparingRebalance {
+		return
+	}
+	for _, m := range g.members {
+		if m.syncing != nil {
+			m.syncing <- &syncgroup.Response{ErrorCode: int16(kafka.RebalanceInProgress)}
+			m.syncing = nil
+		}
+	}
+
+	delay := time.Duration(0)
+	if g.state == GroupStateEmpty {
+		delay = b.initialRebalanceDelay
+		g.delaying = delay > 0
+	} else {
+		for _, m := range g.members {
+			if m.rebalanceTimeout > delay {
+				delay = m.rebalanceTimeout
+			}
+		}
+	}
+	g.state = GroupStatePreparingRebalance
+	g.rebalance++
+	rebalance := g.rebalance
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+	g.timer = time.AfterFunc(delay, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if g.rebalance != rebalance || g.state != GroupStatePreparingRebalance {
+			return
+		}
+		if g.delaying {
+			// initial rebalance delay 结束后继续等待没有加入的成员
+			g.delaying = false
+			b.tryCompleteJoin(g)
+			return
+		}
+		b.completeJoin(g)
+	})
+}
+
+// tryCompleteJoin completes the rebalance if all members have joined, the lock must be held.
+func (b *Broker) tryCompleteJoin(g *group) {
+	if g.state != GroupStatePreparingRebalance || g.delaying {
+		return
+	}
+	for _, m := range g.members {
+		if m.joining == nil {
+			return
+		}
+	}
+	b.completeJoin(g)
+}
+
+// completeJoin removes the members not joined, starts a new generation, and responds the JoinGroup requests, the lock must be held.
+func (b *Broker) completeJoin(g *group) {
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	for id, m := range g.members {
+		if m.joining == nil {
+			delete(g.members, id)
+		}
+	}
+	if len(g.members) == 0 {
+		g.state = GroupStateEmpty
+		g.generation++
+		g.leader = ""
+		g.protocol = ""
+		return
+	}
+
+	ids := make([]string, 0, len(g.members))
+	for id := range g.members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if _, ok := g.members[g.leader]; !ok {
+		g.leader = ids[0]
+	}
+	g.protocol = selectProtocol(g.members[g.leader], g.members)
+	g.generation++
+	g.state = GroupStateCompletingRebalance
+
+	for _, id := range ids {
+		m := g.members[id]
+		m.assignment = nil
+		res := &joingroup.Response{
+			GenerationID: g.generation,
+			ProtocolName: g.protocol,
+			LeaderID:     g.leader,
+			MemberID:     m.id,
+		}
+		if m.id == g.leader {
+			for _, memberID := range ids {
+				res.Members = append(res.Members, joingroup.ResponseMember{
+					MemberID: memberID,
+					Metadata: protocolMetadata(g.members[memberID], g.protocol),
+				})
+			}
+		}
+		m.lastHeartbeat = time.Now()
+		m.joining <- res
+		m.joining = nil
+	}
+}
+
+// selectProtocol returns the first protocol of the leader supported by all members.
+func selectProtocol(leader *member, members map[string]*member) string {
+	for _, p := range leader.protocols {
+		supported := true
+		for _, m := range members {
+			if protocolMetadata(m, p.Name) == nil {
+				supported = false
+				break
+			}
+		}
+		if supported {
+			return p.Name
+		}
+	}
+	return leader.protocols[0].Name
+}
+
+func protocolMetadata(m *member, name string) []byte {
+	for _, p := range m.protocols {
+		if p.Name == name {
+			if p.Metadata == nil {
+				return []byte{}
+			}
+			return p.Metadata
+		}
+	}
+	return nil
+}
+
+// validateMember returns the error code if the member is not in the generation, the lock must be held.
+func validateMember(g *group, memberID string, generation int32) (*member, int16) {
+	m, ok := g.members[memberID]
+	if !ok {
+		return nil, int16(kafka.UnknownMemberId)
+	}
+	if generation != g.generation {
+		return nil, int16(kafka.IllegalGeneration)
+	}
+	return m, 0
+}
+
+func (b *Broker) syncGroup(req request) (protocol.Message, error) {
+	msg := req.msg.(*syncgroup.Request)
+	b.mu.Lock()
+	g := b.group(msg.GroupID)
+	m, code := validateMember(g, msg.MemberID, msg.GenerationID)
+	if code != 0 {
+		b.mu.Unlock()
+		return &syncgroup.Response{ErrorCode: code}, nil
+	}
+	m.lastHeartbeat = time.Now()
+
+	switch g.state {
+	case GroupStateStable:
+		assignment := m.assignment
+		b.mu.Unlock()
+		return &syncgroup.Response{Assignments: assignment}, nil
+	case GroupStateCompletingRebalance:
+	default:
+		b.mu.Unlock()
+		return &syncgroup.Response{ErrorCode: int16(kafka.RebalanceInProgress)}, nil
+	}
+
+	syncing := make(chan *syncgroup.Response, 1)
+	m.syncing = syncing
+	if m.id == g.leader {
+		assignments := make(map[string][]byte, len(msg.Assignments))
+		for _, assignment := range msg.Assignments {
+			assignments[assignment.MemberID] = assignment.Assignment
+		}
+		for id, groupMember := range g.members {
+			groupMember.assignment = assignments[id]
+			if groupMember.syncing != nil {
+				groupMember.syncing <- &syncgroup.Response{Assignments: groupMember.assignment}
+				groupMember.syncing = nil
+			}
+		}
+		g.state = GroupStateStable
+	}
+	b.mu.Unlock()
+
+	select {
+	case res := <-syncing:
+		return res, nil
+	case <-b.closed:
+		return nil, errClosed
+	}
+}
+
+func (b *Broker) heartbeat(req request) protocol.Message {
+	msg := req.msg.(*heartbeat.Request)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g := b.group(msg.GroupID)
+	m, code := validateMember(g, msg.MemberID, msg.GenerationID)
+	if code != 0 {
+		return &heartbeat.Response{ErrorCode: code}
+	}
+	m.lastHeartbeat = time.Now()
+	if g.state == GroupStatePreparingRebalance {
+		return &heartbeat.Response{ErrorCode: int16(kafka.RebalanceInProgress)}
+	}
+	return &heartbeat.Response{}
+}
+
+func (b *Broker) leaveGroup(req request) protocol.Message {
+	msg := req.msg.(*leavegroup.Request)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g := b.group(msg.GroupID)
+	if _, ok := g.members[msg.MemberID]; !ok {
+		return &leavegroup.Response{ErrorCode: int16(kafka.UnknownMemberId)}
+	}
+	b.removeMember(g, msg.MemberID)
+	return &leavegroup.Response{}
+}
+
+// removeMember removes the member and rebalances the group, the lock must be held.
+func (b *Broker) removeMember(g *group, memberID string) {
+	m := g.members[memberID]
+	delete(g.members, memberID)
+	if m.joining != nil {
+		m.joining <- &joingroup.Response{ErrorCode: int16(kafka.UnknownMemberId), GenerationID: -1}
+	}
+	if m.syncing != nil {
+		m.syncing <- &syncgroup.Response{ErrorCode: int16(kafka.UnknownMemberId)}
+	}
+	if len(g.members) == 0 {
+		if g.timer != nil {
+			g.timer.Stop()
+			g.timer = nil
+		}
+		g.state = GroupStateEmpty
+		g.leader = ""
+		g.protocol = ""
+		g.generation++
+		return
+	}
+	b.prepareRebalance(g)
+	b.tryCompleteJoin(g)
+}
+
+// expireMembers removes the members without heartbeat in session timeout.
+func (b *Broker) expireMembers() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.closed:
+			return
+		case now := <-ticker.C:
+			b.mu.Lock()
+			for _, g := range b.groups {
+				for id, m := range g.members {
+					// 等待 rebalance 的成员由 rebalance timeout 控制
+					if m.joining == nil && m.sessionTimeout > 0 && now.Sub(m.lastHeartbeat) > m.sessionTimeout {
+						b.removeMember(g, id)
+					}
+				}
+			}
+			b.mu.Unlock()
+		}
+	}
+}
+
+func (b *Broker) offsetCommit(req request) protocol.Message {
+	msg := req.msg.(*offsetcommit.Request)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g := b.group(msg.GroupID)
+
+	code := int16(0)
+	switch {
+	case msg.GenerationID < 0:
+		// 不属于消费组的提交，只有消费组没有成员时才允许
+		if len(g.members) > 0 {
+			code = int16(kafka.UnknownMemberId)
+		}
+	case g.state == GroupStateCompletingRebalance:
+		code = int16(kafka.RebalanceInProgress)
+	default:
+		var m *member
+		if m, code = validateMember(g, msg.MemberID, msg.GenerationID); code == 0 {
+			m.lastHeartbeat = time.Now()
+		}
+	}
+
+	res := &offsetcommit.Response{}
+	for _, t := range msg.Topics {
+		topicRes := offsetcommit.ResponseTopic{Name: t.Name}
+		existing := b.topic(t.Name)
+		for _, p := range t.Partitions {
+			partitionRes := offsetcommit.ResponsePartition{PartitionIndex: p.PartitionIndex, ErrorCode: code}
+			switch {
+			case code != 0:
+			case existing == nil || p.PartitionIndex < 0 || int(p.PartitionIndex) >= len(existing.partitions):
+				partitionRes.ErrorCode = int16(kafka.UnknownTopicOrPartition)
+			default:
+				if g.offsets[t.Name] == nil {
+					g.offsets[t.Name] = make(map[int32]int64)
+				}
+				g.offsets[t.Name][p.PartitionIndex] = p.CommittedOffset
+			}
+			topicRes.Partitions = append(topicRes.Partitions, partitionRes)
+		}
+		res.Topics = append(res.Topics, topicRes)
+	}
+	return res
+}
+
+func (b *Broker) offsetFetch(req request) protocol.Message {
+	msg := req.msg.(*offsetfetch.Request)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g := b.group(msg.GroupID)
+
+	topics := msg.Topics
+	// v2 开始 topics 为 null 时返回所有提交过的 offset
+	if topics == nil {
+		for name, partitions := range g.offsets {
+			t := offsetfetch.RequestTopic{Name: name}
+			for partition := range partitions {
+				t.PartitionIndexes = append(t.PartitionIndexes, partition)
+			}
+			topics = append(topics, t)
+		}
+	}
+	res := &offsetfetch.Response{}
+	for _, t := range topics {
+		topicRes := offsetfetch.ResponseTopic{Name: t.Name}
+		for _, partition := range t.PartitionIndexes {
+			offset, ok := g.offsets[t.Name][partition]
+			if !ok {
+				offset = -1
+			}
+			topicRes.Partitions = append(topicRes.Partitions, offsetfetch.ResponsePartition{
+				PartitionIndex:      partition,
+				CommittedOffset:     offset,
+				ComittedLeaderEpoch: -1,
+			})
+		}
+		res.Topics = append(res.Topics, topicRes)
+	}
+	return res
+}
+
+func (b *Broker) describeGroups(req request) protocol.Message {
+	msg := req.msg.(*describegroups.Request)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := &describegroups.Response{}
+	for _, groupID := range msg.Groups {
+		g, ok := b.groups[groupID]
+		if !ok {
+			res.Groups = append(res.Groups, describegroups.ResponseGroup{GroupID: groupID, GroupState: GroupStateDead})
+			continue
+		}
+		groupRes := describegroups.ResponseGroup{
+			GroupID:      groupID,
+			GroupState:   g.state,
+			ProtocolType: g.protocolType,
+			ProtocolData: g.protocol,
+		}
+		ids := make([]string, 0, len(g.members))
+		for id := range g.members {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			m := g.members[id]
+			groupRes.Members = append(groupRes.Members, describegroups.ResponseGroupMember{
+				MemberID:         m.id,
+				ClientID:         m.clientID,
+				ClientHost:       m.clientHost,
+				MemberMetadata:   protocolMetadata(m, g.protocol),
+				MemberAssignment: m.assignment,
+			})
+		}
+		res.Groups = append(res.Groups, groupRes)
+	}
+	return res
+}
+
+func (b *Broker) listGroups() protocol.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := &listgroups.Response{}
+	ids := make([]string, 0, len(b.groups))
+	for id := range b.groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		res.Groups = append(res.Groups, listgroups.ResponseGroup{GroupID: id, ProtocolType: b.groups[id].protocolType})
+	}
+	return res
+}
@@ -0,0 +1,107 @@
+package ekafkatest
+
+import (
+	"errors"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/initproducerid"
+)
+
+// producerStateBatches 与 kafka 相同，每个分区记录每个 producer 最近写入的 5 个批次，用于识别重复的批次
+const producerStateBatches = 5
+
+// errResponseLost 写入消息后关闭连接，模拟响应丢失
+var errResponseLost = errors.New("ekafkatest: produce response lost")
+
+type producerKey struct {
+	topic      string
+	partition  int32
+	producerID int64
+}
+
+// producerState the sequences written by a producer to a partition.
+type producerState struct {
+	epoch   int16
+	batches []producedBatch
+}
+
+type producedBatch struct {
+	firstSequence int32
+	lastSequence  int32
+	baseOffset    int64
+}
+
+// LoseProduceResponses 接下来的 n 个写入请求正常写入消息，但是不返回响应并关闭连接，用于测试幂等写入的重试
+func (b *Broker) LoseProduceResponses(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lostProduceResponses = n
+}
+
+// initProducerID assigns a new producer id, transactions are not supported.
+func (b *Broker) initProducerID(req request) protocol.Message {
+	msg := req.msg.(*initproducerid.Request)
+	if msg.TransactionalID != "" {
+		return &initproducerid.Response{ErrorCode: int16(kafka.InvalidRequest), ProducerID: -1, ProducerEpoch: -1}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastProducerID++
+	return &initproducerid.Response{ProducerID: b.lastProducerID}
+}
+
+// checkSequence checks the sequence of an idempotent batch like kafka, the lock must be held.
+// It returns the base offset of the batch if the batch has been written, or -1 if the batch should be appended.
+func (b *Broker) checkSequence(key producerKey, epoch int16, firstSequence int32, count int) (int64, error) {
+	state, ok := b.producers[key]
+	if !ok || epoch > state.epoch {
+		// 新的 producer 或者 epoch 必须从 0 开始
+		if firstSequence != 0 {
+			return -1, kafka.OutOfOrderSequenceNumber
+		}
+		return -1, nil
+	}
+	if epoch < state.epoch {
+		return -1, kafka.InvalidProducerEpoch
+	}
+	lastSequence := firstSequence + int32(count) - 1
+	for _, batch := range state.batches {
+		if batch.firstSequence == firstSequence && batch.lastSequence == lastSequence {
+			return batch.baseOffset, nil
+		}
+	}
+	if last := state.batches[len(state.batches)-1].lastSequence; firstSequence != last+1 {
+		if firstSequence <= last {
+			return -1, kafka.DuplicateSequenceNumber
+		}
+		return -1, kafka.OutOfOrderSequenceNumber
+	}
+	return -1, nil
+}
+
+// recordSequence records an appended idempotent batch, the lock must be held.
+func (b *Broker) recordSequence(key producerKey, epoch int16, firstSequence int32, count int, baseOffset int64) {
+	state, ok := b.producers[key]
+	if !ok || epoch > state.epoch {
+		state = &producerState{epoch: epoch}
+		b.producers[key] = state
+	}
+	state.batches = append(state.batches, producedBatch{
+		firstSequence: firstSequence,
+		lastSequence:  firstSequence + int32(count) - 1,
+		baseOffset:    baseOffset,
+	})
+	if len(state.batches) > producerStateBatches {
+		state.batches = state.batches[1:]
+	}
+}
+
+// idempotentBatch returns the producer of the record set, ok is false if the records are not written idempotently.
+func idempotentBatch(rs protocol.RecordSet) (batch *protocol.RecordBatch, ok bool) {
+	if stream, isStream := rs.Records.(*protocol.RecordStream); isStream && len(stream.Records) == 1 {
+		rs.Records = stream.Records[0]
+	}
+	batch, ok = rs.Records.(*protocol.RecordBatch)
+	return batch, ok && batch.ProducerID >= 0
+}
@@ -0,0 +1,365 @@
+package ekafkatest
+
+import (
+	"io"
+	"sort"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/createpartitions"
+	"github.com/segmentio/kafka-go/protocol/createtopics"
+	"github.com/segmentio/kafka-go/protocol/deletetopics"
+	"github.com/segmentio/kafka-go/protocol/listoffsets"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+)
+
+// topic the partitions of a topic, each partition is a log of records.
+type topic struct {
+	partitions [][]record
+}
+
+type record struct {
+	offset  int64
+	time    time.Time
+	key     []byte
+	value   []byte
+	headers []kafka.Header
+}
+
+func newTopic(partitions int) *topic {
+	if partitions <= 0 {
+		partitions = 1
+	}
+	return &topic{partitions: make([][]record, partitions)}
+}
+
+// CreateTopic 创建 topic，topic 已存在时返回 kafka.TopicAlreadyExists
+func (b *Broker) CreateTopic(name string, partitions int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.topics[name]; ok {
+		return kafka.TopicAlreadyExists
+	}
+	b.topics[name] = newTopic(partitions)
+	return nil
+}
+
+// Produce 直接向分区中写入消息，返回第一条消息的 offset，用于准备测试数据
+func (b *Broker) Produce(topicName string, partition int, messages ...kafka.Message) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.topic(topicName)
+	if t == nil || partition < 0 || partition >= len(t.partitions) {
+		return 0, kafka.UnknownTopicOrPartition
+	}
+	records := make([]record, 0, len(messages))
+	for _, message := range messages {
+		records = append(records, record{time: message.Time, key: message.Key, value: message.Value, headers: message.Headers})
+	}
+	return b.appendRecords(t, partition, records), nil
+}
+
+// Messages 返回分区中的所有消息
+func (b *Broker) Messages(topicName string, partition int) []kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.topics[topicName]
+	if !ok || partition < 0 || partition >= len(t.partitions) {
+		return nil
+	}
+	messages := make([]kafka.Message, 0, len(t.partitions[partition]))
+	for _, r := range t.partitions[partition] {
+		messages = append(messages, kafka.Message{
+			Topic:     topicName,
+			Partition: partition,
+			Offset:    r.offset,
+			Key:       r.key,
+			Value:     r.value,
+			Headers:   r.headers,
+			Time:      r.time,
+		})
+	}
+	return messages
+}
+
+// topic returns the topic, creates it if auto creation is enabled, the lock must be held.
+func (b *Broker) topic(name string) *topic {
+	t, ok := b.topics[name]
+	if !ok && b.autoCreatePartitions > 0 {
+		t = newTopic(b.autoCreatePartitions)
+		b.topics[name] = t
+	}
+	return t
+}
+
+// appendRecords appends the records to the log and wakes up the waiting fetch requests, the lock must be held.
+func (b *Broker) appendRecords(t *topic, partition int, records []record) int64 {
+	baseOffset := int64(len(t.partitions[partition]))
+	now := time.Now()
+	for i := range records {
+		records[i].offset = baseOffset + int64(i)
+		if records[i].time.IsZero() {
+			records[i].time = now
+		}
+		t.partitions[partition] = append(t.partitions[partition], records[i])
+	}
+	if len(records) > 0 {
+		close(b.produced)
+		b.produced = make(chan struct{})
+	}
+	return baseOffset
+}
+
+func (b *Broker) apiVersions() protocol.Message {
+	res := &apiversions.Response{}
+	for apiKey, maxVersion := range maxVersions {
+		if apiKey.MaxVersion() < maxVersion {
+			maxVersion = apiKey.MaxVersion()
+		}
+		res.ApiKeys = append(res.ApiKeys, apiversions.ApiKeyResponse{
+			ApiKey:     int16(apiKey),
+			MinVersion: apiKey.MinVersion(),
+			MaxVersion: maxVersion,
+		})
+	}
+	sort.Slice(res.ApiKeys, func(i, j int) bool {
+		return res.ApiKeys[i].ApiKey < res.ApiKeys[j].ApiKey
+	})
+	return res
+}
+
+func (b *Broker) metadata(req request) protocol.Message {
+	msg := req.msg.(*metadata.Request)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := &metadata.Response{
+		Brokers:      []metadata.ResponseBroker{{NodeID: nodeID, Host: b.host, Port: b.port}},
+		ClusterID:    "ekafkatest",
+		ControllerID: nodeID,
+	}
+	names := msg.TopicNames
+	// v0 中空数组表示所有 topic，之后的版本使用 null 表示所有 topic
+	if names == nil || (req.version == 0 && len(names) == 0) {
+		names = make([]string, 0, len(b.topics))
+		for name := range b.topics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	for _, name := range names {
+		t, ok := b.topics[name]
+		if !ok && (req.version < 4 || msg.AllowAutoTopicCreation) {
+			t = b.topic(name)
+		}
+		if t == nil {
+			res.Topics = append(res.Topics, metadata.ResponseTopic{Name: name, ErrorCode: int16(kafka.UnknownTopicOrPartition)})
+			continue
+		}
+		topicRes := metadata.ResponseTopic{Name: name}
+		for partition := range t.partitions {
+			topicRes.Partitions = append(topicRes.Partitions, metadata.ResponsePartition{
+				PartitionIndex: int32(partition),
+				LeaderID:       nodeID,
+				ReplicaNodes:   []int32{nodeID},
+				IsrNodes:       []int32{nodeID},
+			})
+		}
+		res.Topics = append(res.Topics, topicRes)
+	}
+	return res
+}
+
+func (b *Broker) createTopics(req request) protocol.Message {
+	msg := req.msg.(*createtopics.Request)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := &createtopics.Response{}
+	for _, t := range msg.Topics {
+		topicRes := createtopics.ResponseTopic{Name: t.Name}
+		switch _, ok := b.topics[t.Name]; {
+		case ok:
+			topicRes.ErrorCode = int16(kafka.TopicAlreadyExists)
+		case t.NumPartitions == 0 || t.NumPartitions < -1:
+			topicRes.ErrorCode = int16(kafka.InvalidPartitionNumber)
+		case !msg.ValidateOnly:
+			b.topics[t.Name] = newTopic(int(t.NumPartitions))
+		}
+		res.Topics = append(res.Topics, topicRes)
+	}
+	return res
+}
+
+func (b *Broker) deleteTopics(req request) protocol.Message {
+	msg := req.msg.(*deletetopics.Request)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := &deletetopics.Response{}
+	for _, name := range msg.TopicNames {
+		topicRes := deletetopics.ResponseTopic{Name: name}
+		if _, ok := b.topics[name]; ok {
+			delete(b.topics, name)
+			for key := range b.producers {
+				if key.topic == name {
+					delete(b.producers, key)
+				}
+			}
+		} else {
+			topicRes.ErrorCode = int16(kafka.UnknownTopicOrPartition)
+		}
+		res.Responses = append(res.Responses, topicRes)
+	}
+	return res
+}
+
+func (b *Broker) createPartitions(req request) protocol.Message {
+	msg := req.msg.(*createpartitions.Request)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := &createpartitions.Response{}
+	for _, t := range msg.Topics {
+		result := createpartitions.ResponseResult{Name: t.Name}
+		existing, ok := b.topics[t.Name]
+		switch {
+		case !ok:
+			result.ErrorCode = int16(kafka.UnknownTopicOrPartition)
+		case int(t.Count) <= len(existing.partitions):
+			result.ErrorCode = int16(kafka.InvalidPartitionNumber)
+		case !msg.ValidateOnly:
+			existing.partitions = append(existing.partitions, make([][]record, int(t.Count)-len(existing.partitions))...)
+		}
+		res.Results = append(res.Results, result)
+	}
+	return res
+}
+
+func (b *Broker) produce(req request) (protocol.Message, error) {
+	msg := req.msg.(*produce.Request)
+	res := &produce.Response{}
+	lost := false
+	for _, t := range msg.Topics {
+		topicRes := produce.ResponseTopic{Topic: t.Topic}
+		for _, p := range t.Partitions {
+			partitionRes := produce.ResponsePartition{Partition: p.Partition, LogAppendTime: -1}
+			batch, idempotent := idempotentBatch(p.RecordSet)
+			records, err := readRecords(p.RecordSet)
+			if err != nil {
+				partitionRes.ErrorCode = int16(kafka.InvalidMessage)
+				topicRes.Partitions = append(topicRes.Partitions, partitionRes)
+				continue
+			}
+			b.mu.Lock()
+			existing := b.topic(t.Topic)
+			switch {
+			case existing == nil || int(p.Partition) >= len(existing.partitions) || p.Partition < 0:
+				partitionRes.ErrorCode = int16(kafka.UnknownTopicOrPartition)
+			case idempotent:
+				key := producerKey{topic: t.Topic, partition: p.Partition, producerID: batch.ProducerID}
+				baseOffset, err := b.checkSequence(key, batch.ProducerEpoch, batch.BaseSequence, len(records))
+				switch {
+				case err != nil:
+					partitionRes.ErrorCode = int16(err.(kafka.Error))
+				case baseOffset >= 0:
+					// 重复的批次不再写入，返回第一次写入的 offset
+					partitionRes.BaseOffset = baseOffset
+				default:
+					partitionRes.BaseOffset = b.appendRecords(existing, int(p.Partition), records)
+					b.recordSequence(key, batch.ProducerEpoch, batch.BaseSequence, len(records), partitionRes.BaseOffset)
+				}
+			default:
+				partitionRes.BaseOffset = b.appendRecords(existing, int(p.Partition), records)
+			}
+			b.mu.Unlock()
+			topicRes.Partitions = append(topicRes.Partitions, partitionRes)
+		}
+		res.Topics = append(res.Topics, topicRes)
+	}
+	b.mu.Lock()
+	if b.lostProduceResponses > 0 {
+		b.lostProduceResponses--
+		lost = true
+	}
+	b.mu.Unlock()
+	if lost {
+		return nil, errResponseLost
+	}
+	if !msg.HasResponse() {
+		return nil, nil
+	}
+	return res, nil
+}
+
+func readRecords(rs protocol.RecordSet) ([]record, error) {
+	if rs.Records == nil {
+		return nil, nil
+	}
+	var records []record
+	for {
+		r, err := rs.Records.ReadRecord()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		key, err := protocol.ReadAll(r.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := protocol.ReadAll(r.Value)
+		if err != nil {
+			return nil, err
+		}
+		var headers []kafka.Header
+		for _, header := range r.Headers {
+			headers = append(headers, kafka.Header{Key: header.Key, Value: append([]byte(nil), header.Value...)})
+		}
+		records = append(records, record{time: r.Time, key: key, value: value, headers: headers})
+	}
+}
+
+func (b *Broker) listOffsets(req request) protocol.Message {
+	msg := req.msg.(*listoffsets.Request)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := &listoffsets.Response{}
+	for _, t := range msg.Topics {
+		topicRes := listoffsets.ResponseTopic{Topic: t.Topic}
+		existing := b.topic(t.Topic)
+		for _, p := range t.Partitions {
+			partitionRes := listoffsets.ResponsePartition{Partition: p.Partition, Timestamp: -1, Offset: -1, LeaderEpoch: -1}
+			if existing == nil || p.Partition < 0 || int(p.Partition) >= len(existing.partitions) {
+				partitionRes.ErrorCode = int16(kafka.UnknownTopicOrPartition)
+				topicRes.Partitions = append(topicRes.Partitions, partitionRes)
+				continue
+			}
+			log := existing.partitions[p.Partition]
+			switch p.Timestamp {
+			case kafka.FirstOffset:
+				partitionRes.Offset = 0
+			case kafka.LastOffset:
+				partitionRes.Offset = int64(len(log))
+			default:
+				// 时间戳大于等于请求时间的第一条消息
+				at := time.Unix(0, p.Timestamp*int64(time.Millisecond))
+				for _, r := range log {
+					if !r.time.Before(at) {
+						partitionRes.Offset = r.offset
+						partitionRes.Timestamp = r.time.UnixNano() / int64(time.Millisecond)
+						break
+					}
+				}
+			}
+			topicRes.Partitions = append(topicRes.Partitions, partitionRes)
+		}
+		res.Topics = append(res.Topics, topicRes)
+	}
+	return res
+}
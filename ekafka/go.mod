@@ -6,15 +6,19 @@ require (
 	github.com/BurntSushi/toml v1.1.0
 	github.com/StackExchange/wmi v0.0.0-20210224194228-fe8f1750fd46 // indirect
 	github.com/frankban/quicktest v1.11.3 // indirect
-	github.com/golang/snappy v0.0.3 // indirect
 	github.com/gotomicro/ego v1.1.3
+	github.com/hamba/avro v1.8.0
+	github.com/json-iterator/go v1.1.12
 	github.com/kr/pretty v0.3.0 // indirect
 	github.com/pierrec/lz4 v2.6.0+incompatible // indirect
+	github.com/prometheus/client_golang v1.12.1
 	github.com/rogpeppe/go-internal v1.8.0 // indirect
+	github.com/santhosh-tekuri/jsonschema/v5 v5.2.0
 	github.com/segmentio/kafka-go v0.4.33
 	github.com/spf13/cast v1.4.1
 	github.com/stretchr/testify v1.8.0
 	go.opentelemetry.io/otel v1.7.0
 	go.opentelemetry.io/otel/trace v1.7.0
+	google.golang.org/protobuf v1.28.0
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 )
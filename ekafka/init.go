@@ -0,0 +1,30 @@
+package ekafka
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/ego/server/egovernor"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// instances Build 创建的组件
+var instances = sync.Map{}
+
+func init() {
+	type kafkaStats struct {
+		Kafkas map[string]interface{} `json:"kafkas"`
+	}
+	egovernor.HandleFunc("/debug/kafka/stats", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		rets := kafkaStats{Kafkas: make(map[string]interface{})}
+		instances.Range(func(key, val interface{}) bool {
+			rets.Kafkas[key.(string)] = val.(*Component).ConsumerStats(ctx)
+			return true
+		})
+		_ = jsoniter.NewEncoder(w).Encode(rets)
+	})
+}
@@ -0,0 +1,144 @@
+package ekafka
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+)
+
+var (
+	// consumerLagGauge 分区未消费的消息数量
+	consumerLagGauge = emetric.GaugeVecOpts{
+		Namespace: emetric.DefaultNamespace,
+		Name:      "kafka_consumer_lag",
+		Help:      "kafka consumer lag of partition",
+		Labels:    []string{"name", "consumer", "group", "topic", "partition"},
+	}.Build()
+	// consumerCommittedOffsetGauge 已提交的 offset
+	consumerCommittedOffsetGauge = emetric.GaugeVecOpts{
+		Namespace: emetric.DefaultNamespace,
+		Name:      "kafka_consumer_committed_offset",
+		Help:      "kafka consumer committed offset of partition",
+		Labels:    []string{"name", "consumer", "group", "topic", "partition"},
+	}.Build()
+	// consumerHighWatermarkGauge 分区的 high watermark
+	consumerHighWatermarkGauge = emetric.GaugeVecOpts{
+		Namespace: emetric.DefaultNamespace,
+		Name:      "kafka_consumer_high_watermark",
+		Help:      "kafka high watermark of partition",
+		Labels:    []string{"name", "consumer", "group", "topic", "partition"},
+	}.Build()
+	// consumerAssignmentGauge 分区分配给当前实例时为 1
+	consumerAssignmentGauge = emetric.GaugeVecOpts{
+		Namespace: emetric.DefaultNamespace,
+		Name:      "kafka_consumer_assignment",
+		Help:      "kafka partition assigned to this instance",
+		Labels:    []string{"name", "consumer", "group", "topic", "partition"},
+	}.Build()
+)
+
+// lagCollector publishes the consumer stats of a component as gauges periodically.
+type lagCollector struct {
+	cmp      *Component
+	interval time.Duration
+	logger   *elog.Component
+	stop     chan struct{}
+	done     chan struct{}
+
+	mu sync.Mutex
+	// published 上一次上报的 label，分区被回收或消费者关闭后删除对应的 label
+	published map[string][]string
+}
+
+func newLagCollector(cmp *Component, interval time.Duration) *lagCollector {
+	return &lagCollector{
+		cmp:       cmp,
+		interval:  interval,
+		logger:    cmp.logger,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		published: make(map[string][]string),
+	}
+}
+
+// run collects the stats every interval until Close is called.
+func (c *lagCollector) run() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), c.interval)
+		c.collect(ctx)
+		cancel()
+	}
+}
+
+// Close stops collecting and deletes the published gauges.
+func (c *lagCollector) Close() {
+	close(c.stop)
+	<-c.done
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, labels := range c.published {
+		deleteLagGauges(labels)
+	}
+	c.published = nil
+}
+
+func (c *lagCollector) collect(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := make(map[string][]string)
+	for _, stats := range c.cmp.ConsumerStats(ctx) {
+		if stats.Error != "" {
+			c.logger.Warn("collect consumer stats fail", elog.String("consumer", stats.Name), elog.String("error", stats.Error))
+			// 获取失败时保留上一次上报的数据
+			for key, labels := range c.published {
+				if labels[1] == stats.Name && labels[2] == stats.GroupID {
+					current[key] = labels
+				}
+			}
+			continue
+		}
+		for _, partition := range stats.Partitions {
+			labels := []string{c.cmp.GetCompName(), stats.Name, stats.GroupID, partition.Topic, strconv.Itoa(partition.Partition)}
+			consumerLagGauge.Set(float64(partition.Lag), labels...)
+			consumerHighWatermarkGauge.Set(float64(partition.HighWatermark), labels...)
+			if partition.CommittedOffset >= 0 {
+				consumerCommittedOffsetGauge.Set(float64(partition.CommittedOffset), labels...)
+			} else {
+				consumerCommittedOffsetGauge.DeleteLabelValues(labels...)
+			}
+			if partition.Assigned {
+				consumerAssignmentGauge.Set(1, labels...)
+			} else {
+				consumerAssignmentGauge.DeleteLabelValues(labels...)
+			}
+			current[strings.Join(labels, "\x00")] = labels
+		}
+	}
+	for key, labels := range c.published {
+		if _, ok := current[key]; !ok {
+			deleteLagGauges(labels)
+		}
+	}
+	c.published = current
+}
+
+func deleteLagGauges(labels []string) {
+	consumerLagGauge.DeleteLabelValues(labels...)
+	consumerHighWatermarkGauge.DeleteLabelValues(labels...)
+	consumerCommittedOffsetGauge.DeleteLabelValues(labels...)
+	consumerAssignmentGauge.DeleteLabelValues(labels...)
+}
@@ -1,6 +1,8 @@
 package ekafka
 
 import (
+	"crypto/tls"
+
 	"github.com/segmentio/kafka-go"
 )
 
@@ -41,3 +43,24 @@ func WithRegisterBalancer(balancerName string, balancer Balancer) Option {
 		c.config.balancers[balancerName] = balancer
 	}
 }
+
+// WithTLSConfig 注入 TLS 配置，优先于配置文件中的 TLS 配置项
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *Container) {
+		c.config.tlsConfig = tlsConfig
+	}
+}
+
+// WithOAuthBearerTokenSource 注入 OAUTHBEARER 认证的 token 来源，优先于 saslOAuth 配置
+func WithOAuthBearerTokenSource(tokenSource OAuthBearerTokenSource) Option {
+	return func(c *Container) {
+		c.config.oauthTokenSource = tokenSource
+	}
+}
+
+// WithAWSCredentialsProvider 注入 AWS_MSK_IAM 认证的凭证来源，默认使用 saslUserName、saslPassword 或环境变量中的凭证
+func WithAWSCredentialsProvider(provider AWSCredentialsProvider) Option {
+	return func(c *Container) {
+		c.config.awsCredentials = provider
+	}
+}
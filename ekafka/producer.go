@@ -16,6 +16,11 @@ func (p *Producer) setProcessor(c ClientInterceptor) {
 	p.processor = c
 }
 
+// Topic returns the topic of the producer, empty if the topic is set by each message.
+func (p *Producer) Topic() string {
+	return p.w.Topic
+}
+
 func (p *Producer) Close() error {
 	return p.processor(func(ctx context.Context, msgs Messages, c *cmd) error {
 		logCmd(p.logMode, c, "ProducerClose", cmdWithTopic(p.w.Topic))
@@ -8,9 +8,50 @@ import (
 	"github.com/segmentio/kafka-go/sasl/scram"
 )
 
-func NewMechanism(saslMechanism, saslUserName, saslPassword string) (sasl.Mechanism, error) {
+const (
+	// SASLMechanismOAuthBearer OAUTHBEARER 认证，token 由 OAuthBearerTokenSource 提供
+	SASLMechanismOAuthBearer = "OAUTHBEARER"
+	// SASLMechanismAWSMSKIAM AWS MSK IAM 认证，使用 AWS 凭证签名
+	SASLMechanismAWSMSKIAM = "AWS_MSK_IAM"
+)
+
+// MechanismOption 设置 OAUTHBEARER、AWS_MSK_IAM 认证所需的参数
+type MechanismOption func(o *mechanismOptions)
+
+type mechanismOptions struct {
+	tokenSource    OAuthBearerTokenSource
+	awsRegion      string
+	awsCredentials AWSCredentialsProvider
+}
+
+// WithMechanismTokenSource 设置 OAUTHBEARER 认证的 token 来源
+func WithMechanismTokenSource(tokenSource OAuthBearerTokenSource) MechanismOption {
+	return func(o *mechanismOptions) {
+		o.tokenSource = tokenSource
+	}
+}
+
+// WithMechanismAWSRegion 设置 AWS_MSK_IAM 认证时 MSK 集群所在的 region
+func WithMechanismAWSRegion(region string) MechanismOption {
+	return func(o *mechanismOptions) {
+		o.awsRegion = region
+	}
+}
+
+// WithMechanismAWSCredentials 设置 AWS_MSK_IAM 认证的凭证来源
+func WithMechanismAWSCredentials(provider AWSCredentialsProvider) MechanismOption {
+	return func(o *mechanismOptions) {
+		o.awsCredentials = provider
+	}
+}
+
+func NewMechanism(saslMechanism, saslUserName, saslPassword string, opts ...MechanismOption) (sasl.Mechanism, error) {
 	var mechanism sasl.Mechanism
 	var err error
+	options := &mechanismOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
 	if saslMechanism != "" {
 		switch saslMechanism {
 		case "SCRAM-SHA-256":
@@ -28,6 +69,20 @@ func NewMechanism(saslMechanism, saslUserName, saslPassword string) (sasl.Mechan
 				Username: saslUserName,
 				Password: saslPassword,
 			}
+		case SASLMechanismOAuthBearer:
+			if options.tokenSource == nil {
+				return nil, errors.New("token source is required by OAUTHBEARER mechanism")
+			}
+			mechanism = &oauthBearerMechanism{tokenSource: options.tokenSource}
+		case SASLMechanismAWSMSKIAM:
+			credentials := options.awsCredentials
+			if credentials == nil {
+				credentials = NewAWSCredentialsProvider(saslUserName, saslPassword)
+			}
+			mechanism, err = newAWSMSKIAMMechanism(options.awsRegion, credentials)
+			if err != nil {
+				return nil, err
+			}
 		default:
 			return nil, errors.New("unknown mechanism")
 		}
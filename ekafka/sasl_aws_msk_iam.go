@@ -0,0 +1,178 @@
+package ekafka
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+const (
+	awsMSKIAMVersion   = "2020_10_22"
+	awsMSKIAMService   = "kafka-cluster"
+	awsMSKIAMAction    = "kafka-cluster:Connect"
+	awsMSKIAMUserAgent = "ego-component/ekafka"
+	awsSigningAlgo     = "AWS4-HMAC-SHA256"
+	awsSignExpires     = 5 * time.Minute
+	// awsEmptyPayloadHash 空请求体的 sha256
+	awsEmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// AWSCredentials AWS 访问凭证
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// AWSCredentialsProvider 提供 AWS_MSK_IAM 认证使用的凭证，每次建立连接时调用。
+// 需要使用实例角色、IRSA 等凭证时，可以基于 AWS SDK 的 CredentialsProvider 实现
+type AWSCredentialsProvider interface {
+	Retrieve(ctx context.Context) (AWSCredentials, error)
+}
+
+// AWSCredentialsProviderFunc 将函数转换为 AWSCredentialsProvider
+type AWSCredentialsProviderFunc func(ctx context.Context) (AWSCredentials, error)
+
+// Retrieve ...
+func (f AWSCredentialsProviderFunc) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	return f(ctx)
+}
+
+// NewAWSCredentialsProvider returns the static credentials if accessKeyID is set,
+// or the credentials of AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+func NewAWSCredentialsProvider(accessKeyID, secretAccessKey string) AWSCredentialsProvider {
+	return AWSCredentialsProviderFunc(func(ctx context.Context) (AWSCredentials, error) {
+		if accessKeyID != "" {
+			return AWSCredentials{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey}, nil
+		}
+		credentials := AWSCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+			return AWSCredentials{}, errors.New("aws credentials not found")
+		}
+		return credentials, nil
+	})
+}
+
+// awsMSKIAMMechanism implements the AWS_MSK_IAM mechanism, the initial response is a JSON of
+// the SigV4 presigned kafka-cluster:Connect request of the broker.
+type awsMSKIAMMechanism struct {
+	region      string
+	credentials AWSCredentialsProvider
+	now         func() time.Time
+}
+
+func newAWSMSKIAMMechanism(region string, credentials AWSCredentialsProvider) (*awsMSKIAMMechanism, error) {
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		return nil, errors.New("aws region is required by AWS_MSK_IAM mechanism")
+	}
+	return &awsMSKIAMMechanism{region: region, credentials: credentials, now: time.Now}, nil
+}
+
+func (m *awsMSKIAMMechanism) Name() string {
+	return SASLMechanismAWSMSKIAM
+}
+
+func (m *awsMSKIAMMechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	metadata := sasl.MetadataFromContext(ctx)
+	if metadata == nil {
+		return nil, nil, errors.New("missing broker metadata of AWS_MSK_IAM mechanism")
+	}
+	credentials, err := m.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("retrieve aws credentials fail, %w", err)
+	}
+	payload, err := json.Marshal(awsMSKIAMPayload(metadata.Host, m.region, credentials, m.now()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, payload, nil
+}
+
+func (m *awsMSKIAMMechanism) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	// 认证成功后服务端返回包含 version、request-id 的 JSON
+	if len(challenge) == 0 {
+		return false, nil, errors.New("unexpected empty challenge of AWS_MSK_IAM mechanism")
+	}
+	return true, nil, nil
+}
+
+// awsMSKIAMPayload signs the kafka-cluster:Connect request of host, returns the fields of the payload.
+func awsMSKIAMPayload(host, region string, credentials AWSCredentials, now time.Time) map[string]string {
+	var (
+		amzDate = now.UTC().Format("20060102T150405Z")
+		date    = amzDate[:8]
+		scope   = date + "/" + region + "/" + awsMSKIAMService + "/aws4_request"
+	)
+	query := url.Values{
+		"Action":              {awsMSKIAMAction},
+		"X-Amz-Algorithm":     {awsSigningAlgo},
+		"X-Amz-Credential":    {credentials.AccessKeyID + "/" + scope},
+		"X-Amz-Date":          {amzDate},
+		"X-Amz-Expires":       {strconv.Itoa(int(awsSignExpires / time.Second))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	if credentials.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	// 预签名请求 GET kafka://host/?Action=kafka-cluster:Connect，只签名 host 头
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		"/",
+		strings.Replace(query.Encode(), "+", "%20", -1),
+		"host:" + host + "\n",
+		"host",
+		awsEmptyPayloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{awsSigningAlgo, amzDate, scope, hashSHA256Hex([]byte(canonicalRequest))}, "\n")
+	signingKey := awsSigningKey(credentials.SecretAccessKey, date, region, awsMSKIAMService)
+	query.Set("X-Amz-Signature", hex.EncodeToString(hmacSHA256(signingKey, []byte(stringToSign))))
+
+	payload := map[string]string{
+		"version":    awsMSKIAMVersion,
+		"host":       host,
+		"user-agent": awsMSKIAMUserAgent,
+	}
+	// 协议要求 key 为小写
+	for key, values := range query {
+		payload[strings.ToLower(key)] = values[0]
+	}
+	return payload
+}
+
+// awsSigningKey derives the SigV4 signing key.
+func awsSigningKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), []byte(date))
+	key = hmacSHA256(key, []byte(region))
+	key = hmacSHA256(key, []byte(service))
+	return hmacSHA256(key, []byte("aws4_request"))
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write(data)
+	return h.Sum(nil)
+}
+
+func hashSHA256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
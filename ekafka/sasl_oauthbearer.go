@@ -0,0 +1,169 @@
+package ekafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+// oauthTokenRefreshWindow token 过期前多久刷新
+const oauthTokenRefreshWindow = time.Minute
+
+// OAuthBearerToken OAUTHBEARER 认证使用的 token
+type OAuthBearerToken struct {
+	// Value access token
+	Value string
+	// Extensions SASL 扩展参数，如 Confluent Cloud 的 logicalCluster、identityPoolId
+	Extensions map[string]string
+	// ExpiresAt 过期时间，零值表示不过期
+	ExpiresAt time.Time
+}
+
+// OAuthBearerTokenSource 提供 OAUTHBEARER 认证的 token，每次建立连接时调用，需要自行缓存 token
+type OAuthBearerTokenSource interface {
+	Token(ctx context.Context) (OAuthBearerToken, error)
+}
+
+// OAuthBearerTokenSourceFunc 将函数转换为 OAuthBearerTokenSource
+type OAuthBearerTokenSourceFunc func(ctx context.Context) (OAuthBearerToken, error)
+
+// Token ...
+func (f OAuthBearerTokenSourceFunc) Token(ctx context.Context) (OAuthBearerToken, error) {
+	return f(ctx)
+}
+
+// oauthBearerMechanism implements the OAUTHBEARER mechanism of RFC 7628 used by Kafka (KIP-255).
+type oauthBearerMechanism struct {
+	tokenSource OAuthBearerTokenSource
+}
+
+func (m *oauthBearerMechanism) Name() string {
+	return SASLMechanismOAuthBearer
+}
+
+func (m *oauthBearerMechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	token, err := m.tokenSource.Token(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get oauth token fail, %w", err)
+	}
+	if token.Value == "" {
+		return nil, nil, errors.New("get oauth token fail, empty token")
+	}
+	return m, oauthBearerInitialResponse(token), nil
+}
+
+func (m *oauthBearerMechanism) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	// 认证失败时服务端返回错误信息
+	if len(challenge) > 0 {
+		return false, nil, fmt.Errorf("oauthbearer authentication fail, %s", challenge)
+	}
+	return true, nil, nil
+}
+
+// oauthBearerInitialResponse returns the client initial response: gs2 header, auth and the sorted extensions.
+func oauthBearerInitialResponse(token OAuthBearerToken) []byte {
+	var b strings.Builder
+	b.WriteString("n,,\x01auth=Bearer ")
+	b.WriteString(token.Value)
+	keys := make([]string, 0, len(token.Extensions))
+	for key := range token.Extensions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteString("\x01" + key + "=" + token.Extensions[key])
+	}
+	b.WriteString("\x01\x01")
+	return []byte(b.String())
+}
+
+// ClientCredentialsTokenSource 使用 OAuth 2.0 client credentials 获取 token，token 过期前自动刷新
+type ClientCredentialsTokenSource struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Extensions 附加在每个 token 上的 SASL 扩展参数
+	Extensions map[string]string
+	// HTTPClient 为空时使用 http.DefaultClient
+	HTTPClient *http.Client
+
+	mu    sync.Mutex
+	token OAuthBearerToken
+	now   func() time.Time
+}
+
+// Token returns the cached token, a new token is requested if the cached one expires within a minute.
+func (s *ClientCredentialsTokenSource) Token(ctx context.Context) (OAuthBearerToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+	if s.token.Value != "" && (s.token.ExpiresAt.IsZero() || now.Add(oauthTokenRefreshWindow).Before(s.token.ExpiresAt)) {
+		return s.token, nil
+	}
+	token, err := s.fetch(ctx, now)
+	if err != nil {
+		return OAuthBearerToken{}, err
+	}
+	s.token = token
+	return token, nil
+}
+
+func (s *ClientCredentialsTokenSource) fetch(ctx context.Context, now time.Time) (OAuthBearerToken, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.Scopes, " "))
+	}
+	req, err := http.NewRequest(http.MethodPost, s.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return OAuthBearerToken{}, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(s.ClientID), url.QueryEscape(s.ClientSecret))
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return OAuthBearerToken{}, fmt.Errorf("request oauth token fail, %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return OAuthBearerToken{}, fmt.Errorf("read oauth token fail, %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return OAuthBearerToken{}, fmt.Errorf("request oauth token fail, status %d, body %s", resp.StatusCode, body)
+	}
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return OAuthBearerToken{}, fmt.Errorf("decode oauth token fail, %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return OAuthBearerToken{}, errors.New("decode oauth token fail, empty access_token")
+	}
+	token := OAuthBearerToken{Value: tokenResp.AccessToken, Extensions: s.Extensions}
+	if tokenResp.ExpiresIn > 0 {
+		token.ExpiresAt = now.Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
@@ -0,0 +1,142 @@
+package ekafka
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMechanism(t *testing.T) {
+	mechanism, err := NewMechanism("", "", "")
+	assert.NoError(t, err)
+	assert.Nil(t, mechanism)
+
+	_, err = NewMechanism("UNKNOWN", "", "")
+	assert.Error(t, err)
+
+	_, err = NewMechanism(SASLMechanismOAuthBearer, "", "")
+	assert.Error(t, err)
+	mechanism, err = NewMechanism(SASLMechanismOAuthBearer, "", "", WithMechanismTokenSource(OAuthBearerTokenSourceFunc(func(ctx context.Context) (OAuthBearerToken, error) {
+		return OAuthBearerToken{Value: "token"}, nil
+	})))
+	assert.NoError(t, err)
+	assert.Equal(t, SASLMechanismOAuthBearer, mechanism.Name())
+
+	mechanism, err = NewMechanism(SASLMechanismAWSMSKIAM, "AKID", "SECRET", WithMechanismAWSRegion("us-east-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, SASLMechanismAWSMSKIAM, mechanism.Name())
+}
+
+func TestOAuthBearerMechanism(t *testing.T) {
+	mechanism := &oauthBearerMechanism{tokenSource: OAuthBearerTokenSourceFunc(func(ctx context.Context) (OAuthBearerToken, error) {
+		return OAuthBearerToken{Value: "abc", Extensions: map[string]string{"logicalCluster": "lkc-1", "identityPoolId": "pool-1"}}, nil
+	})}
+	sm, ir, err := mechanism.Start(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "n,,\x01auth=Bearer abc\x01identityPoolId=pool-1\x01logicalCluster=lkc-1\x01\x01", string(ir))
+
+	done, _, err := sm.Next(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	_, _, err = sm.Next(context.Background(), []byte(`{"status":"invalid_token"}`))
+	assert.Error(t, err)
+}
+
+func TestClientCredentialsTokenSource(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		user, pass, _ := r.BasicAuth()
+		if user != "client" || pass != "secret" || r.PostFormValue("grant_type") != "client_credentials" || r.PostFormValue("scope") != "kafka read" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": fmt.Sprintf("token-%d", n), "expires_in": 300})
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	source := &ClientCredentialsTokenSource{
+		TokenURL:     server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Scopes:       []string{"kafka", "read"},
+		now:          func() time.Time { return now },
+	}
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token.Value)
+	assert.Equal(t, now.Add(300*time.Second), token.ExpiresAt)
+
+	// 未到刷新时间时使用缓存
+	now = now.Add(3 * time.Minute)
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token.Value)
+
+	// 过期前一分钟内刷新
+	now = now.Add(time.Minute + time.Second)
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token.Value)
+
+	source = &ClientCredentialsTokenSource{TokenURL: server.URL, ClientID: "client", ClientSecret: "wrong"}
+	_, err = source.Token(context.Background())
+	assert.Error(t, err)
+}
+
+func TestAWSSigningKey(t *testing.T) {
+	// AWS 文档中的 SigV4 签名密钥示例
+	key := awsSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	assert.Equal(t, "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d", hex.EncodeToString(key))
+}
+
+func TestAWSMSKIAMMechanism(t *testing.T) {
+	mechanism, err := newAWSMSKIAMMechanism("us-east-1", NewAWSCredentialsProvider("AKID", "SECRET"))
+	require.NoError(t, err)
+	mechanism.now = func() time.Time { return time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	_, _, err = mechanism.Start(context.Background())
+	assert.Error(t, err)
+
+	ctx := sasl.WithMetadata(context.Background(), &sasl.Metadata{Host: "b-1.msk.us-east-1.amazonaws.com", Port: 9098})
+	sm, ir, err := mechanism.Start(ctx)
+	require.NoError(t, err)
+	var payload map[string]string
+	require.NoError(t, json.Unmarshal(ir, &payload))
+	assert.Equal(t, "2020_10_22", payload["version"])
+	assert.Equal(t, "b-1.msk.us-east-1.amazonaws.com", payload["host"])
+	assert.Equal(t, "kafka-cluster:Connect", payload["action"])
+	assert.Equal(t, "AWS4-HMAC-SHA256", payload["x-amz-algorithm"])
+	assert.Equal(t, "AKID/20210102/us-east-1/kafka-cluster/aws4_request", payload["x-amz-credential"])
+	assert.Equal(t, "20210102T030405Z", payload["x-amz-date"])
+	assert.Equal(t, "300", payload["x-amz-expires"])
+	assert.Equal(t, "host", payload["x-amz-signedheaders"])
+	assert.Len(t, payload["x-amz-signature"], 64)
+	_, ok := payload["x-amz-security-token"]
+	assert.False(t, ok)
+
+	// 签名是确定的，session token 参与签名
+	_, again, err := mechanism.Start(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ir, again)
+	withToken := awsMSKIAMPayload("b-1.msk.us-east-1.amazonaws.com", "us-east-1", AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET", SessionToken: "TOKEN"}, mechanism.now())
+	assert.Equal(t, "TOKEN", withToken["x-amz-security-token"])
+	// 与 AWS SDK v4.Signer.Presign 的签名结果一致
+	assert.Equal(t, "6ed28555086f12db60b3ff52a3326ecf86bd54579a9d79002eca988cca8640c4", withToken["x-amz-signature"])
+	assert.NotEqual(t, payload["x-amz-signature"], withToken["x-amz-signature"])
+
+	done, _, err := sm.Next(ctx, []byte(`{"version":"2020_10_22","request-id":"1"}`))
+	assert.NoError(t, err)
+	assert.True(t, done)
+}
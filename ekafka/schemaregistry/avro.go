@@ -0,0 +1,57 @@
+package schemaregistry
+
+import (
+	"context"
+
+	"github.com/hamba/avro"
+)
+
+// AvroSerializer 使用 Avro schema 编码消息，v 可以是带 avro tag 的结构体或者 map[string]interface{}
+type AvroSerializer struct {
+	serde *serde
+}
+
+// NewAvroSerializer ...
+func NewAvroSerializer(registry Registry, opts ...SerdeOption) *AvroSerializer {
+	return &AvroSerializer{serde: newSerde(registry, SchemaTypeAvro, parseAvroSchema, opts)}
+}
+
+// Serialize ...
+func (s *AvroSerializer) Serialize(ctx context.Context, topic string, v interface{}) ([]byte, error) {
+	id, schema, err := s.serde.writerSchema(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := avro.Marshal(schema.(avro.Schema), v)
+	if err != nil {
+		return nil, err
+	}
+	return append(appendHeader(make([]byte, 0, headerSize+len(payload)), id), payload...), nil
+}
+
+// AvroDeserializer 使用写入时的 Avro schema 解码消息
+type AvroDeserializer struct {
+	serde *serde
+}
+
+// NewAvroDeserializer ...
+func NewAvroDeserializer(registry Registry, opts ...SerdeOption) *AvroDeserializer {
+	return &AvroDeserializer{serde: newSerde(registry, SchemaTypeAvro, parseAvroSchema, opts)}
+}
+
+// Deserialize ...
+func (d *AvroDeserializer) Deserialize(ctx context.Context, topic string, data []byte, v interface{}) error {
+	id, payload, err := readHeader(data)
+	if err != nil {
+		return err
+	}
+	schema, err := d.serde.schema(ctx, id)
+	if err != nil {
+		return err
+	}
+	return avro.Unmarshal(schema.(avro.Schema), payload, v)
+}
+
+func parseAvroSchema(schema Schema) (interface{}, error) {
+	return avro.Parse(schema.Schema)
+}
@@ -0,0 +1,219 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+// ClientOption 设置 Client
+type ClientOption func(c *Client)
+
+// WithBasicAuth 设置 basic 认证的用户名、密码
+func WithBasicAuth(username, password string) ClientOption {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithHTTPClient 设置请求 schema registry 使用的 http.Client
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithLatestCacheTTL 设置 subject 最新版本的缓存时间，0 表示不缓存
+func WithLatestCacheTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.latestCacheTTL = ttl
+	}
+}
+
+type latestCache struct {
+	metadata SchemaMetadata
+	expireAt time.Time
+}
+
+// Client Confluent Schema Registry REST API 客户端。
+// 注册过、查询过的 schema 以及 id 在本地永久缓存，subject 的最新版本缓存 latestCacheTTL
+type Client struct {
+	baseURL        string
+	username       string
+	password       string
+	httpClient     *http.Client
+	latestCacheTTL time.Duration
+
+	mu      sync.RWMutex
+	schemas map[int]Schema
+	ids     map[string]int
+	latest  map[string]latestCache
+	nowFunc func() time.Time
+}
+
+// NewClient returns a client of the schema registry at baseURL.
+func NewClient(baseURL string, opts ...ClientOption) *Client {
+	c := &Client{
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		httpClient:     &http.Client{Timeout: 5 * time.Second},
+		latestCacheTTL: time.Minute,
+		schemas:        make(map[int]Schema),
+		ids:            make(map[string]int),
+		latest:         make(map[string]latestCache),
+		nowFunc:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Register ...
+func (c *Client) Register(ctx context.Context, subject string, schema Schema) (int, error) {
+	key, err := idCacheKey(subject, schema)
+	if err != nil {
+		return 0, err
+	}
+	if id, ok := c.cachedID(key); ok {
+		return id, nil
+	}
+	var res struct {
+		ID int `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", schema, &res); err != nil {
+		return 0, err
+	}
+	c.cacheID(key, res.ID, schema)
+	return res.ID, nil
+}
+
+// Lookup ...
+func (c *Client) Lookup(ctx context.Context, subject string, schema Schema) (int, error) {
+	key, err := idCacheKey(subject, schema)
+	if err != nil {
+		return 0, err
+	}
+	if id, ok := c.cachedID(key); ok {
+		return id, nil
+	}
+	var res SchemaMetadata
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject), schema, &res); err != nil {
+		return 0, err
+	}
+	c.cacheID(key, res.ID, schema)
+	return res.ID, nil
+}
+
+// GetByID ...
+func (c *Client) GetByID(ctx context.Context, id int) (Schema, error) {
+	c.mu.RLock()
+	schema, ok := c.schemas[id]
+	c.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &schema); err != nil {
+		return Schema{}, err
+	}
+	c.mu.Lock()
+	c.schemas[id] = schema
+	c.mu.Unlock()
+	return schema, nil
+}
+
+// GetLatest ...
+func (c *Client) GetLatest(ctx context.Context, subject string) (SchemaMetadata, error) {
+	now := c.nowFunc()
+	c.mu.RLock()
+	cached, ok := c.latest[subject]
+	c.mu.RUnlock()
+	if ok && now.Before(cached.expireAt) {
+		return cached.metadata, nil
+	}
+	var res SchemaMetadata
+	if err := c.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/latest", nil, &res); err != nil {
+		return SchemaMetadata{}, err
+	}
+	c.mu.Lock()
+	c.schemas[res.ID] = res.Schema
+	if c.latestCacheTTL > 0 {
+		c.latest[subject] = latestCache{metadata: res, expireAt: now.Add(c.latestCacheTTL)}
+	}
+	c.mu.Unlock()
+	return res, nil
+}
+
+func (c *Client) cachedID(key string) (int, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	id, ok := c.ids[key]
+	return id, ok
+}
+
+func (c *Client) cacheID(key string, id int, schema Schema) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ids[key] = id
+	c.schemas[id] = schema
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, res interface{}) error {
+	var reqBody []byte
+	if body != nil {
+		var err error
+		if reqBody, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("schemaregistry: request %s fail, %w", path, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("schemaregistry: read response of %s fail, %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		e := &Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(respBody, e) != nil || e.Message == "" {
+			e.Message = string(respBody)
+		}
+		return e
+	}
+	if err := json.Unmarshal(respBody, res); err != nil {
+		return fmt.Errorf("schemaregistry: decode response of %s fail, %w", path, err)
+	}
+	return nil
+}
+
+// idCacheKey returns the cache key of the schema registered under subject.
+func idCacheKey(subject string, schema Schema) (string, error) {
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return "", err
+	}
+	return subject + "\x00" + string(b), nil
+}
@@ -0,0 +1,192 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer serves the schema registry REST API with a FileRegistry.
+func newTestServer(t *testing.T, requests *int32) *httptest.Server {
+	registry, err := NewFileRegistry(filepath.Join(t.TempDir(), "schemas.json"))
+	require.NoError(t, err)
+	writeError := func(w http.ResponseWriter, err error) {
+		status, code := http.StatusInternalServerError, 50001
+		if errors.Is(err, ErrNotFound) {
+			status, code = http.StatusNotFound, 40403
+		}
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(Error{Code: code, Message: err.Error()})
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/subjects/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if user, pass, _ := r.BasicAuth(); user != "key" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var (
+			subject string
+			action  string
+		)
+		rest := r.URL.Path[len("/subjects/"):]
+		for i := 0; i < len(rest); i++ {
+			if rest[i] == '/' {
+				subject, action = rest[:i], rest[i:]
+				break
+			}
+		}
+		if subject == "" {
+			subject = rest
+		}
+		var schema Schema
+		if r.Method == http.MethodPost {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&schema))
+		}
+		switch {
+		case r.Method == http.MethodPost && action == "/versions":
+			id, err := registry.Register(r.Context(), subject, schema)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]int{"id": id})
+		case r.Method == http.MethodPost && action == "":
+			id, err := registry.Lookup(r.Context(), subject, schema)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(SchemaMetadata{Schema: schema, ID: id, Subject: subject})
+		case r.Method == http.MethodGet && action == "/versions/latest":
+			metadata, err := registry.GetLatest(r.Context(), subject)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(metadata)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/schemas/ids/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		var id int
+		_ = json.Unmarshal([]byte(r.URL.Path[len("/schemas/ids/"):]), &id)
+		schema, err := registry.GetByID(r.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(schema)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient(t *testing.T) {
+	var requests int32
+	server := newTestServer(t, &requests)
+	ctx := context.Background()
+	now := time.Now()
+	client := NewClient(server.URL+"/", WithBasicAuth("key", "secret"))
+	client.nowFunc = func() time.Time { return now }
+
+	schema := Schema{Schema: `{"type":"string"}`}
+	id, err := client.Register(ctx, "topic-value", schema)
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+	// 注册过的 schema 使用缓存
+	id, err = client.Register(ctx, "topic-value", schema)
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+	id, err = client.Lookup(ctx, "topic-value", schema)
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+	got, err := client.GetByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, schema, got)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	latest, err := client.GetLatest(ctx, "topic-value")
+	require.NoError(t, err)
+	assert.Equal(t, 1, latest.ID)
+	assert.Equal(t, 1, latest.Version)
+	assert.Equal(t, SchemaTypeAvro, latest.Type())
+	_, err = client.GetLatest(ctx, "topic-value")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// 最新版本缓存过期后重新请求
+	_, err = client.Register(ctx, "topic-value", Schema{Schema: `{"type":"int"}`})
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	latest, err = client.GetLatest(ctx, "topic-value")
+	require.NoError(t, err)
+	assert.Equal(t, 2, latest.ID)
+	assert.Equal(t, 2, latest.Version)
+
+	_, err = client.Lookup(ctx, "topic-value", Schema{Schema: `{"type":"long"}`})
+	assert.True(t, errors.Is(err, ErrNotFound))
+	var registryErr *Error
+	require.True(t, errors.As(err, &registryErr))
+	assert.Equal(t, 40403, registryErr.Code)
+	_, err = client.GetByID(ctx, 100)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = NewClient(server.URL).GetLatest(ctx, "topic-value")
+	require.True(t, errors.As(err, &registryErr))
+	assert.Equal(t, http.StatusUnauthorized, registryErr.StatusCode)
+}
+
+func TestFileRegistry(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "schemas.json")
+	registry, err := NewFileRegistry(path)
+	require.NoError(t, err)
+
+	_, err = registry.GetLatest(ctx, "a-value")
+	assert.Equal(t, ErrNotFound, err)
+
+	v1 := Schema{Schema: `{"type":"string"}`}
+	v2 := Schema{Schema: `{"type":"object"}`, SchemaType: SchemaTypeJSON}
+	id, err := registry.Register(ctx, "a-value", v1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+	id, err = registry.Register(ctx, "a-value", v2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, id)
+	// 相同的 schema 在其他 subject 下使用相同的 id
+	id, err = registry.Register(ctx, "b-value", v1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+	// AVRO 类型与空类型相同
+	id, err = registry.Register(ctx, "a-value", Schema{Schema: v1.Schema, SchemaType: SchemaTypeAvro})
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	// 重新加载文件
+	registry, err = NewFileRegistry(path)
+	require.NoError(t, err)
+	latest, err := registry.GetLatest(ctx, "a-value")
+	require.NoError(t, err)
+	assert.Equal(t, SchemaMetadata{Schema: v2, ID: 2, Subject: "a-value", Version: 2}, latest)
+	id, err = registry.Lookup(ctx, "b-value", v1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+	_, err = registry.Lookup(ctx, "b-value", v2)
+	assert.Equal(t, ErrNotFound, err)
+	schema, err := registry.GetByID(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, v2, schema)
+}
@@ -0,0 +1,17 @@
+package schemaregistry
+
+import (
+	"github.com/gotomicro/ego/core/elog"
+)
+
+// Component schema registry 组件
+type Component struct {
+	Registry
+	name   string
+	logger *elog.Component
+}
+
+// GetCompName ...
+func (cmp *Component) GetCompName() string {
+	return cmp.name
+}
@@ -0,0 +1,30 @@
+package schemaregistry
+
+import (
+	"time"
+)
+
+type config struct {
+	// URL schema registry 地址，如 http://localhost:8081
+	URL string `json:"url" toml:"url"`
+	// Username basic 认证用户名，Confluent Cloud 中为 API key
+	Username string `json:"username" toml:"username"`
+	// Password basic 认证密码，Confluent Cloud 中为 API secret
+	Password string `json:"password" toml:"password"`
+	// Timeout 请求超时时间，默认5s
+	Timeout time.Duration `json:"timeout" toml:"timeout"`
+	// LatestCacheTTL subject 最新版本的缓存时间，默认1m，schema 与 id 的对应关系永久缓存
+	LatestCacheTTL time.Duration `json:"latestCacheTTL" toml:"latestCacheTTL"`
+	// File 配置后使用本地 JSON 文件代替 schema registry，用于测试环境
+	File string `json:"file" toml:"file"`
+
+	registry Registry
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *config {
+	return &config{
+		Timeout:        5 * time.Second,
+		LatestCacheTTL: time.Minute,
+	}
+}
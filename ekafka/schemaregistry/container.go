@@ -0,0 +1,74 @@
+package schemaregistry
+
+import (
+	"net/http"
+
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/gotomicro/ego/core/elog"
+)
+
+const PackageName = "component.ekafka.schemaregistry"
+
+type Option func(c *Container)
+
+type Container struct {
+	name   string
+	config *config
+	logger *elog.Component
+}
+
+// DefaultContainer 返回默认Container
+func DefaultContainer() *Container {
+	return &Container{
+		config: DefaultConfig(),
+		logger: elog.EgoLogger.With(elog.FieldComponent(PackageName)),
+	}
+}
+
+// Load 载入配置，初始化Container
+func Load(key string) *Container {
+	c := DefaultContainer()
+	if err := econf.UnmarshalKey(key, &c.config); err != nil {
+		c.logger.Panic("parse config error", elog.FieldErr(err), elog.FieldKey(key))
+		return c
+	}
+
+	c.logger = c.logger.With(elog.FieldComponentName(key))
+	c.name = key
+	return c
+}
+
+// Build 构建Component，配置了 file 时使用 FileRegistry，否则使用 Client
+func (c *Container) Build(options ...Option) *Component {
+	for _, option := range options {
+		option(c)
+	}
+
+	registry := c.config.registry
+	switch {
+	case registry != nil:
+	case c.config.File != "":
+		fileRegistry, err := NewFileRegistry(c.config.File)
+		if err != nil {
+			c.logger.Panic("load schema file error", elog.FieldErr(err), elog.String("file", c.config.File))
+		}
+		registry = fileRegistry
+	case c.config.URL != "":
+		opts := []ClientOption{WithLatestCacheTTL(c.config.LatestCacheTTL)}
+		if c.config.Username != "" {
+			opts = append(opts, WithBasicAuth(c.config.Username, c.config.Password))
+		}
+		if c.config.Timeout > 0 {
+			opts = append(opts, WithHTTPClient(&http.Client{Timeout: c.config.Timeout}))
+		}
+		registry = NewClient(c.config.URL, opts...)
+	default:
+		c.logger.Panic("schema registry url or file is required")
+	}
+
+	return &Component{
+		Registry: registry,
+		name:     c.name,
+		logger:   c.logger,
+	}
+}
@@ -0,0 +1,152 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileRegistry 基于本地 JSON 文件的 Registry，用于单元测试以及没有 schema registry 的环境。
+// 文件内容为 SchemaMetadata 数组，可以预先写入 schema，注册的 schema 会写回文件
+type FileRegistry struct {
+	path string
+
+	mu      sync.RWMutex
+	schemas []SchemaMetadata
+}
+
+// NewFileRegistry loads the schemas in path, the file is created on the first registration if it does not exist.
+func NewFileRegistry(path string) (*FileRegistry, error) {
+	r := &FileRegistry{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &r.schemas); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register ...
+func (r *FileRegistry) Register(ctx context.Context, subject string, schema Schema) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if metadata, ok := r.find(subject, schema); ok {
+		return metadata.ID, nil
+	}
+	// 与 Confluent Schema Registry 一致，相同的 schema 在不同 subject 下使用同一个 id
+	id, version := 0, 1
+	for _, metadata := range r.schemas {
+		if metadata.ID > id {
+			id = metadata.ID
+		}
+		if metadata.Subject == subject && metadata.Version >= version {
+			version = metadata.Version + 1
+		}
+	}
+	id++
+	for _, metadata := range r.schemas {
+		if sameSchema(metadata.Schema, schema) {
+			id = metadata.ID
+			break
+		}
+	}
+	r.schemas = append(r.schemas, SchemaMetadata{Schema: schema, ID: id, Subject: subject, Version: version})
+	if err := r.save(); err != nil {
+		r.schemas = r.schemas[:len(r.schemas)-1]
+		return 0, err
+	}
+	return id, nil
+}
+
+// Lookup ...
+func (r *FileRegistry) Lookup(ctx context.Context, subject string, schema Schema) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if metadata, ok := r.find(subject, schema); ok {
+		return metadata.ID, nil
+	}
+	return 0, ErrNotFound
+}
+
+// GetByID ...
+func (r *FileRegistry) GetByID(ctx context.Context, id int) (Schema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, metadata := range r.schemas {
+		if metadata.ID == id {
+			return metadata.Schema, nil
+		}
+	}
+	return Schema{}, ErrNotFound
+}
+
+// GetLatest ...
+func (r *FileRegistry) GetLatest(ctx context.Context, subject string) (SchemaMetadata, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var (
+		latest SchemaMetadata
+		found  bool
+	)
+	for _, metadata := range r.schemas {
+		if metadata.Subject == subject && (!found || metadata.Version > latest.Version) {
+			latest, found = metadata, true
+		}
+	}
+	if !found {
+		return SchemaMetadata{}, ErrNotFound
+	}
+	return latest, nil
+}
+
+func (r *FileRegistry) find(subject string, schema Schema) (SchemaMetadata, bool) {
+	for _, metadata := range r.schemas {
+		if metadata.Subject == subject && sameSchema(metadata.Schema, schema) {
+			return metadata, true
+		}
+	}
+	return SchemaMetadata{}, false
+}
+
+// save writes the schemas to a temporary file, then renames it to path.
+func (r *FileRegistry) save() error {
+	data, err := json.MarshalIndent(r.schemas, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), r.path)
+}
+
+func sameSchema(a, b Schema) bool {
+	if a.Schema != b.Schema || a.Type() != b.Type() || len(a.References) != len(b.References) {
+		return false
+	}
+	for i := range a.References {
+		if a.References[i] != b.References[i] {
+			return false
+		}
+	}
+	return true
+}
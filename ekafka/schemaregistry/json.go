@@ -0,0 +1,85 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// JSONSchemaSerializer 将 v 编码为 JSON，写入前使用 JSON Schema 校验
+type JSONSchemaSerializer struct {
+	serde *serde
+}
+
+// NewJSONSchemaSerializer ...
+func NewJSONSchemaSerializer(registry Registry, opts ...SerdeOption) *JSONSchemaSerializer {
+	return &JSONSchemaSerializer{serde: newSerde(registry, SchemaTypeJSON, parseJSONSchema, opts)}
+}
+
+// Serialize ...
+func (s *JSONSchemaSerializer) Serialize(ctx context.Context, topic string, v interface{}) ([]byte, error) {
+	id, schema, err := s.serde.writerSchema(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateJSON(schema.(*jsonschema.Schema), payload); err != nil {
+		return nil, err
+	}
+	return append(appendHeader(make([]byte, 0, headerSize+len(payload)), id), payload...), nil
+}
+
+// JSONSchemaDeserializer 解码 JSON 消息，解码前使用写入时的 JSON Schema 校验
+type JSONSchemaDeserializer struct {
+	serde *serde
+}
+
+// NewJSONSchemaDeserializer ...
+func NewJSONSchemaDeserializer(registry Registry, opts ...SerdeOption) *JSONSchemaDeserializer {
+	return &JSONSchemaDeserializer{serde: newSerde(registry, SchemaTypeJSON, parseJSONSchema, opts)}
+}
+
+// Deserialize ...
+func (d *JSONSchemaDeserializer) Deserialize(ctx context.Context, topic string, data []byte, v interface{}) error {
+	id, payload, err := readHeader(data)
+	if err != nil {
+		return err
+	}
+	schema, err := d.serde.schema(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := validateJSON(schema.(*jsonschema.Schema), payload); err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, v)
+}
+
+func parseJSONSchema(schema Schema) (interface{}, error) {
+	const url = "schema.json"
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, strings.NewReader(schema.Schema)); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(url)
+}
+
+func validateJSON(schema *jsonschema.Schema, payload []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return err
+	}
+	if err := schema.Validate(doc); err != nil {
+		return fmt.Errorf("schemaregistry: validate json fail, %w", err)
+	}
+	return nil
+}
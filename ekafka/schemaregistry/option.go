@@ -0,0 +1,8 @@
+package schemaregistry
+
+// WithRegistry 注入 Registry，如自定义 http.Client 的 Client，或 NewFileRegistry 创建的 FileRegistry
+func WithRegistry(registry Registry) Option {
+	return func(c *Container) {
+		c.config.registry = registry
+	}
+}
@@ -0,0 +1,87 @@
+package schemaregistry
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ProtobufSerializer 编码 proto.Message，wire format 中包含消息在 schema 中的 message indexes。
+// 需要通过 WithSchema 设置 .proto 文件内容，或者预先将 schema 注册到 subject 下
+type ProtobufSerializer struct {
+	serde *serde
+}
+
+// NewProtobufSerializer ...
+func NewProtobufSerializer(registry Registry, opts ...SerdeOption) *ProtobufSerializer {
+	return &ProtobufSerializer{serde: newSerde(registry, SchemaTypeProtobuf, parseProtobufSchema, opts)}
+}
+
+// Serialize ...
+func (s *ProtobufSerializer) Serialize(ctx context.Context, topic string, v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("schemaregistry: %T is not a proto.Message", v)
+	}
+	id, _, err := s.serde.writerSchema(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	b := appendHeader(make([]byte, 0, headerSize+1+len(payload)), id)
+	b = appendMessageIndexes(b, messageIndexes(msg.ProtoReflect().Descriptor()))
+	return append(b, payload...), nil
+}
+
+// ProtobufDeserializer 将消息解码到 proto.Message
+type ProtobufDeserializer struct {
+	serde *serde
+}
+
+// NewProtobufDeserializer ...
+func NewProtobufDeserializer(registry Registry, opts ...SerdeOption) *ProtobufDeserializer {
+	return &ProtobufDeserializer{serde: newSerde(registry, SchemaTypeProtobuf, parseProtobufSchema, opts)}
+}
+
+// Deserialize ...
+func (d *ProtobufDeserializer) Deserialize(ctx context.Context, topic string, data []byte, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("schemaregistry: %T is not a proto.Message", v)
+	}
+	id, payload, err := readHeader(data)
+	if err != nil {
+		return err
+	}
+	// 只校验 schema 存在并且是 PROTOBUF 类型，消息类型由 v 决定
+	if _, err := d.serde.schema(ctx, id); err != nil {
+		return err
+	}
+	_, payload, err = readMessageIndexes(payload)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(payload, msg)
+}
+
+func parseProtobufSchema(schema Schema) (interface{}, error) {
+	return schema, nil
+}
+
+// messageIndexes returns the path of the message in its file, e.g. [1, 0] is the first nested message of the second message.
+func messageIndexes(desc protoreflect.MessageDescriptor) []int {
+	var indexes []int
+	for {
+		indexes = append([]int{desc.Index()}, indexes...)
+		parent, ok := desc.Parent().(protoreflect.MessageDescriptor)
+		if !ok {
+			return indexes
+		}
+		desc = parent
+	}
+}
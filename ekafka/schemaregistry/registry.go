@@ -0,0 +1,75 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Schema 类型，与 Confluent Schema Registry 的 schemaType 一致，为空时表示 AVRO
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeProtobuf = "PROTOBUF"
+	SchemaTypeJSON     = "JSON"
+)
+
+// ErrNotFound subject、version 或 schema 不存在
+var ErrNotFound = errors.New("schemaregistry: not found")
+
+// Reference 引用的其他 subject 中的 schema
+type Reference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// Schema 注册的 schema
+type Schema struct {
+	Schema     string      `json:"schema"`
+	SchemaType string      `json:"schemaType,omitempty"`
+	References []Reference `json:"references,omitempty"`
+}
+
+// Type returns the type of the schema, AVRO if not set.
+func (s Schema) Type() string {
+	if s.SchemaType == "" {
+		return SchemaTypeAvro
+	}
+	return s.SchemaType
+}
+
+// SchemaMetadata 某个 subject 下某个版本的 schema
+type SchemaMetadata struct {
+	Schema
+	ID      int    `json:"id"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// Registry schema registry 客户端，实现需要是并发安全的
+type Registry interface {
+	// Register registers the schema under subject, the id of the schema is returned if it is registered already.
+	Register(ctx context.Context, subject string, schema Schema) (int, error)
+	// Lookup returns the id of the schema registered under subject, ErrNotFound if it is not registered.
+	Lookup(ctx context.Context, subject string, schema Schema) (int, error)
+	// GetByID returns the schema of id.
+	GetByID(ctx context.Context, id int) (Schema, error)
+	// GetLatest returns the latest version of subject.
+	GetLatest(ctx context.Context, subject string) (SchemaMetadata, error)
+}
+
+// Error schema registry 返回的错误
+type Error struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("schemaregistry: status %d, error code %d, %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Is makes errors.Is(err, ErrNotFound) true for 404 responses.
+func (e *Error) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == 404
+}
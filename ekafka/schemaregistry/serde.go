@@ -0,0 +1,157 @@
+package schemaregistry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Serializer 将 v 编码为 schema registry 的 wire format
+type Serializer interface {
+	Serialize(ctx context.Context, topic string, v interface{}) ([]byte, error)
+}
+
+// Deserializer 将 schema registry 的 wire format 解码到 v
+type Deserializer interface {
+	Deserialize(ctx context.Context, topic string, data []byte, v interface{}) error
+}
+
+// SubjectNameStrategy 返回 topic 的 key 或 value 使用的 subject
+type SubjectNameStrategy func(topic string, isKey bool) string
+
+// TopicNameStrategy 默认的 subject 命名规则，<topic>-key 或 <topic>-value
+func TopicNameStrategy(topic string, isKey bool) string {
+	if isKey {
+		return topic + "-key"
+	}
+	return topic + "-value"
+}
+
+// SerdeOption 设置 Serializer 和 Deserializer
+type SerdeOption func(c *serdeConfig)
+
+type serdeConfig struct {
+	isKey               bool
+	subjectNameStrategy SubjectNameStrategy
+	schema              string
+	references          []Reference
+	autoRegister        bool
+}
+
+// WithKey 编码、解码消息的 key，subject 使用 <topic>-key
+func WithKey() SerdeOption {
+	return func(c *serdeConfig) {
+		c.isKey = true
+	}
+}
+
+// WithSubjectNameStrategy 设置 subject 命名规则，默认为 TopicNameStrategy
+func WithSubjectNameStrategy(strategy SubjectNameStrategy) SerdeOption {
+	return func(c *serdeConfig) {
+		c.subjectNameStrategy = strategy
+	}
+}
+
+// WithSchema 设置 Serializer 写入使用的 schema，不设置时使用 subject 的最新版本
+func WithSchema(schema string, references ...Reference) SerdeOption {
+	return func(c *serdeConfig) {
+		c.schema = schema
+		c.references = references
+	}
+}
+
+// WithAutoRegister 设置是否自动注册 WithSchema 设置的 schema，默认为 true。
+// 设置为 false 时，schema 需要已经注册到 subject 下
+func WithAutoRegister(autoRegister bool) SerdeOption {
+	return func(c *serdeConfig) {
+		c.autoRegister = autoRegister
+	}
+}
+
+func newSerdeConfig(opts []SerdeOption) *serdeConfig {
+	c := &serdeConfig{
+		subjectNameStrategy: TopicNameStrategy,
+		autoRegister:        true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// serde resolves the schemas of serializers and deserializers, and caches the schemas parsed by parse.
+type serde struct {
+	registry   Registry
+	config     *serdeConfig
+	schemaType string
+	parse      func(schema Schema) (interface{}, error)
+
+	mu     sync.RWMutex
+	parsed map[int]interface{}
+}
+
+func newSerde(registry Registry, schemaType string, parse func(schema Schema) (interface{}, error), opts []SerdeOption) *serde {
+	return &serde{
+		registry:   registry,
+		config:     newSerdeConfig(opts),
+		schemaType: schemaType,
+		parse:      parse,
+		parsed:     make(map[int]interface{}),
+	}
+}
+
+// writerSchema returns the id and the parsed schema used to serialize the messages of topic.
+func (s *serde) writerSchema(ctx context.Context, topic string) (int, interface{}, error) {
+	subject := s.config.subjectNameStrategy(topic, s.config.isKey)
+	var (
+		id  int
+		err error
+	)
+	if s.config.schema == "" {
+		var metadata SchemaMetadata
+		if metadata, err = s.registry.GetLatest(ctx, subject); err != nil {
+			return 0, nil, err
+		}
+		id = metadata.ID
+	} else {
+		schema := Schema{Schema: s.config.schema, SchemaType: s.schemaType, References: s.config.references}
+		// AVRO 是默认类型，注册时不传 schemaType，与 Confluent 客户端保持一致
+		if schema.SchemaType == SchemaTypeAvro {
+			schema.SchemaType = ""
+		}
+		if s.config.autoRegister {
+			id, err = s.registry.Register(ctx, subject, schema)
+		} else {
+			id, err = s.registry.Lookup(ctx, subject, schema)
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	parsed, err := s.schema(ctx, id)
+	return id, parsed, err
+}
+
+// schema returns the parsed schema of id.
+func (s *serde) schema(ctx context.Context, id int) (interface{}, error) {
+	s.mu.RLock()
+	parsed, ok := s.parsed[id]
+	s.mu.RUnlock()
+	if ok {
+		return parsed, nil
+	}
+	schema, err := s.registry.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if schema.Type() != s.schemaType {
+		return nil, fmt.Errorf("schemaregistry: schema %d is %s, not %s", id, schema.Type(), s.schemaType)
+	}
+	if parsed, err = s.parse(schema); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.parsed[id] = parsed
+	s.mu.Unlock()
+	return parsed, nil
+}
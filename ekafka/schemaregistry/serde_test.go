@@ -0,0 +1,169 @@
+package schemaregistry
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const userAvroSchema = `{
+	"type": "record",
+	"name": "User",
+	"namespace": "ekafka.test",
+	"fields": [
+		{"name": "name", "type": "string"},
+		{"name": "age", "type": "int"},
+		{"name": "email", "type": ["null", "string"], "default": null}
+	]
+}`
+
+type avroUser struct {
+	Name  string  `avro:"name"`
+	Age   int     `avro:"age"`
+	Email *string `avro:"email"`
+}
+
+func newTestRegistry(t *testing.T) *FileRegistry {
+	registry, err := NewFileRegistry(filepath.Join(t.TempDir(), "schemas.json"))
+	require.NoError(t, err)
+	return registry
+}
+
+func TestMessageIndexes(t *testing.T) {
+	for _, indexes := range [][]int{{0}, {1}, {2, 0}, {0, 3, 1}} {
+		b := appendMessageIndexes([]byte{}, indexes)
+		got, rest, err := readMessageIndexes(append(b, 0xff))
+		require.NoError(t, err)
+		assert.Equal(t, indexes, got)
+		assert.Equal(t, []byte{0xff}, rest)
+	}
+	// [0] 编码为单个 0，[2, 0] 为 zigzag varint 的 count、indexes
+	assert.Equal(t, []byte{0}, appendMessageIndexes(nil, []int{0}))
+	assert.Equal(t, []byte{4, 4, 0}, appendMessageIndexes(nil, []int{2, 0}))
+
+	assert.Equal(t, []int{0}, messageIndexes((&timestamppb.Timestamp{}).ProtoReflect().Descriptor()))
+	// descriptor.proto 中第 3 个 message 的第 1 个嵌套 message
+	assert.Equal(t, []int{2, 0}, messageIndexes((&descriptorpb.DescriptorProto_ExtensionRange{}).ProtoReflect().Descriptor()))
+
+	_, _, err := readHeader([]byte{1, 0, 0, 0, 1})
+	assert.Equal(t, ErrInvalidWireFormat, err)
+	_, _, err = readHeader([]byte{0, 0})
+	assert.Equal(t, ErrInvalidWireFormat, err)
+}
+
+func TestAvroSerde(t *testing.T) {
+	ctx := context.Background()
+	registry := newTestRegistry(t)
+	serializer := NewAvroSerializer(registry, WithSchema(userAvroSchema))
+	email := "a@example.com"
+	data, err := serializer.Serialize(ctx, "users", &avroUser{Name: "a", Age: 18, Email: &email})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 0, 1}, data[:headerSize])
+
+	latest, err := registry.GetLatest(ctx, "users-value")
+	require.NoError(t, err)
+	assert.Equal(t, "", latest.SchemaType)
+
+	deserializer := NewAvroDeserializer(registry)
+	var user avroUser
+	require.NoError(t, deserializer.Deserialize(ctx, "users", data, &user))
+	assert.Equal(t, "a", user.Name)
+	assert.Equal(t, 18, user.Age)
+	assert.Equal(t, email, *user.Email)
+
+	var m map[string]interface{}
+	require.NoError(t, deserializer.Deserialize(ctx, "users", data, &m))
+	assert.Equal(t, "a", m["name"])
+
+	// 未设置 schema 时使用 subject 的最新版本
+	data, err = NewAvroSerializer(registry).Serialize(ctx, "users", map[string]interface{}{"name": "b", "age": 20, "email": nil})
+	require.NoError(t, err)
+	require.NoError(t, deserializer.Deserialize(ctx, "users", data, &user))
+	assert.Equal(t, "b", user.Name)
+	assert.Nil(t, user.Email)
+
+	// 关闭自动注册时 schema 需要已注册
+	_, err = NewAvroSerializer(registry, WithSchema(`{"type":"string"}`), WithAutoRegister(false)).Serialize(ctx, "users", "c")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = NewAvroSerializer(registry).Serialize(ctx, "orders", &user)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// key 使用 <topic>-key
+	_, err = NewAvroSerializer(registry, WithKey(), WithSchema(`{"type":"string"}`)).Serialize(ctx, "users", "key")
+	require.NoError(t, err)
+	_, err = registry.GetLatest(ctx, "users-key")
+	assert.NoError(t, err)
+}
+
+func TestJSONSchemaSerde(t *testing.T) {
+	ctx := context.Background()
+	registry := newTestRegistry(t)
+	schema := `{
+		"type": "object",
+		"properties": {"name": {"type": "string"}, "age": {"type": "integer", "minimum": 0}},
+		"required": ["name"]
+	}`
+	type jsonUser struct {
+		Name string `json:"name,omitempty"`
+		Age  int    `json:"age"`
+	}
+	serializer := NewJSONSchemaSerializer(registry, WithSchema(schema), WithSubjectNameStrategy(func(topic string, isKey bool) string {
+		return "user"
+	}))
+	data, err := serializer.Serialize(ctx, "users", jsonUser{Name: "a", Age: 18})
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"a","age":18}`, string(data[headerSize:]))
+	latest, err := registry.GetLatest(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, SchemaTypeJSON, latest.SchemaType)
+
+	var user jsonUser
+	require.NoError(t, NewJSONSchemaDeserializer(registry).Deserialize(ctx, "users", data, &user))
+	assert.Equal(t, jsonUser{Name: "a", Age: 18}, user)
+
+	_, err = serializer.Serialize(ctx, "users", jsonUser{Age: 18})
+	assert.Error(t, err)
+	_, err = serializer.Serialize(ctx, "users", jsonUser{Name: "a", Age: -1})
+	assert.Error(t, err)
+
+	// schema 类型不匹配
+	avroData, err := NewAvroSerializer(registry, WithSchema(`{"type":"string"}`)).Serialize(ctx, "users", "a")
+	require.NoError(t, err)
+	assert.Error(t, NewJSONSchemaDeserializer(registry).Deserialize(ctx, "users", avroData, &user))
+}
+
+func TestProtobufSerde(t *testing.T) {
+	ctx := context.Background()
+	registry := newTestRegistry(t)
+	schema := `syntax = "proto3";
+package google.protobuf;
+
+message Timestamp {
+  int64 seconds = 1;
+  int32 nanos = 2;
+}`
+	ts := &timestamppb.Timestamp{Seconds: 100, Nanos: 1}
+	data, err := NewProtobufSerializer(registry, WithSchema(schema)).Serialize(ctx, "events", ts)
+	require.NoError(t, err)
+	payload, err := proto.Marshal(ts)
+	require.NoError(t, err)
+	assert.Equal(t, append([]byte{0, 0, 0, 0, 1, 0}, payload...), data)
+
+	latest, err := registry.GetLatest(ctx, "events-value")
+	require.NoError(t, err)
+	assert.Equal(t, SchemaTypeProtobuf, latest.SchemaType)
+
+	var got timestamppb.Timestamp
+	require.NoError(t, NewProtobufDeserializer(registry).Deserialize(ctx, "events", data, &got))
+	assert.True(t, proto.Equal(ts, &got))
+
+	_, err = NewProtobufSerializer(registry).Serialize(ctx, "events", "not a message")
+	assert.Error(t, err)
+	assert.Error(t, NewProtobufDeserializer(registry).Deserialize(ctx, "events", data, &got.Seconds))
+}
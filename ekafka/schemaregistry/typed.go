@@ -0,0 +1,84 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gotomicro/ego-component/ekafka"
+	"github.com/segmentio/kafka-go"
+)
+
+// Message 待编码的消息，Value 由 Producer 的 Serializer 编码
+type Message struct {
+	// Topic 为空时使用 Producer 的 topic
+	Topic   string
+	Key     []byte
+	Value   interface{}
+	Headers []kafka.Header
+}
+
+// Producer 使用 Serializer 编码消息的 Producer
+type Producer struct {
+	producer   *ekafka.Producer
+	serializer Serializer
+}
+
+// NewProducer ...
+func NewProducer(producer *ekafka.Producer, valueSerializer Serializer) *Producer {
+	return &Producer{producer: producer, serializer: valueSerializer}
+}
+
+// WriteMessages encodes the values of the messages, and writes them by the producer.
+func (p *Producer) WriteMessages(ctx context.Context, msgs ...Message) error {
+	kafkaMsgs := make([]*ekafka.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		topic := msg.Topic
+		if topic == "" {
+			topic = p.producer.Topic()
+		}
+		if topic == "" {
+			return errors.New("schemaregistry: topic of message is required")
+		}
+		value, err := p.serializer.Serialize(ctx, topic, msg.Value)
+		if err != nil {
+			return err
+		}
+		kafkaMsgs = append(kafkaMsgs, &ekafka.Message{Topic: msg.Topic, Key: msg.Key, Value: value, Headers: msg.Headers})
+	}
+	return p.producer.WriteMessages(ctx, kafkaMsgs...)
+}
+
+// Consumer 使用 Deserializer 解码消息的 Consumer
+type Consumer struct {
+	consumer     *ekafka.Consumer
+	deserializer Deserializer
+}
+
+// NewConsumer ...
+func NewConsumer(consumer *ekafka.Consumer, valueDeserializer Deserializer) *Consumer {
+	return &Consumer{consumer: consumer, deserializer: valueDeserializer}
+}
+
+// ReadMessage reads a message and decodes its value into v. The message is returned with the decode error,
+// it is committed already if the consumer is in a group.
+func (c *Consumer) ReadMessage(ctx context.Context, v interface{}) (ekafka.Message, context.Context, error) {
+	msg, ctxOutput, err := c.consumer.ReadMessage(ctx)
+	if err != nil {
+		return msg, ctxOutput, err
+	}
+	return msg, ctxOutput, c.deserializer.Deserialize(ctxOutput, msg.Topic, msg.Value, v)
+}
+
+// FetchMessage fetches a message and decodes its value into v, the message should be committed by CommitMessages.
+func (c *Consumer) FetchMessage(ctx context.Context, v interface{}) (ekafka.Message, context.Context, error) {
+	msg, ctxOutput, err := c.consumer.FetchMessage(ctx)
+	if err != nil {
+		return msg, ctxOutput, err
+	}
+	return msg, ctxOutput, c.deserializer.Deserialize(ctxOutput, msg.Topic, msg.Value, v)
+}
+
+// CommitMessages ...
+func (c *Consumer) CommitMessages(ctx context.Context, msgs ...*ekafka.Message) error {
+	return c.consumer.CommitMessages(ctx, msgs...)
+}
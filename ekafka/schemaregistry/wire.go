@@ -0,0 +1,69 @@
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// magicByte wire format 的第一个字节
+	magicByte = 0
+	// headerSize magic byte 以及 4 字节的 schema id
+	headerSize = 5
+)
+
+// ErrInvalidWireFormat 数据不是 schema registry 的 wire format
+var ErrInvalidWireFormat = errors.New("schemaregistry: invalid wire format")
+
+// appendHeader appends the magic byte and the big endian schema id.
+func appendHeader(b []byte, id int) []byte {
+	var header [headerSize]byte
+	header[0] = magicByte
+	binary.BigEndian.PutUint32(header[1:], uint32(id))
+	return append(b, header[:]...)
+}
+
+// readHeader returns the schema id and the payload after the header.
+func readHeader(data []byte) (int, []byte, error) {
+	if len(data) < headerSize || data[0] != magicByte {
+		return 0, nil, ErrInvalidWireFormat
+	}
+	return int(binary.BigEndian.Uint32(data[1:headerSize])), data[headerSize:], nil
+}
+
+// appendMessageIndexes appends the indexes of the protobuf message in the schema, the path from the top level
+// message to the nested one. [0], the first top level message, is encoded as a single 0.
+func appendMessageIndexes(b []byte, indexes []int) []byte {
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return append(b, 0)
+	}
+	var buf [binary.MaxVarintLen64]byte
+	b = append(b, buf[:binary.PutVarint(buf[:], int64(len(indexes)))]...)
+	for _, index := range indexes {
+		b = append(b, buf[:binary.PutVarint(buf[:], int64(index))]...)
+	}
+	return b
+}
+
+// readMessageIndexes returns the message indexes and the protobuf payload after them.
+func readMessageIndexes(data []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 || count < 0 || count > int64(len(data)) {
+		return nil, nil, fmt.Errorf("%w, bad message indexes", ErrInvalidWireFormat)
+	}
+	data = data[n:]
+	if count == 0 {
+		return []int{0}, data, nil
+	}
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, n := binary.Varint(data)
+		if n <= 0 || index < 0 {
+			return nil, nil, fmt.Errorf("%w, bad message indexes", ErrInvalidWireFormat)
+		}
+		indexes[i] = int(index)
+		data = data[n:]
+	}
+	return indexes, data, nil
+}
@@ -0,0 +1,257 @@
+package ekafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Consumer 的类型
+const (
+	ConsumerKindConsumer      = "consumer"
+	ConsumerKindConsumerGroup = "consumerGroup"
+)
+
+// PartitionStats 消费者在分区上的消费进度
+type PartitionStats struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	// CommittedOffset 下一条要消费的 offset，消费组没有提交过 offset 时为 -1
+	CommittedOffset int64 `json:"committedOffset"`
+	// HighWatermark 分区的 high watermark，配置了 readCommitted 时为 last stable offset
+	HighWatermark int64 `json:"highWatermark"`
+	// Lag HighWatermark 与 CommittedOffset 的差值，没有提交过 offset 时为分区中保留的消息数量
+	Lag int64 `json:"lag"`
+	// Assigned 分区是否分配给当前实例，Consumer 使用 groupID 消费时无法获取分配情况，始终为 false
+	Assigned bool `json:"assigned"`
+}
+
+// GroupMemberStats 消费组成员以及分配到的分区
+type GroupMemberStats struct {
+	MemberID    string           `json:"memberId"`
+	ClientID    string           `json:"clientId"`
+	ClientHost  string           `json:"clientHost"`
+	Assignments map[string][]int `json:"assignments"`
+}
+
+// ConsumerStats Consumer 或 ConsumerGroup 的消费情况
+type ConsumerStats struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	GroupID string `json:"groupId"`
+	Topic   string `json:"topic"`
+	// GroupState broker 中消费组的状态，如 Stable、PreparingRebalance、CompletingRebalance、Empty、Dead
+	GroupState string `json:"groupState"`
+	// Members broker 中消费组的成员
+	Members []GroupMemberStats `json:"members"`
+	// Status 当前实例在消费组中的状态，只有 ConsumerGroup 有
+	Status     *ConsumerGroupStatus `json:"status,omitempty"`
+	Partitions []PartitionStats     `json:"partitions"`
+	Error      string               `json:"error,omitempty"`
+}
+
+// ConsumerStats returns the lag and the assignments of the consumers and consumer groups created by the component.
+func (cmp *Component) ConsumerStats(ctx context.Context) []ConsumerStats {
+	var stats []ConsumerStats
+
+	cmp.consumerMu.RLock()
+	consumers := make(map[string]*Consumer, len(cmp.consumers))
+	for name, consumer := range cmp.consumers {
+		consumers[name] = consumer
+	}
+	cmp.consumerMu.RUnlock()
+	for name, consumer := range consumers {
+		s := ConsumerStats{
+			Name:    name,
+			Kind:    ConsumerKindConsumer,
+			GroupID: consumer.Config.GroupID,
+			Topic:   consumer.Config.Topic,
+		}
+		var err error
+		if consumer.Config.GroupID != "" {
+			err = cmp.collectGroupStats(ctx, &s, consumer.Config.ReadCommitted, nil)
+		} else {
+			err = cmp.collectPartitionStats(ctx, &s, consumer)
+		}
+		if err != nil {
+			s.Error = err.Error()
+		}
+		stats = append(stats, s)
+	}
+
+	cmp.consumerGroupMu.RLock()
+	consumerGroups := make(map[string]*ConsumerGroup, len(cmp.consumerGroups))
+	for name, consumerGroup := range cmp.consumerGroups {
+		consumerGroups[name] = consumerGroup
+	}
+	cmp.consumerGroupMu.RUnlock()
+	for name, consumerGroup := range consumerGroups {
+		status := consumerGroup.Status()
+		s := ConsumerStats{
+			Name:    name,
+			Kind:    ConsumerKindConsumerGroup,
+			GroupID: consumerGroup.options.GroupID,
+			Topic:   consumerGroup.options.Topic,
+			Status:  &status,
+		}
+		if err := cmp.collectGroupStats(ctx, &s, consumerGroup.options.Reader.ReadCommitted, status.Assignments); err != nil {
+			s.Error = err.Error()
+		}
+		stats = append(stats, s)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Kind != stats[j].Kind {
+			return stats[i].Kind < stats[j].Kind
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// collectGroupStats fills the group state, members, and the committed offsets of the group.
+func (cmp *Component) collectGroupStats(ctx context.Context, s *ConsumerStats, readCommitted bool, assignments []TopicPartition) error {
+	client := cmp.Client().cc
+	partitions, err := cmp.topicPartitions(ctx, s.Topic)
+	if err != nil {
+		return err
+	}
+
+	groups, err := client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{s.GroupID}})
+	if err != nil {
+		return err
+	}
+	for _, group := range groups.Groups {
+		if group.Error != nil {
+			return group.Error
+		}
+		s.GroupState = group.GroupState
+		s.Members = append(s.Members, groupMembers(group)...)
+	}
+
+	offsets, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: s.GroupID, Topics: map[string][]int{s.Topic: partitions}})
+	if err != nil {
+		return err
+	}
+	if offsets.Error != nil {
+		return offsets.Error
+	}
+	committed := make(map[int]int64, len(partitions))
+	for _, partition := range offsets.Topics[s.Topic] {
+		if partition.Error != nil {
+			return partition.Error
+		}
+		committed[partition.Partition] = partition.CommittedOffset
+	}
+
+	assigned := make(map[int]bool, len(assignments))
+	for _, assignment := range assignments {
+		if assignment.Topic == s.Topic {
+			assigned[assignment.Partition] = true
+		}
+	}
+	return cmp.fillPartitionStats(ctx, s, partitions, readCommitted, func(partition int) (int64, bool) {
+		offset, ok := committed[partition]
+		if !ok {
+			offset = -1
+		}
+		return offset, assigned[partition]
+	})
+}
+
+// collectPartitionStats fills the stats of a consumer of a single partition, the offset of the reader is used as committed offset.
+func (cmp *Component) collectPartitionStats(ctx context.Context, s *ConsumerStats, consumer *Consumer) error {
+	return cmp.fillPartitionStats(ctx, s, []int{consumer.Config.Partition}, consumer.Config.ReadCommitted, func(partition int) (int64, bool) {
+		return consumer.Offset(), true
+	})
+}
+
+func (cmp *Component) fillPartitionStats(ctx context.Context, s *ConsumerStats, partitions []int, readCommitted bool, offsetOf func(partition int) (int64, bool)) error {
+	requests := make([]kafka.OffsetRequest, 0, 2*len(partitions))
+	for _, partition := range partitions {
+		requests = append(requests, kafka.FirstOffsetOf(partition), kafka.LastOffsetOf(partition))
+	}
+	req := &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{s.Topic: requests}}
+	if readCommitted {
+		req.IsolationLevel = kafka.ReadCommitted
+	}
+	res, err := cmp.Client().cc.ListOffsets(ctx, req)
+	if err != nil {
+		return err
+	}
+	watermarks := make(map[int]kafka.PartitionOffsets, len(partitions))
+	for _, partition := range res.Topics[s.Topic] {
+		if partition.Error != nil {
+			return partition.Error
+		}
+		watermarks[partition.Partition] = partition
+	}
+	for _, partition := range partitions {
+		watermark, ok := watermarks[partition]
+		if !ok {
+			return fmt.Errorf("offsets of %s[%d] not found", s.Topic, partition)
+		}
+		committed, assigned := offsetOf(partition)
+		s.Partitions = append(s.Partitions, PartitionStats{
+			Topic:           s.Topic,
+			Partition:       partition,
+			CommittedOffset: committed,
+			HighWatermark:   watermark.LastOffset,
+			Lag:             partitionLag(committed, watermark.FirstOffset, watermark.LastOffset),
+			Assigned:        assigned,
+		})
+	}
+	return nil
+}
+
+func groupMembers(group kafka.DescribeGroupsResponseGroup) []GroupMemberStats {
+	members := make([]GroupMemberStats, 0, len(group.Members))
+	for _, member := range group.Members {
+		memberStats := GroupMemberStats{
+			MemberID:    member.MemberID,
+			ClientID:    member.ClientID,
+			ClientHost:  member.ClientHost,
+			Assignments: make(map[string][]int),
+		}
+		for _, topic := range member.MemberAssignments.Topics {
+			memberStats.Assignments[topic.Topic] = topic.Partitions
+		}
+		members = append(members, memberStats)
+	}
+	return members
+}
+
+func (cmp *Component) topicPartitions(ctx context.Context, topic string) ([]int, error) {
+	res, err := cmp.Client().cc.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range res.Topics {
+		if t.Name != topic {
+			continue
+		}
+		if t.Error != nil {
+			return nil, t.Error
+		}
+		partitions := make([]int, 0, len(t.Partitions))
+		for _, partition := range t.Partitions {
+			partitions = append(partitions, partition.ID)
+		}
+		sort.Ints(partitions)
+		return partitions, nil
+	}
+	return nil, kafka.UnknownTopicOrPartition
+}
+
+// partitionLag returns the number of messages not consumed, the retained messages are not consumed if offset is unknown.
+func partitionLag(committed, first, last int64) int64 {
+	if committed < 0 || committed < first {
+		committed = first
+	}
+	if lag := last - committed; lag > 0 {
+		return lag
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package ekafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartitionLag(t *testing.T) {
+	assert.Equal(t, int64(5), partitionLag(15, 0, 20))
+	assert.Equal(t, int64(0), partitionLag(20, 0, 20))
+	// 没有提交过 offset 时为保留的消息数量
+	assert.Equal(t, int64(10), partitionLag(-1, 10, 20))
+	// 已提交的 offset 被清理
+	assert.Equal(t, int64(10), partitionLag(5, 10, 20))
+}
+
+func TestConsumerGroupStatus(t *testing.T) {
+	cg := &ConsumerGroup{state: ConsumerGroupStateJoining}
+	status := cg.Status()
+	assert.Equal(t, ConsumerGroupStateJoining, status.State)
+	assert.Empty(t, status.Assignments)
+
+	cg.currentGen = &kafka.Generation{
+		ID:       3,
+		MemberID: "member-1",
+		Assignments: map[string][]kafka.PartitionAssignment{
+			"topic-a": {{ID: 2, Offset: 10}, {ID: 0, Offset: 5}},
+		},
+	}
+	cg.state = ConsumerGroupStateStable
+	status = cg.Status()
+	assert.Equal(t, ConsumerGroupStatus{
+		State:        ConsumerGroupStateStable,
+		GenerationID: 3,
+		MemberID:     "member-1",
+		Assignments:  []TopicPartition{{Topic: "topic-a", Partition: 0, Offset: 5}, {Topic: "topic-a", Partition: 2, Offset: 10}},
+	}, status)
+
+	// rebalance 时分区已被回收
+	cg.setState(ConsumerGroupStateRebalancing)
+	status = cg.Status()
+	assert.Equal(t, ConsumerGroupStateRebalancing, status.State)
+	assert.Empty(t, status.Assignments)
+}
+
+func TestLagCollectorDeletesStaleLabels(t *testing.T) {
+	cmp := &Component{
+		compName:       "kafka.test",
+		logger:         elog.DefaultLogger,
+		consumers:      make(map[string]*Consumer),
+		consumerGroups: make(map[string]*ConsumerGroup),
+	}
+	collector := newLagCollector(cmp, 0)
+	labels := []string{"kafka.test", "cg1", "group-1", "topic-a", "0"}
+	consumerLagGauge.Set(10, labels...)
+	consumerAssignmentGauge.Set(1, labels...)
+	collector.published["stale"] = labels
+	assert.Equal(t, float64(10), testutil.ToFloat64(consumerLagGauge.WithLabelValues(labels...)))
+
+	// 消费者不存在后删除上报过的 label
+	collector.collect(context.Background())
+	assert.Equal(t, 0, testutil.CollectAndCount(consumerLagGauge))
+	assert.Equal(t, 0, testutil.CollectAndCount(consumerAssignmentGauge))
+	assert.Empty(t, collector.published)
+}
+
+func TestComponentClose(t *testing.T) {
+	c := DefaultContainer()
+	c.name = "kafka.close"
+	c.config.EnableLagMetric = true
+	c.config.LagMetricInterval = time.Millisecond
+	cmp := c.Build(WithBrokers("127.0.0.1:1"), WithDebug(false))
+	_, ok := instances.Load("kafka.close")
+	assert.True(t, ok)
+
+	labels := []string{"kafka.close", "cg1", "group-1", "topic-a", "0"}
+	consumerLagGauge.Set(10, labels...)
+	cmp.lagCollector.mu.Lock()
+	cmp.lagCollector.published["stale"] = labels
+	cmp.lagCollector.mu.Unlock()
+
+	assert.NoError(t, cmp.Close())
+	assert.NoError(t, cmp.Close())
+	_, ok = instances.Load("kafka.close")
+	assert.False(t, ok)
+	// 上报的 goroutine 已退出，上报过的 label 被删除
+	select {
+	case <-cmp.lagCollector.done:
+	default:
+		t.Fatal("lag collector not stopped")
+	}
+	assert.Equal(t, 0, testutil.CollectAndCount(consumerLagGauge))
+}
@@ -0,0 +1,58 @@
+package ekafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+// tlsEnabled 开启了 EnableTLS，或配置了 CA、客户端证书时使用 TLS 连接
+func (c *config) tlsEnabled() bool {
+	return c.EnableTLS || c.TLSCaFile != "" || c.TLSCertFile != ""
+}
+
+// newTLSConfig builds the tls config of brokers, nil if TLS is not enabled.
+func (c *config) newTLSConfig() (*tls.Config, error) {
+	if !c.tlsEnabled() {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         c.TLSServerName,
+		InsecureSkipVerify: c.TLSInsecureSkipVerify,
+	}
+	if c.TLSCaFile != "" {
+		caBytes, err := ioutil.ReadFile(c.TLSCaFile)
+		if err != nil {
+			return nil, fmt.Errorf("read TLSCaFile fail, %w", err)
+		}
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("parse TLSCaFile fail, no certificate found in %s", c.TLSCaFile)
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+	if c.TLSCertFile != "" || c.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load TLSCertFile or TLSKeyFile fail, %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// newDialer returns the dialer of readers, nil to use the default dialer if neither SASL nor TLS is enabled.
+func newDialer(mechanism sasl.Mechanism, tlsConfig *tls.Config) *kafka.Dialer {
+	if mechanism == nil && tlsConfig == nil {
+		return nil
+	}
+	return &kafka.Dialer{
+		DualStack:     true,
+		SASLMechanism: mechanism,
+		TLS:           tlsConfig,
+	}
+}
@@ -0,0 +1,87 @@
+package ekafka
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir)
+
+	tlsConfig, err := (&config{}).newTLSConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	tlsConfig, err = (&config{EnableTLS: true, TLSInsecureSkipVerify: true}).newTLSConfig()
+	assert.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+	assert.Nil(t, tlsConfig.RootCAs)
+
+	// 自签名证书同时作为 CA 和客户端证书
+	tlsConfig, err = (&config{TLSCaFile: certFile, TLSCertFile: certFile, TLSKeyFile: keyFile, TLSServerName: "kafka"}).newTLSConfig()
+	assert.NoError(t, err)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.Equal(t, "kafka", tlsConfig.ServerName)
+
+	_, err = (&config{TLSCaFile: keyFile}).newTLSConfig()
+	assert.Error(t, err)
+	_, err = (&config{TLSCaFile: filepath.Join(dir, "missing.pem")}).newTLSConfig()
+	assert.Error(t, err)
+	_, err = (&config{TLSCertFile: certFile}).newTLSConfig()
+	assert.Error(t, err)
+}
+
+func TestNewDialer(t *testing.T) {
+	assert.Nil(t, newDialer(nil, nil))
+
+	tlsConfig, err := (&config{EnableTLS: true}).newTLSConfig()
+	require.NoError(t, err)
+	dialer := newDialer(nil, tlsConfig)
+	assert.Equal(t, tlsConfig, dialer.TLS)
+	assert.Nil(t, dialer.SASLMechanism)
+
+	dialer = newDialer(plain.Mechanism{Username: "u", Password: "p"}, nil)
+	assert.Nil(t, dialer.TLS)
+	assert.NotNil(t, dialer.SASLMechanism)
+}
+
+func writeTestCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kafka"},
+		DNSNames:              []string{"kafka"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
@@ -0,0 +1,538 @@
+package ekafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	defaultTransactionTimeout = 60 * time.Second
+	defaultTxnWriteTimeout    = 10 * time.Second
+	defaultTxnMaxAttempts     = 10
+	defaultTxnBatchSize       = 100
+	// txnRetryBackoff 幂等、事务请求遇到可重试错误时的重试间隔
+	txnRetryBackoff = 100 * time.Millisecond
+	// maxSequence sequence 达到 int32 最大值后从 0 开始
+	maxSequence = 1<<31 - 1
+)
+
+var (
+	// ErrNotTransactional producer 没有配置 transactionalID
+	ErrNotTransactional = errors.New("ekafka: producer is not transactional")
+	// ErrTxnNotBegun 事务 producer 需要在 BeginTxn 之后发送消息
+	ErrTxnNotBegun = errors.New("ekafka: transaction not begun")
+	// ErrTxnInProgress 上一个事务没有提交或回滚
+	ErrTxnInProgress = errors.New("ekafka: transaction in progress")
+	// ErrTxnAbortRequired 事务中的请求失败后只能回滚事务
+	ErrTxnAbortRequired = errors.New("ekafka: transaction failed, abort required")
+)
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// TxnProducer 幂等或事务 Producer。
+// 幂等 Producer 保证写入一个分区的请求在内部重试时不会重复写入。WriteMessages 的消息分布在多个分区时，
+// 返回错误时部分分区可能已经写入成功，再次调用 WriteMessages 会重复写入这些分区的消息。
+// 配置了 transactionalID 时，BeginTxn 与 CommitTxn 之间写入的消息和消费位点一起提交或回滚，失败时回滚事务后重新写入不会产生重复消息。
+// TxnProducer 不是并发安全的，同一时间只能有一个事务
+type TxnProducer struct {
+	topic           string
+	transactionalID string
+	txnTimeout      time.Duration
+	writeTimeout    time.Duration
+	maxAttempts     int
+	batchSize       int
+	balancer        Balancer
+	compression     kafka.Compression
+	addr            net.Addr
+	transport       kafka.RoundTripper
+	client          *kafka.Client
+	processor       ClientInterceptor
+	logMode         bool
+
+	mu            sync.Mutex
+	producerID    int64
+	producerEpoch int16
+	sequences     map[topicPartition]int32
+	inTxn         bool
+	abortRequired bool
+	// produceFailed 事务中有写入失败，broker 上的 sequence 可能已经超前，回滚后需要重新获取 producer id
+	produceFailed bool
+	txnPartitions map[topicPartition]bool
+	txnGroups     map[string]bool
+}
+
+func (p *TxnProducer) setProcessor(c ClientInterceptor) {
+	p.processor = c
+}
+
+// TransactionalID returns the transactional id, empty if the producer is only idempotent.
+func (p *TxnProducer) TransactionalID() string {
+	return p.transactionalID
+}
+
+// WriteMessages writes the messages idempotently, the messages are written to the topic of the message,
+// or the topic of the producer if not set. Transactional producers must write messages in a transaction.
+func (p *TxnProducer) WriteMessages(ctx context.Context, msgs ...*Message) error {
+	return p.processor(func(ctx context.Context, req Messages, c *cmd) error {
+		logCmd(p.logMode, c, "WriteMessages", cmdWithTopic(p.topic))
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.transactionalID != "" {
+			if !p.inTxn {
+				return ErrTxnNotBegun
+			}
+			if p.abortRequired {
+				return ErrTxnAbortRequired
+			}
+		}
+		if err := p.initProducerID(ctx); err != nil {
+			return err
+		}
+		err := p.writeMessages(ctx, req)
+		if err != nil && p.transactionalID != "" {
+			p.abortRequired = true
+		}
+		return err
+	})(ctx, msgs, &cmd{})
+}
+
+// BeginTxn begins a transaction.
+func (p *TxnProducer) BeginTxn() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.transactionalID == "" {
+		return ErrNotTransactional
+	}
+	if p.inTxn {
+		return ErrTxnInProgress
+	}
+	p.inTxn = true
+	p.abortRequired = false
+	p.txnPartitions = make(map[topicPartition]bool)
+	p.txnGroups = make(map[string]bool)
+	return nil
+}
+
+// SendOffsetsToTxn commits the offsets of the consumed messages of groupID in the transaction,
+// the offsets are visible to the group after the transaction is committed.
+// Messages should be consumed by a reader without auto commit, e.g. a Consumer with groupID but never committed.
+func (p *TxnProducer) SendOffsetsToTxn(ctx context.Context, groupID string, msgs ...*Message) error {
+	// 消费的消息不经过拦截器，避免被写入 trace header
+	return p.processor(func(ctx context.Context, _ Messages, c *cmd) error {
+		logCmd(p.logMode, c, "SendOffsetsToTxn", cmdWithTopic(p.topic))
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if err := p.checkTxn(); err != nil {
+			return err
+		}
+		if err := p.initProducerID(ctx); err != nil {
+			return err
+		}
+		err := p.sendOffsets(ctx, groupID, txnOffsets(msgs))
+		if err != nil {
+			p.abortRequired = true
+		}
+		return err
+	})(ctx, nil, &cmd{})
+}
+
+// CommitTxn commits the transaction.
+func (p *TxnProducer) CommitTxn(ctx context.Context) error {
+	return p.processor(func(ctx context.Context, msgs Messages, c *cmd) error {
+		logCmd(p.logMode, c, "CommitTxn", cmdWithTopic(p.topic))
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if err := p.checkTxn(); err != nil {
+			return err
+		}
+		return p.endTxn(ctx, true)
+	})(ctx, nil, &cmd{})
+}
+
+// AbortTxn aborts the transaction, the messages written in the transaction are never visible to read committed consumers.
+func (p *TxnProducer) AbortTxn(ctx context.Context) error {
+	return p.processor(func(ctx context.Context, msgs Messages, c *cmd) error {
+		logCmd(p.logMode, c, "AbortTxn", cmdWithTopic(p.topic))
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.transactionalID == "" {
+			return ErrNotTransactional
+		}
+		if !p.inTxn {
+			return ErrTxnNotBegun
+		}
+		return p.endTxn(ctx, false)
+	})(ctx, nil, &cmd{})
+}
+
+// Close closes the connections of the producer, the transaction in progress is aborted by the coordinator after it times out.
+func (p *TxnProducer) Close() error {
+	return p.processor(func(ctx context.Context, msgs Messages, c *cmd) error {
+		logCmd(p.logMode, c, "ProducerClose", cmdWithTopic(p.topic))
+		if t, ok := p.transport.(*kafka.Transport); ok {
+			t.CloseIdleConnections()
+		}
+		return nil
+	})(context.Background(), nil, &cmd{})
+}
+
+func (p *TxnProducer) checkTxn() error {
+	if p.transactionalID == "" {
+		return ErrNotTransactional
+	}
+	if !p.inTxn {
+		return ErrTxnNotBegun
+	}
+	if p.abortRequired {
+		return ErrTxnAbortRequired
+	}
+	return nil
+}
+
+// initProducerID gets the producer id and epoch from the coordinator, the previous transaction of the
+// transactional id is aborted, and the producers with the same transactional id are fenced.
+func (p *TxnProducer) initProducerID(ctx context.Context) error {
+	if p.producerID >= 0 {
+		return nil
+	}
+	return p.retry(ctx, func() error {
+		res, err := p.client.InitProducerID(ctx, &kafka.InitProducerIDRequest{
+			Addr:                 p.addr,
+			TransactionalID:      p.transactionalID,
+			TransactionTimeoutMs: int(p.txnTimeout / time.Millisecond),
+			ProducerID:           -1,
+			ProducerEpoch:        -1,
+		})
+		if err != nil {
+			return err
+		}
+		if res.Error != nil {
+			return res.Error
+		}
+		p.producerID = int64(res.Producer.ProducerID)
+		p.producerEpoch = int16(res.Producer.ProducerEpoch)
+		p.sequences = make(map[topicPartition]int32)
+		return nil
+	})
+}
+
+// resetProducerID drops the producer id, a new one is requested before the next write.
+func (p *TxnProducer) resetProducerID() {
+	p.producerID = -1
+	p.producerEpoch = -1
+	p.sequences = nil
+}
+
+func (p *TxnProducer) writeMessages(ctx context.Context, msgs Messages) error {
+	batches, order, err := p.partitionMessages(ctx, msgs)
+	if err != nil {
+		return err
+	}
+	if p.transactionalID != "" {
+		if err := p.addPartitionsToTxn(ctx, order); err != nil {
+			return err
+		}
+	}
+	for _, tp := range order {
+		batch := batches[tp]
+		for len(batch) > 0 {
+			n := len(batch)
+			if n > p.batchSize {
+				n = p.batchSize
+			}
+			if err := p.produce(ctx, tp, batch[:n]); err != nil {
+				return fmt.Errorf("write messages to %s[%d] fail, %w", tp.topic, tp.partition, err)
+			}
+			batch = batch[n:]
+		}
+	}
+	return nil
+}
+
+// partitionMessages groups the messages by partition with the balancer, the order of partitions is kept.
+func (p *TxnProducer) partitionMessages(ctx context.Context, msgs Messages) (map[topicPartition][]kafka.Message, []topicPartition, error) {
+	var (
+		batches    = make(map[topicPartition][]kafka.Message)
+		order      []topicPartition
+		partitions = make(map[string][]int)
+	)
+	for _, msg := range msgs {
+		topic := msg.Topic
+		if topic == "" {
+			topic = p.topic
+		}
+		if topic == "" {
+			return nil, nil, errors.New("ekafka: topic of message is required")
+		}
+		ids, ok := partitions[topic]
+		if !ok {
+			var err error
+			if ids, err = p.lookupPartitions(ctx, topic); err != nil {
+				return nil, nil, err
+			}
+			partitions[topic] = ids
+		}
+		m := *msg
+		m.Topic = ""
+		tp := topicPartition{topic: topic, partition: int32(p.balancer.Balance(m, ids...))}
+		if _, ok := batches[tp]; !ok {
+			order = append(order, tp)
+		}
+		batches[tp] = append(batches[tp], m)
+	}
+	return batches, order, nil
+}
+
+func (p *TxnProducer) lookupPartitions(ctx context.Context, topic string) ([]int, error) {
+	res, err := p.client.Metadata(ctx, &kafka.MetadataRequest{Addr: p.addr, Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range res.Topics {
+		if t.Name != topic {
+			continue
+		}
+		if t.Error != nil {
+			return nil, t.Error
+		}
+		ids := make([]int, 0, len(t.Partitions))
+		for _, partition := range t.Partitions {
+			ids = append(ids, partition.ID)
+		}
+		sort.Ints(ids)
+		if len(ids) > 0 {
+			return ids, nil
+		}
+	}
+	return nil, kafka.UnknownTopicOrPartition
+}
+
+// addPartitionsToTxn registers the partitions not yet in the transaction to the coordinator.
+func (p *TxnProducer) addPartitionsToTxn(ctx context.Context, tps []topicPartition) error {
+	topics := make(map[string][]kafka.AddPartitionToTxn)
+	for _, tp := range tps {
+		if !p.txnPartitions[tp] {
+			topics[tp.topic] = append(topics[tp.topic], kafka.AddPartitionToTxn{Partition: int(tp.partition)})
+		}
+	}
+	if len(topics) == 0 {
+		return nil
+	}
+	err := p.retry(ctx, func() error {
+		res, err := p.client.AddPartitionsToTxn(ctx, &kafka.AddPartitionsToTxnRequest{
+			Addr:            p.addr,
+			TransactionalID: p.transactionalID,
+			ProducerID:      int(p.producerID),
+			ProducerEpoch:   int(p.producerEpoch),
+			Topics:          topics,
+		})
+		if err != nil {
+			return err
+		}
+		for _, partitions := range res.Topics {
+			for _, partition := range partitions {
+				if partition.Error != nil {
+					return partition.Error
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for topic, partitions := range topics {
+		for _, partition := range partitions {
+			p.txnPartitions[topicPartition{topic: topic, partition: int32(partition.Partition)}] = true
+		}
+	}
+	return nil
+}
+
+// produce writes the messages to the partition, the same sequence is used in retries, so that the broker
+// drops the duplicated batch.
+func (p *TxnProducer) produce(ctx context.Context, tp topicPartition, msgs []kafka.Message) error {
+	req := &produceRequest{
+		transactionalID: p.transactionalID,
+		topic:           tp.topic,
+		partition:       tp.partition,
+		producerID:      p.producerID,
+		producerEpoch:   p.producerEpoch,
+		baseSequence:    p.sequences[tp],
+		compression:     p.compression,
+		timeout:         p.writeTimeout,
+		messages:        msgs,
+	}
+	retried := false
+	err := p.retry(ctx, func() error {
+		// 只有重试同一批消息时，之前的请求可能已经写入，重复的 sequence 才表示写入成功
+		duplicateOK := retried
+		retried = true
+		res, err := p.transport.RoundTrip(ctx, p.addr, req)
+		if err != nil {
+			return err
+		}
+		if err := res.(*produceResponse).err; err != nil && !(duplicateOK && errors.Is(err, kafka.DuplicateSequenceNumber)) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		// 写入失败后无法确定 broker 上的 sequence，幂等 producer 重新获取 producer id，sequence 从 0 开始。
+		// 事务 producer 只能回滚事务，回滚后再重新获取 producer id
+		if p.transactionalID == "" {
+			p.resetProducerID()
+		} else {
+			p.produceFailed = true
+		}
+		return err
+	}
+	p.sequences[tp] = int32((int64(req.baseSequence) + int64(len(msgs))) & maxSequence)
+	return nil
+}
+
+// sendOffsets adds the group to the transaction, then commits the offsets to the group coordinator.
+func (p *TxnProducer) sendOffsets(ctx context.Context, groupID string, offsets map[string][]kafka.TxnOffsetCommit) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+	if !p.txnGroups[groupID] {
+		err := p.retry(ctx, func() error {
+			res, err := p.client.AddOffsetsToTxn(ctx, &kafka.AddOffsetsToTxnRequest{
+				Addr:            p.addr,
+				TransactionalID: p.transactionalID,
+				ProducerID:      int(p.producerID),
+				ProducerEpoch:   int(p.producerEpoch),
+				GroupID:         groupID,
+			})
+			if err != nil {
+				return err
+			}
+			return res.Error
+		})
+		if err != nil {
+			return err
+		}
+		p.txnGroups[groupID] = true
+	}
+	return p.retry(ctx, func() error {
+		res, err := p.client.TxnOffsetCommit(ctx, &kafka.TxnOffsetCommitRequest{
+			Addr:            p.addr,
+			TransactionalID: p.transactionalID,
+			GroupID:         groupID,
+			ProducerID:      int(p.producerID),
+			ProducerEpoch:   int(p.producerEpoch),
+			GenerationID:    -1,
+			Topics:          offsets,
+		})
+		if err != nil {
+			return err
+		}
+		for _, partitions := range res.Topics {
+			for _, partition := range partitions {
+				if partition.Error != nil {
+					return partition.Error
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// endTxn commits or aborts the transaction, nothing is sent to the coordinator if the transaction is empty.
+// After aborting a transaction with a failed write, the producer id is requested again like the java client,
+// the coordinator bumps the epoch and the sequences restart from 0.
+func (p *TxnProducer) endTxn(ctx context.Context, committed bool) error {
+	if p.producerID >= 0 && (len(p.txnPartitions) > 0 || len(p.txnGroups) > 0) {
+		err := p.retry(ctx, func() error {
+			res, err := p.client.EndTxn(ctx, &kafka.EndTxnRequest{
+				Addr:            p.addr,
+				TransactionalID: p.transactionalID,
+				ProducerID:      int(p.producerID),
+				ProducerEpoch:   int(p.producerEpoch),
+				Committed:       committed,
+			})
+			if err != nil {
+				return err
+			}
+			return res.Error
+		})
+		if err != nil {
+			// 被新的 producer 隔离后无法继续使用当前 producer id
+			if errors.Is(err, kafka.ProducerFenced) || errors.Is(err, kafka.InvalidProducerEpoch) {
+				p.resetProducerID()
+				p.inTxn = false
+				p.produceFailed = false
+			} else if committed {
+				p.abortRequired = true
+			}
+			return err
+		}
+	}
+	if !committed && p.produceFailed {
+		p.resetProducerID()
+	}
+	p.inTxn = false
+	p.abortRequired = false
+	p.produceFailed = false
+	p.txnPartitions = nil
+	p.txnGroups = nil
+	return nil
+}
+
+// retry runs fn until it succeeds, fails with a non retryable error, or runs out of attempts.
+func (p *TxnProducer) retry(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < p.maxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(txnRetryBackoff * time.Duration(attempt)):
+			}
+		}
+		if err = fn(); err == nil || !isTxnRetryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func isTxnRetryable(err error) bool {
+	var kafkaErr kafka.Error
+	if errors.As(err, &kafkaErr) {
+		return kafkaErr.Temporary() || kafkaErr == kafka.ConcurrentTransactions
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// txnOffsets returns the offsets to commit of the consumed messages, which are the next offsets of the last messages.
+func txnOffsets(msgs Messages) map[string][]kafka.TxnOffsetCommit {
+	next := make(map[topicPartition]int64)
+	for _, msg := range msgs {
+		tp := topicPartition{topic: msg.Topic, partition: int32(msg.Partition)}
+		if offset, ok := next[tp]; !ok || msg.Offset+1 > offset {
+			next[tp] = msg.Offset + 1
+		}
+	}
+	offsets := make(map[string][]kafka.TxnOffsetCommit)
+	for tp, offset := range next {
+		offsets[tp.topic] = append(offsets[tp.topic], kafka.TxnOffsetCommit{Partition: int(tp.partition), Offset: offset})
+	}
+	for _, partitions := range offsets {
+		sort.Slice(partitions, func(i, j int) bool { return partitions[i].Partition < partitions[j].Partition })
+	}
+	return offsets
+}
@@ -0,0 +1,330 @@
+package ekafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+// produceRequestVersion record batch v2 和 transactional_id 需要 Produce v3 及以上版本
+const produceRequestVersion = 3
+
+const (
+	recordBatchAttrTransactional = 1 << 4
+	// recordBatchCRCOffset crc 之后的字段参与校验
+	recordBatchCRCOffset = 21
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// produceRequest is a Produce v3 request of a partition, records are encoded with the producer id,
+// epoch and sequence, which are always -1 in kafka-go.
+// It is routed to the partition leader by kafka.Transport, and written to the connection by RawExchange,
+// so the connection is established with the TLS and SASL of the transport.
+type produceRequest struct {
+	transactionalID string
+	topic           string
+	partition       int32
+	producerID      int64
+	producerEpoch   int16
+	baseSequence    int32
+	compression     kafka.Compression
+	timeout         time.Duration
+	messages        []kafka.Message
+}
+
+// produceResponse is the response of a produceRequest.
+type produceResponse struct {
+	err        error
+	baseOffset int64
+}
+
+func (r *produceRequest) ApiKey() protocol.ApiKey { return protocol.Produce }
+
+func (r *produceResponse) ApiKey() protocol.ApiKey { return protocol.Produce }
+
+// Broker returns the leader of the partition.
+func (r *produceRequest) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	topic, ok := cluster.Topics[r.topic]
+	if !ok {
+		return protocol.Broker{}, kafka.UnknownTopicOrPartition
+	}
+	partition, ok := topic.Partitions[r.partition]
+	if !ok {
+		return protocol.Broker{}, kafka.UnknownTopicOrPartition
+	}
+	broker, ok := cluster.Brokers[partition.Leader]
+	if !ok {
+		return protocol.Broker{}, kafka.LeaderNotAvailable
+	}
+	return broker, nil
+}
+
+// Required is always true, the request is never encoded by kafka-go.
+func (r *produceRequest) Required(versions map[protocol.ApiKey]int16) bool {
+	return true
+}
+
+// RawExchange writes the request and reads the response, the connection serves one request at a time.
+func (r *produceRequest) RawExchange(rw io.ReadWriter) (protocol.Message, error) {
+	const correlationID = 1
+	req, err := r.encode(correlationID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rw.Write(req); err != nil {
+		return nil, err
+	}
+	return readProduceResponse(rw, correlationID)
+}
+
+// encode returns the size prefixed request.
+func (r *produceRequest) encode(correlationID int32) ([]byte, error) {
+	batch, err := r.encodeRecordBatch()
+	if err != nil {
+		return nil, err
+	}
+	w := &protocolWriter{}
+	w.int32(0) // size placeholder
+	// request header v1
+	w.int16(int16(protocol.Produce))
+	w.int16(produceRequestVersion)
+	w.int32(correlationID)
+	w.string("ekafka")
+	// request body
+	w.nullableString(r.transactionalID)
+	w.int16(int16(kafka.RequireAll))
+	w.int32(int32(r.timeout / time.Millisecond))
+	w.int32(1) // topics
+	w.string(r.topic)
+	w.int32(1) // partitions
+	w.int32(r.partition)
+	w.int32(int32(len(batch)))
+	w.buf.Write(batch)
+
+	b := w.buf.Bytes()
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	return b, nil
+}
+
+// encodeRecordBatch encodes the messages as a record batch v2.
+func (r *produceRequest) encodeRecordBatch() ([]byte, error) {
+	if len(r.messages) == 0 {
+		return nil, protocol.ErrNoRecord
+	}
+	attributes := int16(r.compression) & 0x7
+	if r.transactionalID != "" {
+		attributes |= recordBatchAttrTransactional
+	}
+	now := time.Now()
+	firstTimestamp := timestampMs(r.messages[0].Time, now)
+	maxTimestamp := firstTimestamp
+
+	records := &protocolWriter{}
+	for i, msg := range r.messages {
+		ts := timestampMs(msg.Time, now)
+		if ts > maxTimestamp {
+			maxTimestamp = ts
+		}
+		record := &protocolWriter{}
+		record.int8(0) // record attributes
+		record.varint(ts - firstTimestamp)
+		record.varint(int64(i))
+		record.varBytes(msg.Key)
+		record.varBytes(msg.Value)
+		record.varint(int64(len(msg.Headers)))
+		for _, h := range msg.Headers {
+			record.varBytes([]byte(h.Key))
+			record.varBytes(h.Value)
+		}
+		records.varint(int64(record.buf.Len()))
+		records.buf.Write(record.buf.Bytes())
+	}
+	recordsBytes := records.buf.Bytes()
+	if codec := r.compression.Codec(); codec != nil {
+		compressed := &bytes.Buffer{}
+		cw := codec.NewWriter(compressed)
+		if _, err := cw.Write(recordsBytes); err != nil {
+			return nil, err
+		}
+		if err := cw.Close(); err != nil {
+			return nil, err
+		}
+		recordsBytes = compressed.Bytes()
+	}
+
+	w := &protocolWriter{}
+	w.int64(0)  // base offset
+	w.int32(0)  // batch length placeholder
+	w.int32(-1) // partition leader epoch
+	w.int8(2)   // magic
+	w.int32(0)  // crc placeholder
+	w.int16(attributes)
+	w.int32(int32(len(r.messages) - 1)) // last offset delta
+	w.int64(firstTimestamp)
+	w.int64(maxTimestamp)
+	w.int64(r.producerID)
+	w.int16(r.producerEpoch)
+	w.int32(r.baseSequence)
+	w.int32(int32(len(r.messages)))
+	w.buf.Write(recordsBytes)
+
+	b := w.buf.Bytes()
+	binary.BigEndian.PutUint32(b[8:], uint32(len(b)-12))
+	binary.BigEndian.PutUint32(b[17:], crc32.Checksum(b[recordBatchCRCOffset:], crc32cTable))
+	return b, nil
+}
+
+// readProduceResponse reads a Produce v3 response of a single partition.
+func readProduceResponse(r io.Reader, correlationID int32) (*produceResponse, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	body := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	d := &protocolReader{b: body}
+	if id := d.int32(); id != correlationID && d.err == nil {
+		return nil, fmt.Errorf("produce response correlation id mismatch, %d != %d", id, correlationID)
+	}
+	res := &produceResponse{}
+	found := false
+	for topics := d.int32(); topics > 0 && d.err == nil; topics-- {
+		d.string()
+		for partitions := d.int32(); partitions > 0 && d.err == nil; partitions-- {
+			d.int32() // partition
+			code := d.int16()
+			res.baseOffset = d.int64()
+			d.int64() // log append time
+			if code != 0 {
+				res.err = kafka.Error(code)
+			}
+			found = true
+		}
+	}
+	d.int32() // throttle time
+	if d.err != nil {
+		return nil, fmt.Errorf("decode produce response fail, %w", d.err)
+	}
+	if !found {
+		return nil, errors.New("decode produce response fail, no partition in response")
+	}
+	return res, nil
+}
+
+func timestampMs(t, now time.Time) int64 {
+	if t.IsZero() {
+		t = now
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// protocolWriter encodes the primitive types of kafka protocol.
+type protocolWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *protocolWriter) int8(v int8) { w.buf.WriteByte(byte(v)) }
+
+func (w *protocolWriter) int16(v int16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(v))
+	w.buf.Write(b[:])
+}
+
+func (w *protocolWriter) int32(v int32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(v))
+	w.buf.Write(b[:])
+}
+
+func (w *protocolWriter) int64(v int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	w.buf.Write(b[:])
+}
+
+func (w *protocolWriter) string(v string) {
+	w.int16(int16(len(v)))
+	w.buf.WriteString(v)
+}
+
+func (w *protocolWriter) nullableString(v string) {
+	if v == "" {
+		w.int16(-1)
+		return
+	}
+	w.string(v)
+}
+
+func (w *protocolWriter) varint(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(b[:], v)
+	w.buf.Write(b[:n])
+}
+
+func (w *protocolWriter) varBytes(v []byte) {
+	if v == nil {
+		w.varint(-1)
+		return
+	}
+	w.varint(int64(len(v)))
+	w.buf.Write(v)
+}
+
+// protocolReader decodes the primitive types of kafka protocol, the first error is kept in err.
+type protocolReader struct {
+	b   []byte
+	err error
+}
+
+func (r *protocolReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.b) < n {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *protocolReader) int16() int16 {
+	if b := r.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *protocolReader) int32() int32 {
+	if b := r.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *protocolReader) int64() int64 {
+	if b := r.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (r *protocolReader) string() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
@@ -0,0 +1,496 @@
+package ekafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego-component/ekafka/ekafkatest"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/addoffsetstotxn"
+	"github.com/segmentio/kafka-go/protocol/addpartitionstotxn"
+	"github.com/segmentio/kafka-go/protocol/endtxn"
+	"github.com/segmentio/kafka-go/protocol/initproducerid"
+	metadataapi "github.com/segmentio/kafka-go/protocol/metadata"
+	produceapi "github.com/segmentio/kafka-go/protocol/produce"
+	"github.com/segmentio/kafka-go/protocol/txnoffsetcommit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProduceRequestEncode(t *testing.T) {
+	for _, compression := range []kafka.Compression{0, kafka.Gzip, kafka.Snappy} {
+		req := &produceRequest{
+			transactionalID: "txn-1",
+			topic:           "topic-a",
+			partition:       2,
+			producerID:      1000,
+			producerEpoch:   3,
+			baseSequence:    42,
+			compression:     compression,
+			timeout:         defaultTxnWriteTimeout,
+			messages: []kafka.Message{
+				{Key: []byte("k1"), Value: []byte("v1"), Headers: []kafka.Header{{Key: "h", Value: []byte("hv")}}},
+				{Value: []byte("v2")},
+			},
+		}
+		b, err := req.encode(7)
+		require.NoError(t, err)
+
+		version, correlationID, clientID, msg, err := protocol.ReadRequest(bytes.NewReader(b))
+		require.NoError(t, err)
+		assert.Equal(t, int16(produceRequestVersion), version)
+		assert.Equal(t, int32(7), correlationID)
+		assert.Equal(t, "ekafka", clientID)
+
+		produceReq := msg.(*produceapi.Request)
+		assert.Equal(t, "txn-1", produceReq.TransactionalID)
+		assert.Equal(t, int16(kafka.RequireAll), produceReq.Acks)
+		require.Len(t, produceReq.Topics, 1)
+		assert.Equal(t, "topic-a", produceReq.Topics[0].Topic)
+		require.Len(t, produceReq.Topics[0].Partitions, 1)
+		partition := produceReq.Topics[0].Partitions[0]
+		assert.Equal(t, int32(2), partition.Partition)
+
+		batch := recordBatch(t, partition.RecordSet)
+		assert.True(t, batch.Attributes.Transactional())
+		assert.Equal(t, compression, kafka.Compression(batch.Attributes.Compression()))
+		assert.Equal(t, int64(1000), batch.ProducerID)
+		assert.Equal(t, int16(3), batch.ProducerEpoch)
+		assert.Equal(t, int32(42), batch.BaseSequence)
+
+		record, err := batch.ReadRecord()
+		require.NoError(t, err)
+		assert.Equal(t, "k1", readBytes(t, record.Key))
+		assert.Equal(t, "v1", readBytes(t, record.Value))
+		assert.Equal(t, []protocol.Header{{Key: "h", Value: []byte("hv")}}, record.Headers)
+		record, err = batch.ReadRecord()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), record.Offset)
+		assert.Nil(t, record.Key)
+		assert.Equal(t, "v2", readBytes(t, record.Value))
+	}
+
+	// 幂等 producer 没有 transactional id
+	b, err := (&produceRequest{topic: "topic-a", producerID: 1, messages: []kafka.Message{{Value: []byte("v")}}}).encode(1)
+	require.NoError(t, err)
+	_, _, _, msg, err := protocol.ReadRequest(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, "", msg.(*produceapi.Request).TransactionalID)
+	batch := recordBatch(t, msg.(*produceapi.Request).Topics[0].Partitions[0].RecordSet)
+	assert.False(t, batch.Attributes.Transactional())
+
+	_, err = (&produceRequest{topic: "topic-a"}).encode(1)
+	assert.Equal(t, protocol.ErrNoRecord, err)
+}
+
+func TestReadProduceResponse(t *testing.T) {
+	response := func(correlationID int32, code int16) []byte {
+		w := &protocolWriter{}
+		w.int32(0)
+		w.int32(correlationID)
+		w.int32(1)
+		w.string("topic-a")
+		w.int32(1)
+		w.int32(2)
+		w.int16(code)
+		w.int64(100)
+		w.int64(-1)
+		w.int32(0)
+		b := w.buf.Bytes()
+		b[3] = byte(len(b) - 4)
+		return b
+	}
+
+	res, err := readProduceResponse(bytes.NewReader(response(1, 0)), 1)
+	require.NoError(t, err)
+	assert.NoError(t, res.err)
+	assert.Equal(t, int64(100), res.baseOffset)
+
+	res, err = readProduceResponse(bytes.NewReader(response(1, int16(kafka.DuplicateSequenceNumber))), 1)
+	require.NoError(t, err)
+	assert.Equal(t, kafka.DuplicateSequenceNumber, res.err)
+
+	_, err = readProduceResponse(bytes.NewReader(response(2, 0)), 1)
+	assert.Error(t, err)
+	_, err = readProduceResponse(bytes.NewReader(response(1, 0)[:10]), 1)
+	assert.Error(t, err)
+}
+
+func TestTxnOffsets(t *testing.T) {
+	offsets := txnOffsets(Messages{
+		{Topic: "a", Partition: 1, Offset: 10},
+		{Topic: "a", Partition: 0, Offset: 5},
+		{Topic: "a", Partition: 1, Offset: 12},
+		{Topic: "a", Partition: 1, Offset: 11},
+		{Topic: "b", Partition: 0, Offset: 0},
+	})
+	assert.Equal(t, map[string][]kafka.TxnOffsetCommit{
+		"a": {{Partition: 0, Offset: 6}, {Partition: 1, Offset: 13}},
+		"b": {{Partition: 0, Offset: 1}},
+	}, offsets)
+}
+
+func TestTxnProducerState(t *testing.T) {
+	newProducer := func(transactionalID string) *TxnProducer {
+		p := &TxnProducer{transactionalID: transactionalID, producerID: -1, producerEpoch: -1}
+		p.setProcessor(InterceptorClientChain())
+		return p
+	}
+	ctx := context.Background()
+
+	idempotent := newProducer("")
+	assert.Equal(t, ErrNotTransactional, idempotent.BeginTxn())
+	assert.Equal(t, ErrNotTransactional, idempotent.CommitTxn(ctx))
+	assert.Equal(t, ErrNotTransactional, idempotent.AbortTxn(ctx))
+	assert.Equal(t, ErrNotTransactional, idempotent.SendOffsetsToTxn(ctx, "group"))
+
+	txn := newProducer("txn-1")
+	assert.Equal(t, ErrTxnNotBegun, txn.WriteMessages(ctx, &Message{Value: []byte("v")}))
+	assert.Equal(t, ErrTxnNotBegun, txn.CommitTxn(ctx))
+	require.NoError(t, txn.BeginTxn())
+	assert.Equal(t, ErrTxnInProgress, txn.BeginTxn())
+	// 空事务不需要请求 coordinator
+	assert.NoError(t, txn.CommitTxn(ctx))
+	require.NoError(t, txn.BeginTxn())
+
+	txn.abortRequired = true
+	assert.Equal(t, ErrTxnAbortRequired, txn.WriteMessages(ctx, &Message{Value: []byte("v")}))
+	assert.Equal(t, ErrTxnAbortRequired, txn.CommitTxn(ctx))
+	assert.NoError(t, txn.AbortTxn(ctx))
+	assert.NoError(t, txn.BeginTxn())
+}
+
+func TestTxnProducerIdempotent(t *testing.T) {
+	broker, err := ekafkatest.NewBroker(ekafkatest.WithTopic("idempotent", 1))
+	require.NoError(t, err)
+	defer broker.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c := DefaultContainer()
+	c.config.Producers = map[string]producerConfig{"p1": {Topic: "idempotent", Idempotent: true, MaxAttempts: 3}}
+	producer := c.Build(WithBrokers(broker.Addr()), WithDebug(false)).TxnProducer("p1")
+	values := func() []string {
+		var list []string
+		for _, message := range broker.Messages("idempotent", 0) {
+			list = append(list, string(message.Value))
+		}
+		return list
+	}
+
+	require.NoError(t, producer.WriteMessages(ctx, &Message{Value: []byte("1")}, &Message{Value: []byte("2")}))
+	producerID := producer.producerID
+
+	// 响应丢失后重试，broker 丢弃重复的批次
+	broker.LoseProduceResponses(1)
+	require.NoError(t, producer.WriteMessages(ctx, &Message{Value: []byte("3")}))
+	assert.Equal(t, []string{"1", "2", "3"}, values())
+	assert.Equal(t, producerID, producer.producerID)
+
+	// 重试耗尽后重新获取 producer id
+	broker.LoseProduceResponses(3)
+	assert.Error(t, producer.WriteMessages(ctx, &Message{Value: []byte("4")}))
+	assert.Equal(t, int64(-1), producer.producerID)
+	require.NoError(t, producer.WriteMessages(ctx, &Message{Value: []byte("5")}))
+	assert.NotEqual(t, producerID, producer.producerID)
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, values())
+
+	// 第一次发送就返回重复的 sequence，说明 sequence 与 broker 不一致，不能当作写入成功
+	producer.sequences[topicPartition{topic: "idempotent"}] = 0
+	err = producer.WriteMessages(ctx, &Message{Value: []byte("6")}, &Message{Value: []byte("7")})
+	assert.ErrorIs(t, err, kafka.DuplicateSequenceNumber)
+	assert.Equal(t, int64(-1), producer.producerID)
+	require.NoError(t, producer.WriteMessages(ctx, &Message{Value: []byte("6")}, &Message{Value: []byte("7")}))
+	assert.Equal(t, []string{"1", "2", "3", "4", "5", "6", "7"}, values())
+}
+
+func TestIsTxnRetryable(t *testing.T) {
+	assert.True(t, isTxnRetryable(kafka.NotCoordinatorForGroup))
+	assert.True(t, isTxnRetryable(kafka.ConcurrentTransactions))
+	assert.True(t, isTxnRetryable(io.ErrUnexpectedEOF))
+	assert.False(t, isTxnRetryable(kafka.ProducerFenced))
+	assert.False(t, isTxnRetryable(ErrTxnAbortRequired))
+}
+
+func recordBatch(t *testing.T, rs protocol.RecordSet) *protocol.RecordBatch {
+	stream, ok := rs.Records.(*protocol.RecordStream)
+	require.True(t, ok)
+	require.Len(t, stream.Records, 1)
+	batch, ok := stream.Records[0].(*protocol.RecordBatch)
+	require.True(t, ok)
+	return batch
+}
+
+func readBytes(t *testing.T, b protocol.Bytes) string {
+	data, err := ioutil.ReadAll(b)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// fakeTxnBroker 实现事务 producer 用到的请求，Produce 请求经过 RawExchange 编码、解码，用于测试事务的完整流程
+type fakeTxnBroker struct {
+	topic         string
+	producerID    int64
+	producerEpoch int16
+	// sequences 当前 epoch 下一个期望的 sequence
+	sequences     map[int32]int32
+	txnPartitions map[int32]bool
+	txnGroups     map[string]bool
+	pending       []string
+	pendingOffset map[string]int64
+	committed     []string
+	offsets       map[string]int64
+	requests      []string
+	// loseProduceResponses 接下来的 n 个写入请求正常写入，但是响应丢失
+	loseProduceResponses int
+	// fenceEndTxn EndTxn 返回 ProducerFenced
+	fenceEndTxn bool
+	response    bytes.Buffer
+}
+
+func newFakeTxnBroker(topic string) *fakeTxnBroker {
+	return &fakeTxnBroker{topic: topic, producerID: 1000, producerEpoch: -1, offsets: make(map[string]int64)}
+}
+
+func (b *fakeTxnBroker) newProducer(transactionalID string) *TxnProducer {
+	addr := kafka.TCP("fake:9092")
+	p := &TxnProducer{
+		topic:           b.topic,
+		transactionalID: transactionalID,
+		txnTimeout:      defaultTransactionTimeout,
+		writeTimeout:    defaultTxnWriteTimeout,
+		maxAttempts:     3,
+		batchSize:       defaultTxnBatchSize,
+		balancer:        &kafka.RoundRobin{},
+		addr:            addr,
+		transport:       b,
+		client:          &kafka.Client{Addr: addr, Transport: b},
+		producerID:      -1,
+		producerEpoch:   -1,
+	}
+	p.setProcessor(InterceptorClientChain())
+	return p
+}
+
+func (b *fakeTxnBroker) RoundTrip(ctx context.Context, addr net.Addr, msg protocol.Message) (protocol.Message, error) {
+	b.requests = append(b.requests, msg.ApiKey().String())
+	switch req := msg.(type) {
+	case *initproducerid.Request:
+		// 同一个 transactional id 再次初始化时回滚未完成的事务，epoch 加一
+		b.producerEpoch++
+		b.sequences = make(map[int32]int32)
+		b.endTxn(false)
+		return &initproducerid.Response{ProducerID: b.producerID, ProducerEpoch: b.producerEpoch}, nil
+	case *metadataapi.Request:
+		return &metadataapi.Response{
+			Brokers: []metadataapi.ResponseBroker{{NodeID: 1, Host: "fake", Port: 9092}},
+			Topics:  []metadataapi.ResponseTopic{{Name: b.topic, Partitions: []metadataapi.ResponsePartition{{PartitionIndex: 0, LeaderID: 1}}}},
+		}, nil
+	case *addpartitionstotxn.Request:
+		if code := b.checkProducer(req.ProducerID, req.ProducerEpoch); code != 0 {
+			return nil, kafka.Error(code)
+		}
+		res := &addpartitionstotxn.Response{}
+		for _, topic := range req.Topics {
+			result := addpartitionstotxn.ResponseResult{Name: topic.Name}
+			for _, partition := range topic.Partitions {
+				b.txnPartitions[partition] = true
+				result.Results = append(result.Results, addpartitionstotxn.ResponsePartition{PartitionIndex: partition})
+			}
+			res.Results = append(res.Results, result)
+		}
+		return res, nil
+	case *produceRequest:
+		return req.RawExchange(b)
+	case *addoffsetstotxn.Request:
+		b.txnGroups[req.GroupID] = true
+		return &addoffsetstotxn.Response{ErrorCode: b.checkProducer(req.ProducerID, req.ProducerEpoch)}, nil
+	case *txnoffsetcommit.Request:
+		code := b.checkProducer(req.ProducerID, req.ProducerEpoch)
+		if code == 0 && !b.txnGroups[req.GroupID] {
+			code = int16(kafka.InvalidTransactionState)
+		}
+		res := &txnoffsetcommit.Response{}
+		for _, topic := range req.Topics {
+			result := txnoffsetcommit.ResponseTopic{Name: topic.Name}
+			for _, partition := range topic.Partitions {
+				if code == 0 {
+					b.pendingOffset[fmt.Sprintf("%s/%s/%d", req.GroupID, topic.Name, partition.Partition)] = partition.CommittedOffset
+				}
+				result.Partitions = append(result.Partitions, txnoffsetcommit.ResponsePartition{Partition: partition.Partition, ErrorCode: code})
+			}
+			res.Topics = append(res.Topics, result)
+		}
+		return res, nil
+	case *endtxn.Request:
+		if b.fenceEndTxn {
+			return &endtxn.Response{ErrorCode: int16(kafka.ProducerFenced)}, nil
+		}
+		if code := b.checkProducer(req.ProducerID, req.ProducerEpoch); code != 0 {
+			return &endtxn.Response{ErrorCode: code}, nil
+		}
+		b.endTxn(req.Committed)
+		return &endtxn.Response{}, nil
+	}
+	return nil, fmt.Errorf("unexpected request %T", msg)
+}
+
+func (b *fakeTxnBroker) checkProducer(producerID int64, producerEpoch int16) int16 {
+	if producerID != b.producerID || producerEpoch != b.producerEpoch {
+		return int16(kafka.InvalidProducerEpoch)
+	}
+	return 0
+}
+
+// endTxn 提交时写入的消息以及消费位点才可见
+func (b *fakeTxnBroker) endTxn(committed bool) {
+	if committed {
+		b.committed = append(b.committed, b.pending...)
+		for key, offset := range b.pendingOffset {
+			b.offsets[key] = offset
+		}
+	}
+	b.pending = nil
+	b.pendingOffset = make(map[string]int64)
+	b.txnPartitions = make(map[int32]bool)
+	b.txnGroups = make(map[string]bool)
+}
+
+// Write decodes the Produce request written by RawExchange and prepares the response.
+func (b *fakeTxnBroker) Write(p []byte) (int, error) {
+	_, correlationID, _, msg, err := protocol.ReadRequest(bytes.NewReader(p))
+	if err != nil {
+		return 0, err
+	}
+	req := msg.(*produceapi.Request)
+	partition := req.Topics[0].Partitions[0]
+	rs := partition.RecordSet
+	batch, ok := rs.Records.(*protocol.RecordStream).Records[0].(*protocol.RecordBatch)
+	if !ok {
+		return 0, errors.New("record batch v2 expected")
+	}
+	var values []string
+	for {
+		record, err := batch.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+		data, err := ioutil.ReadAll(record.Value)
+		if err != nil {
+			return 0, err
+		}
+		values = append(values, string(data))
+	}
+
+	code := b.checkProducer(batch.ProducerID, batch.ProducerEpoch)
+	switch {
+	case code != 0:
+	case req.TransactionalID == "" || !batch.Attributes.Transactional():
+		code = int16(kafka.InvalidRequest)
+	case !b.txnPartitions[partition.Partition]:
+		code = int16(kafka.InvalidTransactionState)
+	case batch.BaseSequence < b.sequences[partition.Partition]:
+		code = int16(kafka.DuplicateSequenceNumber)
+	case batch.BaseSequence > b.sequences[partition.Partition]:
+		code = int16(kafka.OutOfOrderSequenceNumber)
+	default:
+		b.sequences[partition.Partition] += int32(len(values))
+		b.pending = append(b.pending, values...)
+	}
+	if b.loseProduceResponses > 0 {
+		b.loseProduceResponses--
+		return 0, io.ErrUnexpectedEOF
+	}
+	res := &produceapi.Response{Topics: []produceapi.ResponseTopic{{
+		Topic:      req.Topics[0].Topic,
+		Partitions: []produceapi.ResponsePartition{{Partition: partition.Partition, ErrorCode: code}},
+	}}}
+	if err := protocol.WriteResponse(&b.response, produceRequestVersion, correlationID, res); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (b *fakeTxnBroker) Read(p []byte) (int, error) {
+	return b.response.Read(p)
+}
+
+func TestTxnProducerTransaction(t *testing.T) {
+	broker := newFakeTxnBroker("orders")
+	producer := broker.newProducer("txn-1")
+	ctx := context.Background()
+
+	// 写入的消息以及消费位点在提交后可见
+	require.NoError(t, producer.BeginTxn())
+	require.NoError(t, producer.WriteMessages(ctx, &Message{Value: []byte("1")}, &Message{Value: []byte("2")}))
+	require.NoError(t, producer.SendOffsetsToTxn(ctx, "group-1", &Message{Topic: "input", Partition: 0, Offset: 9}))
+	assert.Empty(t, broker.committed)
+	require.NoError(t, producer.CommitTxn(ctx))
+	assert.Equal(t, []string{"1", "2"}, broker.committed)
+	assert.Equal(t, map[string]int64{"group-1/input/0": 10}, broker.offsets)
+	assert.Equal(t, []string{"InitProducerId", "Metadata", "AddPartitionsToTxn", "Produce", "AddOffsetsToTxn", "TxnOffsetCommit", "EndTxn"}, broker.requests)
+
+	// 回滚的消息不可见，sequence 继续递增
+	require.NoError(t, producer.BeginTxn())
+	require.NoError(t, producer.WriteMessages(ctx, &Message{Value: []byte("3")}))
+	require.NoError(t, producer.AbortTxn(ctx))
+	require.NoError(t, producer.BeginTxn())
+	require.NoError(t, producer.WriteMessages(ctx, &Message{Value: []byte("4")}))
+	require.NoError(t, producer.CommitTxn(ctx))
+	assert.Equal(t, []string{"1", "2", "4"}, broker.committed)
+	assert.Equal(t, int16(0), broker.producerEpoch)
+}
+
+func TestTxnProducerAbortAfterFailedWrite(t *testing.T) {
+	broker := newFakeTxnBroker("orders")
+	producer := broker.newProducer("txn-1")
+	ctx := context.Background()
+
+	require.NoError(t, producer.BeginTxn())
+	require.NoError(t, producer.WriteMessages(ctx, &Message{Value: []byte("1")}))
+	// 重试耗尽，broker 已经写入了最后一批消息，sequence 比 producer 超前
+	broker.loseProduceResponses = 3
+	assert.Error(t, producer.WriteMessages(ctx, &Message{Value: []byte("2")}))
+	// 写入失败后只能回滚事务
+	assert.Equal(t, ErrTxnAbortRequired, producer.WriteMessages(ctx, &Message{Value: []byte("3")}))
+	assert.Equal(t, ErrTxnAbortRequired, producer.CommitTxn(ctx))
+	require.NoError(t, producer.AbortTxn(ctx))
+	assert.Equal(t, int64(-1), producer.producerID)
+
+	// 重新获取 producer id，epoch 加一，sequence 从 0 开始
+	require.NoError(t, producer.BeginTxn())
+	require.NoError(t, producer.WriteMessages(ctx, &Message{Value: []byte("4")}))
+	require.NoError(t, producer.CommitTxn(ctx))
+	assert.Equal(t, int16(1), producer.producerEpoch)
+	assert.Equal(t, []string{"4"}, broker.committed)
+}
+
+func TestTxnProducerFenced(t *testing.T) {
+	broker := newFakeTxnBroker("orders")
+	producer := broker.newProducer("txn-1")
+	ctx := context.Background()
+
+	require.NoError(t, producer.BeginTxn())
+	require.NoError(t, producer.WriteMessages(ctx, &Message{Value: []byte("1")}))
+	// 被新的 producer 隔离后结束事务，下次写入重新获取 producer id
+	broker.fenceEndTxn = true
+	assert.ErrorIs(t, producer.CommitTxn(ctx), kafka.ProducerFenced)
+	assert.Equal(t, int64(-1), producer.producerID)
+	assert.Equal(t, ErrTxnNotBegun, producer.CommitTxn(ctx))
+
+	broker.fenceEndTxn = false
+	require.NoError(t, producer.BeginTxn())
+	require.NoError(t, producer.WriteMessages(ctx, &Message{Value: []byte("2")}))
+	require.NoError(t, producer.CommitTxn(ctx))
+	assert.Equal(t, []string{"2"}, broker.committed)
+}
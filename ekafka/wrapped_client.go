@@ -91,3 +91,57 @@ func (wc *Client) CreateTopics(ctx context.Context, req *kafka.CreateTopicsReque
 	})(ctx, nil, &cmd{})
 	return
 }
+
+func (wc *Client) CreatePartitions(ctx context.Context, req *kafka.CreatePartitionsRequest) (res *kafka.CreatePartitionsResponse, err error) {
+	err = wc.processor(func(ctx context.Context, msgs Messages, c *cmd) error {
+		logCmd(wc.logMode, c, "CreatePartitions")
+		res, err = wc.cc.CreatePartitions(ctx, req)
+		return err
+	})(ctx, nil, &cmd{})
+	return
+}
+
+func (wc *Client) DescribeConfigs(ctx context.Context, req *kafka.DescribeConfigsRequest) (res *kafka.DescribeConfigsResponse, err error) {
+	err = wc.processor(func(ctx context.Context, msgs Messages, c *cmd) error {
+		logCmd(wc.logMode, c, "DescribeConfigs")
+		res, err = wc.cc.DescribeConfigs(ctx, req)
+		return err
+	})(ctx, nil, &cmd{})
+	return
+}
+
+func (wc *Client) IncrementalAlterConfigs(ctx context.Context, req *kafka.IncrementalAlterConfigsRequest) (res *kafka.IncrementalAlterConfigsResponse, err error) {
+	err = wc.processor(func(ctx context.Context, msgs Messages, c *cmd) error {
+		logCmd(wc.logMode, c, "IncrementalAlterConfigs")
+		res, err = wc.cc.IncrementalAlterConfigs(ctx, req)
+		return err
+	})(ctx, nil, &cmd{})
+	return
+}
+
+func (wc *Client) ListGroups(ctx context.Context, req *kafka.ListGroupsRequest) (res *kafka.ListGroupsResponse, err error) {
+	err = wc.processor(func(ctx context.Context, msgs Messages, c *cmd) error {
+		logCmd(wc.logMode, c, "ListGroups")
+		res, err = wc.cc.ListGroups(ctx, req)
+		return err
+	})(ctx, nil, &cmd{})
+	return
+}
+
+func (wc *Client) DescribeGroups(ctx context.Context, req *kafka.DescribeGroupsRequest) (res *kafka.DescribeGroupsResponse, err error) {
+	err = wc.processor(func(ctx context.Context, msgs Messages, c *cmd) error {
+		logCmd(wc.logMode, c, "DescribeGroups")
+		res, err = wc.cc.DescribeGroups(ctx, req)
+		return err
+	})(ctx, nil, &cmd{})
+	return
+}
+
+func (wc *Client) OffsetCommit(ctx context.Context, req *kafka.OffsetCommitRequest) (res *kafka.OffsetCommitResponse, err error) {
+	err = wc.processor(func(ctx context.Context, msgs Messages, c *cmd) error {
+		logCmd(wc.logMode, c, "OffsetCommit")
+		res, err = wc.cc.OffsetCommit(ctx, req)
+		return err
+	})(ctx, nil, &cmd{})
+	return
+}
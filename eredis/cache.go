@@ -0,0 +1,251 @@
+package eredis
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gotomicro/ego/core/elog"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// cacheHeaderSize 缓存值头部：1 字节标记 + 4 字节加载耗时(ms) + 8 字节过期时间(unix ms)
+	cacheHeaderSize   = 13
+	cacheFlagValue    = byte(0)
+	cacheFlagNotFound = byte(1)
+)
+
+// Cache is a typed cache-aside helper on top of a Component.
+// Concurrent misses of the same key are de-duplicated, values are refreshed early with the probabilistic
+// XFetch algorithm to avoid stampedes when hot keys expire.
+type Cache[T any] struct {
+	client redis.Cmdable
+	logger *elog.Component
+	opt    *cacheOption
+	group  singleflight.Group
+}
+
+// NewCache creates a Cache storing values of type T in the component.
+func NewCache[T any](comp *Component, opts ...CacheOption) *Cache[T] {
+	opt := &cacheOption{
+		codec:            JSONCodec{},
+		ttl:              time.Minute,
+		earlyRefreshBeta: 1,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return &Cache[T]{
+		client: comp.client,
+		logger: comp.logger,
+		opt:    opt,
+	}
+}
+
+// GetOrLoad returns the cached value of key, on miss the value is loaded by loader and cached.
+// If loader returns ErrNotFound and negative caching is enabled, ErrNotFound is cached as well.
+func (c *Cache[T]) GetOrLoad(ctx context.Context, key string, loader func(ctx context.Context) (T, error)) (T, error) {
+	key = c.opt.keyPrefix + key
+	entry, err := c.get(ctx, key)
+	if err != nil {
+		return c.load(ctx, key, loader)
+	}
+	if c.shouldRefresh(entry) {
+		// 提前刷新失败时，继续使用尚未过期的缓存值
+		if val, err := c.load(ctx, key, loader); err == nil || errors.Is(err, ErrNotFound) {
+			return val, err
+		}
+	}
+	val, err := c.decode(entry)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		// 缓存内容无法解码，例如结构体变更，重新加载
+		return c.load(ctx, key, loader)
+	}
+	return val, err
+}
+
+// Get returns the cached value of key, returns Nil if key does not exist.
+func (c *Cache[T]) Get(ctx context.Context, key string) (T, error) {
+	entry, err := c.get(ctx, c.opt.keyPrefix+key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return c.decode(entry)
+}
+
+// Set caches the value of key.
+func (c *Cache[T]) Set(ctx context.Context, key string, val T) error {
+	data, err := c.opt.codec.Marshal(val)
+	if err != nil {
+		return err
+	}
+	return c.set(ctx, c.opt.keyPrefix+key, cacheFlagValue, data, 0, c.ttl(c.opt.ttl))
+}
+
+// Delete removes the cached value of key.
+func (c *Cache[T]) Delete(ctx context.Context, key string) error {
+	return c.client.Del(ctx, c.opt.keyPrefix+key).Err()
+}
+
+func (c *Cache[T]) load(ctx context.Context, key string, loader func(ctx context.Context) (T, error)) (T, error) {
+	res, err, _ := c.group.Do(key, func() (interface{}, error) {
+		start := time.Now()
+		val, err := loader(ctx)
+		delta := time.Since(start)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) && c.opt.negativeTTL > 0 {
+				if err := c.set(ctx, key, cacheFlagNotFound, nil, delta, c.ttl(c.opt.negativeTTL)); err != nil {
+					c.logger.Warn("cache set not found fail", elog.FieldKey(key), elog.FieldErr(err))
+				}
+			}
+			return val, err
+		}
+
+		data, err := c.opt.codec.Marshal(val)
+		if err != nil {
+			return val, err
+		}
+		if err := c.set(ctx, key, cacheFlagValue, data, delta, c.ttl(c.opt.ttl)); err != nil {
+			c.logger.Warn("cache set fail", elog.FieldKey(key), elog.FieldErr(err))
+		}
+		return val, nil
+	})
+	val, _ := res.(T)
+	return val, err
+}
+
+type cacheEntry struct {
+	flag     byte
+	delta    time.Duration
+	expireAt time.Time
+	data     []byte
+}
+
+func (c *Cache[T]) get(ctx context.Context, key string) (*cacheEntry, error) {
+	b, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.logger.Warn("cache get fail", elog.FieldKey(key), elog.FieldErr(err))
+		}
+		return nil, err
+	}
+	if len(b) < cacheHeaderSize {
+		return nil, Nil
+	}
+	return &cacheEntry{
+		flag:     b[0],
+		delta:    time.Duration(binary.BigEndian.Uint32(b[1:5])) * time.Millisecond,
+		expireAt: time.Unix(0, int64(binary.BigEndian.Uint64(b[5:13]))*int64(time.Millisecond)),
+		data:     b[cacheHeaderSize:],
+	}, nil
+}
+
+func (c *Cache[T]) set(ctx context.Context, key string, flag byte, data []byte, delta, ttl time.Duration) error {
+	b := make([]byte, cacheHeaderSize+len(data))
+	b[0] = flag
+	binary.BigEndian.PutUint32(b[1:5], uint32(delta/time.Millisecond))
+	binary.BigEndian.PutUint64(b[5:13], uint64(time.Now().Add(ttl).UnixNano()/int64(time.Millisecond)))
+	copy(b[cacheHeaderSize:], data)
+	return c.client.Set(ctx, key, b, ttl).Err()
+}
+
+func (c *Cache[T]) decode(entry *cacheEntry) (T, error) {
+	var val T
+	if entry.flag == cacheFlagNotFound {
+		return val, ErrNotFound
+	}
+	err := c.opt.codec.Unmarshal(entry.data, &val)
+	return val, err
+}
+
+// shouldRefresh implements XFetch: refresh when now - delta*beta*ln(rand()) >= expiry.
+// See "Optimal Probabilistic Cache Stampede Prevention", Vattani et al.
+func (c *Cache[T]) shouldRefresh(entry *cacheEntry) bool {
+	if c.opt.earlyRefreshBeta <= 0 || entry.delta <= 0 {
+		return false
+	}
+	r := rand.Float64()
+	if r == 0 {
+		return true
+	}
+	gap := time.Duration(-float64(entry.delta) * c.opt.earlyRefreshBeta * math.Log(r))
+	return !time.Now().Add(gap).Before(entry.expireAt)
+}
+
+// ttl adds a random jitter to ttl, so that keys written together do not expire together.
+func (c *Cache[T]) ttl(ttl time.Duration) time.Duration {
+	if c.opt.ttlJitter <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Float64()*c.opt.ttlJitter*float64(ttl))
+}
+
+type CacheOption func(c *cacheOption)
+
+// cacheOption describes the options for the Cache
+type cacheOption struct {
+	// codec encodes values stored in redis.
+	// Default: JSONCodec
+	codec Codec
+
+	// ttl of cached values.
+	// Default: 1m
+	ttl time.Duration
+
+	// negativeTTL caches ErrNotFound returned by loader for the given ttl.
+	// Default: 0, not cached
+	negativeTTL time.Duration
+
+	// ttlJitter adds a random [0, ttlJitter*ttl) duration to ttl.
+	// Default: 0
+	ttlJitter float64
+
+	// earlyRefreshBeta controls the probability of refreshing before expiration, larger values refresh earlier.
+	// Default: 1, 0 disables early refresh
+	earlyRefreshBeta float64
+
+	// keyPrefix is prepended to every key.
+	keyPrefix string
+}
+
+func WithCacheOptionCodec(codec Codec) CacheOption {
+	return func(co *cacheOption) {
+		co.codec = codec
+	}
+}
+
+func WithCacheOptionTTL(ttl time.Duration) CacheOption {
+	return func(co *cacheOption) {
+		co.ttl = ttl
+	}
+}
+
+func WithCacheOptionNegativeTTL(ttl time.Duration) CacheOption {
+	return func(co *cacheOption) {
+		co.negativeTTL = ttl
+	}
+}
+
+func WithCacheOptionTTLJitter(jitter float64) CacheOption {
+	return func(co *cacheOption) {
+		co.ttlJitter = jitter
+	}
+}
+
+func WithCacheOptionEarlyRefreshBeta(beta float64) CacheOption {
+	return func(co *cacheOption) {
+		co.earlyRefreshBeta = beta
+	}
+}
+
+func WithCacheOptionKeyPrefix(prefix string) CacheOption {
+	return func(co *cacheOption) {
+		co.keyPrefix = prefix
+	}
+}
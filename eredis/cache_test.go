@@ -0,0 +1,91 @@
+package eredis
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type cacheUser struct {
+	ID   int    `json:"id" msgpack:"id"`
+	Name string `json:"name" msgpack:"name"`
+}
+
+func TestCodec(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec{}, MsgpackCodec{}} {
+		data, err := codec.Marshal(cacheUser{ID: 1, Name: "ego"})
+		assert.NoError(t, err)
+		var user cacheUser
+		assert.NoError(t, codec.Unmarshal(data, &user))
+		assert.Equal(t, cacheUser{ID: 1, Name: "ego"}, user)
+	}
+
+	data, err := ProtoCodec{}.Marshal(wrapperspb.String("ego"))
+	assert.NoError(t, err)
+	var msg *wrapperspb.StringValue
+	assert.NoError(t, ProtoCodec{}.Unmarshal(data, &msg))
+	assert.Equal(t, "ego", msg.GetValue())
+}
+
+func TestCacheShouldRefresh(t *testing.T) {
+	cache := &Cache[string]{opt: &cacheOption{earlyRefreshBeta: 1}}
+	// far from expiration
+	assert.False(t, cache.shouldRefresh(&cacheEntry{delta: time.Millisecond, expireAt: time.Now().Add(time.Hour)}))
+	// already expired
+	assert.True(t, cache.shouldRefresh(&cacheEntry{delta: time.Millisecond, expireAt: time.Now().Add(-time.Second)}))
+
+	cache.opt.earlyRefreshBeta = 0
+	assert.False(t, cache.shouldRefresh(&cacheEntry{delta: time.Millisecond, expireAt: time.Now().Add(-time.Second)}))
+}
+
+func TestCacheTTLJitter(t *testing.T) {
+	cache := &Cache[string]{opt: &cacheOption{ttlJitter: 0.1}}
+	for i := 0; i < 100; i++ {
+		ttl := cache.ttl(time.Minute)
+		assert.True(t, ttl >= time.Minute && ttl < time.Minute+6*time.Second)
+	}
+}
+
+func TestCacheGetOrLoad(t *testing.T) {
+	cmp := newCmpLock(t)
+	ctx := context.Background()
+	cache := NewCache[cacheUser](cmp, WithCacheOptionKeyPrefix("test:cache:"), WithCacheOptionNegativeTTL(time.Second))
+	_ = cache.Delete(ctx, "1")
+	_ = cache.Delete(ctx, "2")
+
+	var loads int32
+	loader := func(ctx context.Context) (cacheUser, error) {
+		atomic.AddInt32(&loads, 1)
+		time.Sleep(50 * time.Millisecond)
+		return cacheUser{ID: 1, Name: "ego"}, nil
+	}
+	// concurrent misses are de-duplicated
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := cache.GetOrLoad(ctx, "1", loader)
+			assert.NoError(t, err)
+			assert.Equal(t, "ego", user.Name)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	// not found is cached
+	notFound := func(ctx context.Context) (cacheUser, error) {
+		atomic.AddInt32(&loads, 1)
+		return cacheUser{}, ErrNotFound
+	}
+	_, err := cache.GetOrLoad(ctx, "2", notFound)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = cache.GetOrLoad(ctx, "2", notFound)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loads))
+}
@@ -0,0 +1,322 @@
+package eredis
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+)
+
+// invalidateChannel redis 在 RESP2 协议下通过该频道推送 CLIENT TRACKING 失效通知
+const invalidateChannel = "__redis__:invalidate"
+
+const (
+	clientCacheKindGet     = "get"
+	clientCacheKindHGetAll = "hgetall"
+)
+
+// clientCache is an in-process near cache kept coherent by CLIENT TRACKING invalidation messages.
+// go-redis v8 speaks RESP2 only, so tracking uses the REDIRECT mode: a dedicated connection subscribes
+// to __redis__:invalidate, and every reader connection redirects its invalidations to that connection.
+type clientCache struct {
+	name     string
+	config   *config
+	logger   *elog.Component
+	lru      *lruCache
+	prefixes []string
+	// newClient builds a redis client with the given OnConnect callback, withHooks controls whether interceptors are added.
+	newClient func(onConnect func(ctx context.Context, cn *redis.Conn) error, withHooks bool) *redis.Client
+
+	tracking *redis.Client
+	pubSub   *redis.PubSub
+
+	mu         sync.RWMutex
+	reader     *redis.Client
+	trackingID int64
+
+	// seqMu guards seq, invalidations and the write of a loaded value are done under it,
+	// so a value can not be cached after an invalidation that arrived while it was read.
+	seqMu sync.Mutex
+	// seq is increased by every invalidation and reconnection, a value read while seq changed is not cached.
+	seq uint64
+}
+
+func newClientCache(name string, config *config, logger *elog.Component, newClient func(onConnect func(ctx context.Context, cn *redis.Conn) error, withHooks bool) *redis.Client) *clientCache {
+	c := &clientCache{
+		name:      name,
+		config:    config,
+		logger:    logger,
+		lru:       newLRUCache(config.ClientCacheSize),
+		prefixes:  config.ClientCachePrefixes,
+		newClient: newClient,
+	}
+
+	c.tracking = newClient(c.onTrackingConnect, false)
+	c.pubSub = c.tracking.Subscribe(context.Background(), invalidateChannel)
+	// 确保订阅连接已建立，此时 trackingID 已经就绪
+	if _, err := c.pubSub.Receive(context.Background()); err != nil {
+		c.logger.Error("client cache subscribe invalidation fail", elog.FieldErr(err))
+	}
+	c.reader = c.newReader()
+	go c.watch(c.pubSub.Channel())
+	return c
+}
+
+// Close closes the invalidation subscription and the tracking and reader connections.
+func (c *clientCache) Close() error {
+	err := c.pubSub.Close()
+	if e := c.tracking.Close(); err == nil {
+		err = e
+	}
+	c.mu.RLock()
+	reader := c.reader
+	c.mu.RUnlock()
+	if e := reader.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// onTrackingConnect records the id of the invalidation connection.
+// A reconnection means invalidations may have been lost, so the cache is flushed and reader connections are rebuilt.
+func (c *clientCache) onTrackingConnect(ctx context.Context, cn *redis.Conn) error {
+	id, err := cn.ClientID(ctx).Result()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	old := c.trackingID
+	c.trackingID = id
+	initialized := c.reader != nil
+	c.mu.Unlock()
+	if !initialized {
+		return nil
+	}
+
+	c.logger.Warn("client cache tracking reconnected, flush cache", elog.Int64("oldID", old), elog.Int64("newID", id))
+	c.invalidate(func() {
+		c.lru.Flush()
+	})
+	c.mu.Lock()
+	oldReader := c.reader
+	c.reader = c.newReader()
+	c.mu.Unlock()
+	if oldReader != nil {
+		// 等待进行中的请求结束后再关闭
+		time.AfterFunc(c.config.ReadTimeout+c.config.WriteTimeout, func() {
+			_ = oldReader.Close()
+		})
+	}
+	return nil
+}
+
+func (c *clientCache) newReader() *redis.Client {
+	return c.newClient(func(ctx context.Context, cn *redis.Conn) error {
+		c.mu.RLock()
+		id := c.trackingID
+		c.mu.RUnlock()
+		// 失效通知连接尚未建立，load 不会缓存读取结果
+		if id == 0 {
+			return nil
+		}
+		return cn.Process(ctx, redis.NewStatusCmd(ctx, "client", "tracking", "on", "redirect", id))
+	}, true)
+}
+
+func (c *clientCache) watch(ch <-chan *redis.Message) {
+	for msg := range ch {
+		c.invalidate(func() {
+			if len(msg.PayloadSlice) == 0 && msg.Payload == "" {
+				c.lru.Flush()
+				return
+			}
+			keys := msg.PayloadSlice
+			if msg.Payload != "" {
+				keys = []string{msg.Payload}
+			}
+			for _, key := range keys {
+				c.lru.Delete(clientCacheKindGet + ":" + key)
+				c.lru.Delete(clientCacheKindHGetAll + ":" + key)
+			}
+		})
+	}
+}
+
+// invalidate increases seq and removes entries in the same critical section as the write in load.
+func (c *clientCache) invalidate(remove func()) {
+	c.seqMu.Lock()
+	defer c.seqMu.Unlock()
+	c.seq++
+	remove()
+}
+
+// match reports whether the key is served by the client cache, it is safe to call on a nil clientCache.
+func (c *clientCache) match(key string) bool {
+	if c == nil {
+		return false
+	}
+	if len(c.prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the string value of key from the client cache or redis.
+func (c *clientCache) Get(ctx context.Context, key string) (string, error) {
+	val, err := c.load(ctx, clientCacheKindGet, key, func(reader *redis.Client) (interface{}, error) {
+		return reader.Get(ctx, key).Result()
+	})
+	if err != nil {
+		return "", err
+	}
+	return val.(string), nil
+}
+
+// HGetAll returns all fields of hash key from the client cache or redis.
+func (c *clientCache) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	val, err := c.load(ctx, clientCacheKindHGetAll, key, func(reader *redis.Client) (interface{}, error) {
+		return reader.HGetAll(ctx, key).Result()
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 返回副本，避免调用方修改缓存内容
+	hash := val.(map[string]string)
+	res := make(map[string]string, len(hash))
+	for k, v := range hash {
+		res[k] = v
+	}
+	return res, nil
+}
+
+func (c *clientCache) load(ctx context.Context, kind, key string, fetch func(reader *redis.Client) (interface{}, error)) (interface{}, error) {
+	cacheKey := kind + ":" + key
+	if val, ok := c.lru.Get(cacheKey); ok {
+		c.metric(kind, "CacheHit")
+		return val, nil
+	}
+	c.metric(kind, "CacheMiss")
+
+	c.seqMu.Lock()
+	seq := c.seq
+	c.seqMu.Unlock()
+	c.mu.RLock()
+	reader, trackingID := c.reader, c.trackingID
+	c.mu.RUnlock()
+	val, err := fetch(reader)
+	if err != nil {
+		return nil, err
+	}
+	// 读取期间收到失效通知，无法判断读到的值是否已过期，本次不缓存
+	c.seqMu.Lock()
+	if trackingID != 0 && c.seq == seq {
+		c.lru.Set(cacheKey, val, c.config.ClientCacheTTL)
+	}
+	c.seqMu.Unlock()
+	return val, nil
+}
+
+func (c *clientCache) metric(method, code string) {
+	if c.config.EnableMetricInterceptor {
+		emetric.ClientHandleCounter.Inc(emetric.TypeRedis, c.name, method, c.config.AddrString(), code)
+	}
+}
+
+// lruCache is a size-bounded LRU cache whose entries expire after a TTL.
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key      string
+	value    interface{}
+	expireAt time.Time
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value of key if it exists and has not expired.
+func (l *lruCache) Get(key string) (interface{}, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !entry.expireAt.IsZero() && time.Now().After(entry.expireAt) {
+		l.removeElement(elem)
+		return nil, false
+	}
+	l.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set adds or updates the value of key, the oldest entry is evicted when the cache is full.
+func (l *lruCache) Set(key string, value interface{}, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+	if elem, ok := l.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.expireAt = expireAt
+		l.ll.MoveToFront(elem)
+		return
+	}
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, value: value, expireAt: expireAt})
+	if l.size > 0 && l.ll.Len() > l.size {
+		l.removeElement(l.ll.Back())
+	}
+}
+
+// Delete removes key from the cache.
+func (l *lruCache) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if elem, ok := l.items[key]; ok {
+		l.removeElement(elem)
+	}
+}
+
+// Flush removes all entries.
+func (l *lruCache) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ll.Init()
+	l.items = make(map[string]*list.Element)
+}
+
+// Len returns the number of entries.
+func (l *lruCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ll.Len()
+}
+
+func (l *lruCache) removeElement(elem *list.Element) {
+	l.ll.Remove(elem)
+	delete(l.items, elem.Value.(*lruEntry).key)
+}
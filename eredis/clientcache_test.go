@@ -0,0 +1,71 @@
+package eredis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := newLRUCache(2)
+	cache.Set("a", "1", 0)
+	cache.Set("b", "2", 0)
+
+	// touch a, b becomes the oldest entry
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+	cache.Set("c", "3", 0)
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, cache.Len())
+
+	cache.Set("d", "4", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	_, ok = cache.Get("d")
+	assert.False(t, ok)
+
+	cache.Delete("a")
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+
+	cache.Flush()
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestClientCacheMatch(t *testing.T) {
+	var cache *clientCache
+	assert.False(t, cache.match("user:1"))
+
+	cache = &clientCache{prefixes: []string{"user:"}}
+	assert.True(t, cache.match("user:1"))
+	assert.False(t, cache.match("order:1"))
+}
+
+func TestClientCacheLoadInvalidated(t *testing.T) {
+	cache := &clientCache{config: &config{ClientCacheTTL: time.Minute}, lru: newLRUCache(10), trackingID: 1}
+	fetch := func(val string, invalidate bool) func(reader *redis.Client) (interface{}, error) {
+		return func(reader *redis.Client) (interface{}, error) {
+			if invalidate {
+				// the key changes while it is being read
+				cache.invalidate(func() {
+					cache.lru.Delete(clientCacheKindGet + ":user:1")
+				})
+			}
+			return val, nil
+		}
+	}
+
+	val, err := cache.load(context.Background(), clientCacheKindGet, "user:1", fetch("1", true))
+	assert.NoError(t, err)
+	assert.Equal(t, "1", val)
+	assert.Equal(t, 0, cache.lru.Len())
+
+	_, err = cache.load(context.Background(), clientCacheKindGet, "user:1", fetch("2", false))
+	assert.NoError(t, err)
+	val, err = cache.load(context.Background(), clientCacheKindGet, "user:1", fetch("3", false))
+	assert.NoError(t, err)
+	assert.Equal(t, "2", val)
+}
@@ -13,9 +13,15 @@ func (r *Component) Ping(ctx context.Context) (string, error) {
 	return r.client.Ping(ctx).Result()
 }
 
-// Get
+// Get 开启客户端缓存后优先读取进程内缓存
 func (r *Component) Get(ctx context.Context, key string) (string, error) {
-	reply, err := r.client.Get(ctx, key).Result()
+	var reply string
+	var err error
+	if r.clientCache.match(key) {
+		reply, err = r.clientCache.Get(ctx, key)
+	} else {
+		reply, err = r.reader(ctx).Get(ctx, key).Result()
+	}
 	if err != nil {
 		return reply, fmt.Errorf("eredis get string error %w", err)
 	}
@@ -33,7 +39,7 @@ func (r *Component) GetEx(ctx context.Context, key string, expire time.Duration)
 
 // GetBytes
 func (r *Component) GetBytes(ctx context.Context, key string) ([]byte, error) {
-	c, err := r.client.Get(ctx, key).Bytes()
+	c, err := r.reader(ctx).Get(ctx, key).Bytes()
 	if err != nil {
 		return c, fmt.Errorf("eredis get bytes error %w", err)
 	}
@@ -42,7 +48,7 @@ func (r *Component) GetBytes(ctx context.Context, key string) ([]byte, error) {
 
 // MGet ...
 func (r *Component) MGetString(ctx context.Context, keys ...string) ([]string, error) {
-	reply, err := r.client.MGet(ctx, keys...).Result()
+	reply, err := r.reader(ctx).MGet(ctx, keys...).Result()
 	if err != nil {
 		return []string{}, fmt.Errorf("eredis mgetstring error %w", err)
 	}
@@ -59,7 +65,7 @@ func (r *Component) MGetString(ctx context.Context, keys ...string) ([]string, e
 
 // MGets ...
 func (r *Component) MGet(ctx context.Context, keys []string) ([]interface{}, error) {
-	return r.client.MGet(ctx, keys...).Result()
+	return r.reader(ctx).MGet(ctx, keys...).Result()
 }
 
 // Set 设置redis的string
@@ -77,14 +83,17 @@ func (r *Component) SetNX(ctx context.Context, key string, value interface{}, ex
 	return r.client.SetNX(ctx, key, value, expire).Err()
 }
 
-// HGetAll 从redis获取hash的所有键值对
+// HGetAll 从redis获取hash的所有键值对，开启客户端缓存后优先读取进程内缓存
 func (r *Component) HGetAll(ctx context.Context, key string) (map[string]string, error) {
-	return r.client.HGetAll(ctx, key).Result()
+	if r.clientCache.match(key) {
+		return r.clientCache.HGetAll(ctx, key)
+	}
+	return r.reader(ctx).HGetAll(ctx, key).Result()
 }
 
 // HGet 从redis获取hash单个值
 func (r *Component) HGet(ctx context.Context, key string, fields string) (string, error) {
-	return r.client.HGet(ctx, key, fields).Result()
+	return r.reader(ctx).HGet(ctx, key, fields).Result()
 }
 
 // HMGetMap 批量获取hash值，返回map
@@ -92,7 +101,7 @@ func (r *Component) HMGetMap(ctx context.Context, key string, fields []string) (
 	if len(fields) == 0 {
 		return make(map[string]string), fmt.Errorf("eredis hmgetmap error %w", ErrInvalidParams)
 	}
-	reply, err := r.client.HMGet(ctx, key, fields...).Result()
+	reply, err := r.reader(ctx).HMGet(ctx, key, fields...).Result()
 	if err != nil {
 		return make(map[string]string), fmt.Errorf("eredis hmgetmap error %w", err)
 	}
@@ -167,57 +176,57 @@ func (r *Component) DecrBy(ctx context.Context, key string, decrement int64) (in
 
 // Type ...
 func (r *Component) Type(ctx context.Context, key string) (string, error) {
-	return r.client.Type(ctx, key).Result()
+	return r.reader(ctx).Type(ctx, key).Result()
 }
 
 // ZRevRange 倒序获取有序集合的部分数据
 func (r *Component) ZRevRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
-	return r.client.ZRevRange(ctx, key, start, stop).Result()
+	return r.reader(ctx).ZRevRange(ctx, key, start, stop).Result()
 }
 
 // ZRevRangeWithScores ...
 func (r *Component) ZRevRangeWithScores(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
-	return r.client.ZRevRangeWithScores(ctx, key, start, stop).Result()
+	return r.reader(ctx).ZRevRangeWithScores(ctx, key, start, stop).Result()
 }
 
 // ZRange ...
 func (r *Component) ZRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
-	return r.client.ZRange(ctx, key, start, stop).Result()
+	return r.reader(ctx).ZRange(ctx, key, start, stop).Result()
 }
 
 // ZRangeByScore ...
 func (r *Component) ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) ([]string, error) {
-	return r.client.ZRangeByScore(ctx, key, opt).Result()
+	return r.reader(ctx).ZRangeByScore(ctx, key, opt).Result()
 }
 
 // ZRangeWithScores ...
 func (r *Component) ZRangeWithScores(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
-	return r.client.ZRangeWithScores(ctx, key, start, stop).Result()
+	return r.reader(ctx).ZRangeWithScores(ctx, key, start, stop).Result()
 }
 
 // ZRangeByScoreWithScores ...
 func (r *Component) ZRangeByScoreWithScores(ctx context.Context, key string, opt *redis.ZRangeBy) ([]redis.Z, error) {
-	return r.client.ZRangeByScoreWithScores(ctx, key, opt).Result()
+	return r.reader(ctx).ZRangeByScoreWithScores(ctx, key, opt).Result()
 }
 
 // ZRevRank ...
 func (r *Component) ZRevRank(ctx context.Context, key string, member string) (int64, error) {
-	return r.client.ZRevRank(ctx, key, member).Result()
+	return r.reader(ctx).ZRevRank(ctx, key, member).Result()
 }
 
 // ZRevRangeByScore ...
 func (r *Component) ZRevRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) ([]string, error) {
-	return r.client.ZRevRangeByScore(ctx, key, opt).Result()
+	return r.reader(ctx).ZRevRangeByScore(ctx, key, opt).Result()
 }
 
 // ZRevRangeByScoreWithScores ...
 func (r *Component) ZRevRangeByScoreWithScores(ctx context.Context, key string, opt *redis.ZRangeBy) ([]redis.Z, error) {
-	return r.client.ZRevRangeByScoreWithScores(ctx, key, opt).Result()
+	return r.reader(ctx).ZRevRangeByScoreWithScores(ctx, key, opt).Result()
 }
 
 // HMGet 批量获取hash值
 func (r *Component) HMGetString(ctx context.Context, key string, fileds []string) ([]string, error) {
-	reply, err := r.client.HMGet(ctx, key, fileds...).Result()
+	reply, err := r.reader(ctx).HMGet(ctx, key, fileds...).Result()
 	if err != nil {
 		return []string{}, fmt.Errorf("hmgetstring err %w", err)
 	}
@@ -233,17 +242,17 @@ func (r *Component) HMGetString(ctx context.Context, key string, fileds []string
 }
 
 func (r *Component) HMGet(ctx context.Context, key string, fileds []string) ([]interface{}, error) {
-	return r.client.HMGet(ctx, key, fileds...).Result()
+	return r.reader(ctx).HMGet(ctx, key, fileds...).Result()
 }
 
 // ZCard 获取有序集合的基数
 func (r *Component) ZCard(ctx context.Context, key string) (int64, error) {
-	return r.client.ZCard(ctx, key).Result()
+	return r.reader(ctx).ZCard(ctx, key).Result()
 }
 
 // ZScore 获取有序集合成员 member 的 score 值
 func (r *Component) ZScore(ctx context.Context, key string, member string) (float64, error) {
-	return r.client.ZScore(ctx, key, member).Result()
+	return r.reader(ctx).ZScore(ctx, key, member).Result()
 }
 
 // ZAdd 将一个或多个 member 元素及其 score 值加入到有序集 key 当中
@@ -253,7 +262,7 @@ func (r *Component) ZAdd(ctx context.Context, key string, members ...*redis.Z) (
 
 // ZCount 返回有序集 key 中， score 值在 min 和 max 之间(默认包括 score 值等于 min 或 max )的成员的数量。
 func (r *Component) ZCount(ctx context.Context, key string, min, max string) (int64, error) {
-	return r.client.ZCount(ctx, key, min, max).Result()
+	return r.reader(ctx).ZCount(ctx, key, min, max).Result()
 }
 
 // Del redis删除
@@ -268,7 +277,7 @@ func (r *Component) HIncrBy(ctx context.Context, key string, field string, incr
 
 // Exists 键是否存在
 func (r *Component) Exists(ctx context.Context, key string) (bool, error) {
-	result, err := r.client.Exists(ctx, key).Result()
+	result, err := r.reader(ctx).Exists(ctx, key).Result()
 	if err != nil {
 		return result == 1, err
 	}
@@ -292,12 +301,12 @@ func (r *Component) RPop(ctx context.Context, key string) (string, error) {
 
 // LRange 获取列表指定范围内的元素
 func (r *Component) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
-	return r.client.LRange(ctx, key, start, stop).Result()
+	return r.reader(ctx).LRange(ctx, key, start, stop).Result()
 }
 
 // LLen ...
 func (r *Component) LLen(ctx context.Context, key string) (int64, error) {
-	return r.client.LLen(ctx, key).Result()
+	return r.reader(ctx).LLen(ctx, key).Result()
 }
 
 // LRem ...
@@ -307,7 +316,7 @@ func (r *Component) LRem(ctx context.Context, key string, count int64, value int
 
 // LIndex ...
 func (r *Component) LIndex(ctx context.Context, key string, idx int64) (string, error) {
-	return r.client.LIndex(ctx, key, idx).Result()
+	return r.reader(ctx).LIndex(ctx, key, idx).Result()
 }
 
 // LTrim ...
@@ -337,12 +346,12 @@ func (r *Component) SAdd(ctx context.Context, key string, member ...interface{})
 
 // SMembers 返回set的全部成员
 func (r *Component) SMembers(ctx context.Context, key string) ([]string, error) {
-	return r.client.SMembers(ctx, key).Result()
+	return r.reader(ctx).SMembers(ctx, key).Result()
 }
 
 // SIsMember ...
 func (r *Component) SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
-	return r.client.SIsMember(ctx, key, member).Result()
+	return r.reader(ctx).SIsMember(ctx, key, member).Result()
 }
 
 // SRem ...
@@ -352,12 +361,12 @@ func (r *Component) SRem(ctx context.Context, key string, member interface{}) (i
 
 // HKeys 获取hash的所有域
 func (r *Component) HKeys(ctx context.Context, key string) ([]string, error) {
-	return r.client.HKeys(ctx, key).Result()
+	return r.reader(ctx).HKeys(ctx, key).Result()
 }
 
 // HLen 获取hash的长度
 func (r *Component) HLen(ctx context.Context, key string) (int64, error) {
-	return r.client.HLen(ctx, key).Result()
+	return r.reader(ctx).HLen(ctx, key).Result()
 }
 
 // GeoAdd 写入地理位置
@@ -367,12 +376,67 @@ func (r *Component) GeoAdd(ctx context.Context, key string, location *redis.GeoL
 
 // GeoRadius 根据经纬度查询列表
 func (r *Component) GeoRadius(ctx context.Context, key string, longitude, latitude float64, query *redis.GeoRadiusQuery) ([]redis.GeoLocation, error) {
-	return r.client.GeoRadius(ctx, key, longitude, latitude, query).Result()
+	return r.reader(ctx).GeoRadius(ctx, key, longitude, latitude, query).Result()
 }
 
 // TTL 查询过期时间
 func (r *Component) TTL(ctx context.Context, key string) (time.Duration, error) {
-	return r.client.TTL(ctx, key).Result()
+	return r.reader(ctx).TTL(ctx, key).Result()
+}
+
+// XAdd 向 stream 追加消息，返回消息ID
+func (r *Component) XAdd(ctx context.Context, a *redis.XAddArgs) (string, error) {
+	return r.client.XAdd(ctx, a).Result()
+}
+
+// XLen 获取 stream 的消息数量
+func (r *Component) XLen(ctx context.Context, stream string) (int64, error) {
+	return r.reader(ctx).XLen(ctx, stream).Result()
+}
+
+// XDel 删除 stream 中的消息
+func (r *Component) XDel(ctx context.Context, stream string, ids ...string) (int64, error) {
+	return r.client.XDel(ctx, stream, ids...).Result()
+}
+
+// XGroupCreateMkStream 创建消费组，stream 不存在时自动创建
+func (r *Component) XGroupCreateMkStream(ctx context.Context, stream, group, start string) error {
+	return r.client.XGroupCreateMkStream(ctx, stream, group, start).Err()
+}
+
+// XReadGroup 以消费组的方式读取消息
+func (r *Component) XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) ([]redis.XStream, error) {
+	return r.client.XReadGroup(ctx, a).Result()
+}
+
+// XAck 确认消息已被消费
+func (r *Component) XAck(ctx context.Context, stream, group string, ids ...string) (int64, error) {
+	return r.client.XAck(ctx, stream, group, ids...).Result()
+}
+
+// XPending 获取消费组待确认消息的概要
+func (r *Component) XPending(ctx context.Context, stream, group string) (*redis.XPending, error) {
+	return r.client.XPending(ctx, stream, group).Result()
+}
+
+// XPendingExt 获取消费组待确认消息的明细
+func (r *Component) XPendingExt(ctx context.Context, a *redis.XPendingExtArgs) ([]redis.XPendingExt, error) {
+	return r.client.XPendingExt(ctx, a).Result()
+}
+
+// XClaim 转移空闲时间超过 MinIdle 的待确认消息的所有权
+func (r *Component) XClaim(ctx context.Context, a *redis.XClaimArgs) ([]redis.XMessage, error) {
+	return r.client.XClaim(ctx, a).Result()
+}
+
+// XAutoClaim 自动转移空闲时间超过 MinIdle 的待确认消息的所有权，返回消息及下一次扫描的起始ID。go-redis v8 无法解析 redis 7 的返回值，redis 7 请使用 XPendingExt 与 XClaim
+func (r *Component) XAutoClaim(ctx context.Context, a *redis.XAutoClaimArgs) ([]redis.XMessage, string, error) {
+	return r.client.XAutoClaim(ctx, a).Result()
+}
+
+// XTrimMaxLen 裁剪 stream，只保留最新的 maxLen 条消息
+func (r *Component) XTrimMaxLen(ctx context.Context, stream string, maxLen int64) (int64, error) {
+	return r.client.XTrimMaxLen(ctx, stream, maxLen).Result()
 }
 
 // Close closes the cluster client, releasing any open resources.
@@ -381,6 +445,12 @@ func (r *Component) TTL(ctx context.Context, key string) (time.Duration, error)
 // to be long-lived and shared between many goroutines.
 func (r *Component) Close() (err error) {
 	err = nil
+	if r.replicas != nil {
+		_ = r.replicas.Close()
+	}
+	if r.clientCache != nil {
+		_ = r.clientCache.Close()
+	}
 	if r.client != nil {
 		if r.Cluster() != nil {
 			err = r.Cluster().Close()
@@ -9,10 +9,12 @@ const PackageName = "component.eredis"
 
 // Component client (cmdable and config)
 type Component struct {
-	config     *config
-	client     redis.Cmdable
-	lockClient *lockClient
-	logger     *elog.Component
+	config      *config
+	client      redis.Cmdable
+	lockClient  *lockClient
+	clientCache *clientCache
+	replicas    *replicaRouter
+	logger      *elog.Component
 }
 
 // Client returns a universal redis client(ClusterClient, StubClient or SentinelClient), it depends on you config.
@@ -33,7 +33,11 @@ type config struct {
 	WriteTimeout               time.Duration // WriteTimeout 读超时 默认3s
 	IdleTimeout                time.Duration // IdleTimeout 连接最大空闲时间，默认60s, 超过该时间，连接会被主动关闭
 	Debug                      bool          // Debug开关， 是否开启调试，默认不开启，开启后并加上export EGO_DEBUG=true，可以看到每次请求，配置名、地址、耗时、请求数据、响应数据
-	ReadOnly                   bool          // ReadOnly 集群模式 在从属节点上启用读模式
+	ReadOnly                   bool          // ReadOnly 集群模式下在从节点上执行只读命令，由 go-redis 路由
+	EnableReplicaRouting       bool          // EnableReplicaRouting 哨兵模式以及配置了 Replicas 的 stub 模式下，由 eredis 将只读命令路由到从节点，默认关闭
+	Replicas                   []string      // Replicas stub 模式下的从节点地址，哨兵模式下从节点由哨兵发现
+	ReplicaPolicy              string        // ReplicaPolicy 从节点选择策略 random|latency，默认 random
+	ReplicaRefreshInterval     time.Duration // ReplicaRefreshInterval 探测从节点健康和延迟、从哨兵刷新从节点列表的间隔，默认 5s
 	SlowLogThreshold           time.Duration // 慢日志门限值，超过该门限值的请求，将被记录到慢日志中
 	OnFail                     string        // OnFail panic|error
 	EnableMetricInterceptor    bool          // 是否开启监控，默认开启
@@ -41,6 +45,10 @@ type config struct {
 	EnableAccessInterceptor    bool          // 是否开启，记录请求数据
 	EnableAccessInterceptorReq bool          // 是否开启记录请求参数
 	EnableAccessInterceptorRes bool          // 是否开启记录响应参数
+	EnableClientCache          bool          // 是否开启客户端缓存，Get、HGetAll 优先读取进程内缓存，通过 CLIENT TRACKING 失效通知保持一致，仅支持 stub、sentinel 模式，需要 redis 6.0+
+	ClientCacheSize            int           // 客户端缓存最大条目数，默认 10000
+	ClientCacheTTL             time.Duration // 客户端缓存过期时间，默认 1m
+	ClientCachePrefixes        []string      // 客户端缓存只缓存带有这些前缀的 key，默认缓存所有 key
 	interceptors               []redis.Hook
 }
 
@@ -57,11 +65,15 @@ func DefaultConfig() *config {
 		WriteTimeout:            xtime.Duration("1s"),
 		IdleTimeout:             xtime.Duration("60s"),
 		ReadOnly:                false,
+		ReplicaPolicy:           ReplicaPolicyRandom,
+		ReplicaRefreshInterval:  xtime.Duration("5s"),
 		Debug:                   false,
 		EnableMetricInterceptor: true,
 		EnableTraceInterceptor:  true,
 		SlowLogThreshold:        xtime.Duration("250ms"),
 		OnFail:                  "panic",
+		ClientCacheSize:         10000,
+		ClientCacheTTL:          xtime.Duration("1m"),
 	}
 }
 
@@ -82,13 +82,67 @@ func (c *Container) Build(options ...Option) *Component {
 		c.logger.Panic(`redis mode must be one of ("stub", "cluster", "sentinel")`)
 	}
 
+	var cache *clientCache
+	if c.config.EnableClientCache {
+		if c.config.Mode == ClusterMode {
+			c.logger.Warn("client cache is not supported in cluster mode, ignored")
+		} else {
+			cache = newClientCache(c.name, c.config, c.logger, c.buildClientCacheClient)
+		}
+	}
+
+	var replicas *replicaRouter
+	if c.config.EnableReplicaRouting {
+		if c.config.Mode == ClusterMode {
+			c.logger.Warn(`"enableReplicaRouting" is ignored in cluster mode, use "readOnly" instead`)
+		} else if c.config.Mode == StubMode && len(c.config.Replicas) == 0 {
+			c.logger.Warn(`"enableReplicaRouting" is ignored, "replicas" is empty but with stub mode`)
+		} else {
+			replicas = newReplicaRouter(c.config, c.logger, func(addr string) *redis.Client {
+				opts := c.stubOptions()
+				opts.Addr = addr
+				return redis.NewClient(opts)
+			})
+			client.(*redis.Client).AddHook(nodeInterceptor(replicas.master))
+		}
+	}
+
 	c.logger = c.logger.With(elog.FieldAddr(fmt.Sprintf("%s", c.config.Addrs)))
 	return &Component{
-		config:     c.config,
-		client:     client,
-		lockClient: &lockClient{client: client},
-		logger:     c.logger,
+		config:      c.config,
+		client:      client,
+		lockClient:  &lockClient{client: client},
+		clientCache: cache,
+		replicas:    replicas,
+		logger:      c.logger,
+	}
+}
+
+// buildClientCacheClient builds a stub or sentinel client used by the client cache.
+func (c *Container) buildClientCacheClient(onConnect func(ctx context.Context, cn *redis.Conn) error, withHooks bool) *redis.Client {
+	var client *redis.Client
+	if c.config.Mode == SentinelMode {
+		opts := c.sentinelOptions()
+		opts.OnConnect = onConnect
+		if !withHooks {
+			// 订阅连接不需要连接池，避免空闲连接触发 OnConnect
+			opts.PoolSize, opts.MinIdleConns = 1, 0
+		}
+		client = redis.NewFailoverClient(opts)
+	} else {
+		opts := c.stubOptions()
+		opts.OnConnect = onConnect
+		if !withHooks {
+			opts.PoolSize, opts.MinIdleConns = 1, 0
+		}
+		client = redis.NewClient(opts)
+	}
+	if withHooks {
+		for _, incpt := range c.config.interceptors {
+			client.AddHook(incpt)
+		}
 	}
+	return client
 }
 
 func (c *Container) buildCluster() *redis.ClusterClient {
@@ -122,19 +176,7 @@ func (c *Container) buildCluster() *redis.ClusterClient {
 }
 
 func (c *Container) buildSentinel() *redis.Client {
-	sentinelClient := redis.NewFailoverClient(&redis.FailoverOptions{
-		MasterName:    c.config.MasterName,
-		SentinelAddrs: c.config.Addrs,
-		Password:      c.config.Password,
-		DB:            c.config.DB,
-		MaxRetries:    c.config.MaxRetries,
-		DialTimeout:   c.config.DialTimeout,
-		ReadTimeout:   c.config.ReadTimeout,
-		WriteTimeout:  c.config.WriteTimeout,
-		PoolSize:      c.config.PoolSize,
-		MinIdleConns:  c.config.MinIdleConns,
-		IdleTimeout:   c.config.IdleTimeout,
-	})
+	sentinelClient := redis.NewFailoverClient(c.sentinelOptions())
 
 	for _, incpt := range c.config.interceptors {
 		sentinelClient.AddHook(incpt)
@@ -152,18 +194,7 @@ func (c *Container) buildSentinel() *redis.Client {
 }
 
 func (c *Container) buildStub() *redis.Client {
-	stubClient := redis.NewClient(&redis.Options{
-		Addr:         c.config.Addr,
-		Password:     c.config.Password,
-		DB:           c.config.DB,
-		MaxRetries:   c.config.MaxRetries,
-		DialTimeout:  c.config.DialTimeout,
-		ReadTimeout:  c.config.ReadTimeout,
-		WriteTimeout: c.config.WriteTimeout,
-		PoolSize:     c.config.PoolSize,
-		MinIdleConns: c.config.MinIdleConns,
-		IdleTimeout:  c.config.IdleTimeout,
-	})
+	stubClient := redis.NewClient(c.stubOptions())
 
 	for _, incpt := range c.config.interceptors {
 		stubClient.AddHook(incpt)
@@ -180,6 +211,37 @@ func (c *Container) buildStub() *redis.Client {
 	return stubClient
 }
 
+func (c *Container) sentinelOptions() *redis.FailoverOptions {
+	return &redis.FailoverOptions{
+		MasterName:    c.config.MasterName,
+		SentinelAddrs: c.config.Addrs,
+		Password:      c.config.Password,
+		DB:            c.config.DB,
+		MaxRetries:    c.config.MaxRetries,
+		DialTimeout:   c.config.DialTimeout,
+		ReadTimeout:   c.config.ReadTimeout,
+		WriteTimeout:  c.config.WriteTimeout,
+		PoolSize:      c.config.PoolSize,
+		MinIdleConns:  c.config.MinIdleConns,
+		IdleTimeout:   c.config.IdleTimeout,
+	}
+}
+
+func (c *Container) stubOptions() *redis.Options {
+	return &redis.Options{
+		Addr:         c.config.Addr,
+		Password:     c.config.Password,
+		DB:           c.config.DB,
+		MaxRetries:   c.config.MaxRetries,
+		DialTimeout:  c.config.DialTimeout,
+		ReadTimeout:  c.config.ReadTimeout,
+		WriteTimeout: c.config.WriteTimeout,
+		PoolSize:     c.config.PoolSize,
+		MinIdleConns: c.config.MinIdleConns,
+		IdleTimeout:  c.config.IdleTimeout,
+	}
+}
+
 func (c *Container) Printf(ctx context.Context, format string, v ...interface{}) {
 	c.logger.Errorf(format, v)
 }
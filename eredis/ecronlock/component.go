@@ -14,19 +14,22 @@ type Component struct {
 	config *Config
 	logger *elog.Component
 	client *eredis.Component
-	mutuex sync.RWMutex
+	// redLockClient 不为空时，使用 RedLock 加锁
+	redLockClient *eredis.RedLockClient
+	mutuex        sync.RWMutex
 }
 
-func newComponent(name string, config *Config, logger *elog.Component, client *eredis.Component) *Component {
+func newComponent(name string, config *Config, logger *elog.Component, client *eredis.Component, redLockClient *eredis.RedLockClient) *Component {
 	reg := &Component{
-		name:   name,
-		logger: logger,
-		config: config,
-		client: client,
+		name:          name,
+		logger:        logger,
+		config:        config,
+		client:        client,
+		redLockClient: redLockClient,
 	}
 	return reg
 }
 
 func (c *Component) NewLock(key string) ecron.Lock {
-	return newRedisLock(c.client, c.config.Prefix+key, c.logger)
+	return newRedisLock(c.client, c.redLockClient, c.config.Prefix+key, c.config.AutoRefresh, c.logger)
 }
@@ -6,7 +6,8 @@ import (
 
 // Config ...
 type Config struct {
-	Prefix string // 默认 "ecronlock:{appName}:"
+	Prefix      string // 默认 "ecronlock:{appName}:"
+	AutoRefresh bool   // 是否由看门狗自动续租，开启后 Refresh 只检查锁是否丢失，默认关闭
 }
 
 // DefaultConfig ...
@@ -14,6 +14,8 @@ type Container struct {
 	name   string
 	logger *elog.Component
 	client *eredis.Component
+	// redLockClient 不为空时，使用 RedLock 加锁
+	redLockClient *eredis.RedLockClient
 }
 
 func DefaultContainer() *Container {
@@ -40,8 +42,8 @@ func (c *Container) Build(options ...Option) *Component {
 	for _, option := range options {
 		option(c)
 	}
-	if c.client == nil {
+	if c.client == nil && c.redLockClient == nil {
 		c.logger.Panic("client redis nil", elog.FieldKey("use WithClient method"))
 	}
-	return newComponent(c.name, c.config, c.logger, c.client)
+	return newComponent(c.name, c.config, c.logger, c.client, c.redLockClient)
 }
@@ -9,9 +9,23 @@ func WithClient(client *eredis.Component) Option {
 	}
 }
 
+// WithRedLockClients Optional. 设置多个独立的 redis 实例，使用 RedLock 算法在多数派上加锁，设置后可不调用 WithClient
+func WithRedLockClients(clients ...*eredis.Component) Option {
+	return func(c *Container) {
+		c.redLockClient = eredis.NewRedLockClient(clients...)
+	}
+}
+
 //WithPrefix Optional. 设置 redis 锁的 Key 前缀
 func WithPrefix(prefix string) Option {
 	return func(c *Container) {
 		c.config.Prefix = prefix
 	}
 }
+
+// WithAutoRefresh Optional. 开启看门狗自动续租，不再依赖 ecron 调用 Refresh 续租
+func WithAutoRefresh() Option {
+	return func(c *Container) {
+		c.config.AutoRefresh = true
+	}
+}
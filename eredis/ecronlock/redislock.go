@@ -11,26 +11,33 @@ import (
 )
 
 type redisLock struct {
-	mutex  sync.RWMutex
-	client *eredis.Component
-	key    string
-	locker *eredis.Lock
-	logger *elog.Component
+	mutex         sync.RWMutex
+	client        *eredis.Component
+	redLockClient *eredis.RedLockClient
+	key           string
+	autoRefresh   bool
+	locker        eredis.Locker
+	logger        *elog.Component
 }
 
-func newRedisLock(client *eredis.Component, key string, logger *elog.Component) *redisLock {
+func newRedisLock(client *eredis.Component, redLockClient *eredis.RedLockClient, key string, autoRefresh bool, logger *elog.Component) *redisLock {
 	return &redisLock{
-		mutex:  sync.RWMutex{},
-		client: client,
-		key:    key,
-		locker: nil,
-		logger: logger,
+		mutex:         sync.RWMutex{},
+		client:        client,
+		redLockClient: redLockClient,
+		key:           key,
+		autoRefresh:   autoRefresh,
+		locker:        nil,
+		logger:        logger,
 	}
 }
 
 func (c *redisLock) Lock(ctx context.Context, ttl time.Duration) error {
-	locker := c.client.LockClient()
-	lock, err := locker.Obtain(ctx, c.key, ttl, eredis.WithLockOptionRetryStrategy(eredis.LinearBackoffRetry(ttl)))
+	opts := []eredis.LockOption{eredis.WithLockOptionRetryStrategy(eredis.LinearBackoffRetry(ttl))}
+	if c.autoRefresh {
+		opts = append(opts, eredis.WithLockOptionAutoRefresh())
+	}
+	lock, err := c.obtain(ctx, ttl, opts...)
 	if err != nil {
 		return err
 	}
@@ -40,6 +47,21 @@ func (c *redisLock) Lock(ctx context.Context, ttl time.Duration) error {
 	return nil
 }
 
+func (c *redisLock) obtain(ctx context.Context, ttl time.Duration, opts ...eredis.LockOption) (eredis.Locker, error) {
+	if c.redLockClient != nil {
+		lock, err := c.redLockClient.Obtain(ctx, c.key, ttl, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return lock, nil
+	}
+	lock, err := c.client.LockClient().Obtain(ctx, c.key, ttl, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
 func (c *redisLock) Unlock(ctx context.Context) error {
 	c.mutex.RLock()
 	locker := c.locker
@@ -48,7 +70,7 @@ func (c *redisLock) Unlock(ctx context.Context) error {
 		return nil
 	}
 
-	err := c.locker.Release(ctx)
+	err := locker.Release(ctx)
 	if err != nil {
 		c.logger.Warn("cron unlock warning", elog.FieldErr(err))
 	}
@@ -63,5 +85,14 @@ func (c *redisLock) Refresh(ctx context.Context, ttl time.Duration) error {
 		return nil
 	}
 
+	// 看门狗已经在续租，这里只需要检查锁是否丢失
+	if c.autoRefresh {
+		select {
+		case <-locker.Lost():
+			return eredis.ErrNotObtained
+		default:
+			return nil
+		}
+	}
 	return locker.Refresh(ctx, ttl)
 }
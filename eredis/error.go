@@ -16,6 +16,9 @@ const (
 	// ErrLockNotHeld is returned when trying to release an inactive Lock.
 	ErrLockNotHeld = Err("redislock: lock not held")
 
+	// ErrNotFound is returned by Cache loaders when the value does not exist, it can be cached as a negative result.
+	ErrNotFound = Err("eredis: not found")
+
 	//Nil reply returned by Redis when key does not exist.
 	Nil = redis.Nil
 )
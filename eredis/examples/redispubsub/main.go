@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gotomicro/ego"
+	"github.com/gotomicro/ego/core/elog"
+
+	"github.com/gotomicro/ego-component/eredis"
+	"github.com/gotomicro/ego-component/eredis/pubsubserver"
+)
+
+// export EGO_DEBUG=true && go run main.go --config=config.toml
+func main() {
+	err := ego.New().Serve(pubSubServer()).Run()
+	if err != nil {
+		elog.Panic("startup", elog.FieldErr(err))
+	}
+}
+
+func pubSubServer() *pubsubserver.Component {
+	client := eredis.Load("redis.test").Build()
+	cmp := pubsubserver.Load("redisPubSubServer.test").Build(pubsubserver.WithEredis(client))
+	// 连接断开后会自动重新订阅，断开期间发布的消息会丢失
+	_ = cmp.OnEachMessage(func(ctx context.Context, message *redis.Message) error {
+		log.Println("got a message", message.Channel, message.Pattern, message.Payload)
+		return nil
+	})
+	return cmp
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gotomicro/ego"
+	"github.com/gotomicro/ego/core/elog"
+
+	"github.com/gotomicro/ego-component/eredis"
+	"github.com/gotomicro/ego-component/eredis/streamserver"
+)
+
+// export EGO_DEBUG=true && go run main.go --config=config.toml
+func main() {
+	err := ego.New().Serve(streamServer()).Run()
+	if err != nil {
+		elog.Panic("startup", elog.FieldErr(err))
+	}
+}
+
+func streamServer() *streamserver.Component {
+	client := eredis.Load("redis.test").Build()
+	cmp := streamserver.Load("redisStreamServer.test").Build(streamserver.WithEredis(client))
+	// 返回 nil 时消息会被确认，返回错误时消息会在 claimMinIdle 之后被重新认领
+	_ = cmp.OnEachMessage(func(ctx context.Context, stream string, message redis.XMessage) error {
+		log.Println("got a message", stream, message.ID, message.Values)
+		return nil
+	})
+	return cmp
+}
@@ -162,6 +162,11 @@ func accessInterceptor(compName string, config *config, logger *elog.Component)
 				elog.FieldMethod(cmd.Name()),
 				elog.FieldCost(cost))
 
+			// 开启从节点路由后，记录执行命令的节点
+			if node, ok := ctx.Value(ctxNodeKey).(string); ok && node != "" {
+				fields = append(fields, elog.FieldAddr(node))
+			}
+
 			if config.EnableAccessInterceptorReq {
 				fields = append(fields, elog.Any("req", cmd.Args()))
 			}
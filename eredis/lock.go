@@ -41,23 +41,38 @@ func (c *lockClient) Obtain(ctx context.Context, key string, ttl time.Duration,
 	}
 
 	value := token + opt.metadata
-	retry := opt.retryStrategy
+	err = retryObtain(ctx, ttl, opt.retryStrategy, func(ctx context.Context) (bool, error) {
+		return c.obtain(ctx, key, value, ttl)
+	})
+	if err != nil {
+		return nil, err
+	}
+	lock := &Lock{client: c, key: key, value: value}
+	if opt.autoRefresh {
+		lock.watchdog = newWatchdog(ttl, func(ctx context.Context) error {
+			return lock.Refresh(ctx, ttl)
+		})
+	}
+	return lock, nil
+}
 
+// retryObtain calls obtain until it succeeds, the retry strategy gives up or the ttl is exhausted.
+func retryObtain(ctx context.Context, ttl time.Duration, retry RetryStrategy, obtain func(ctx context.Context) (bool, error)) error {
 	deadlineCtx, cancel := context.WithDeadline(ctx, time.Now().Add(ttl))
 	defer cancel()
 
 	var timer *time.Timer
 	for {
-		ok, err := c.obtain(deadlineCtx, key, value, ttl)
+		ok, err := obtain(deadlineCtx)
 		if err != nil {
-			return nil, err
+			return err
 		} else if ok {
-			return &Lock{client: c, key: key, value: value}, nil
+			return nil
 		}
 
 		backoff := retry.NextBackoff()
 		if backoff < 1 {
-			return nil, ErrNotObtained
+			return ErrNotObtained
 		}
 
 		if timer == nil {
@@ -69,7 +84,7 @@ func (c *lockClient) Obtain(ctx context.Context, key string, ttl time.Duration,
 
 		select {
 		case <-deadlineCtx.Done():
-			return nil, ErrNotObtained
+			return ErrNotObtained
 		case <-timer.C:
 		}
 	}
@@ -95,9 +110,10 @@ func (c *lockClient) randomToken() (string, error) {
 
 // Lock represents an obtained, distributed Lock.
 type Lock struct {
-	client *lockClient
-	key    string
-	value  string
+	client   *lockClient
+	key      string
+	value    string
+	watchdog *watchdog
 }
 
 // Key returns the redis key used by the Lock.
@@ -115,6 +131,12 @@ func (l *Lock) Metadata() string {
 	return l.value[22:]
 }
 
+// Lost returns a channel which is closed when the automatic refresh fails.
+// Returns nil if WithLockOptionAutoRefresh is not used.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.watchdog.Lost()
+}
+
 // TTL returns the remaining time-to-live. Returns 0 if the Lock has expired.
 func (l *Lock) TTL(ctx context.Context) (time.Duration, error) {
 	res, err := luaPTTL.Run(ctx, l.client.client, []string{l.key}, l.value).Result()
@@ -143,9 +165,10 @@ func (l *Lock) Refresh(ctx context.Context, ttl time.Duration, opts ...LockOptio
 	return ErrNotObtained
 }
 
-// Release manually releases the Lock.
+// Release manually releases the Lock, the automatic refresh is stopped.
 // May return ErrLockNotHeld.
 func (l *Lock) Release(ctx context.Context) error {
+	l.watchdog.Stop()
 	res, err := luaRelease.Run(ctx, l.client.client, []string{l.key}, l.value).Result()
 	if err == redis.Nil {
 		return ErrLockNotHeld
@@ -169,6 +192,18 @@ type lockOption struct {
 
 	// metadata string is appended to the Lock token.
 	metadata string
+
+	// owner identifies the holder of a ReentrantLock, obtaining again with the same owner re-enters the Lock.
+	// Default: a random token
+	owner string
+
+	// autoRefresh starts a watchdog which refreshes the Lock every ttl/3 until it is released.
+	// Default: false
+	autoRefresh bool
+
+	// nodeTimeout limits the time spent on each redis instance, only used by RedLock.
+	// Default: ttl/10
+	nodeTimeout time.Duration
 }
 
 func WithLockOptionMetadata(md string) LockOption {
@@ -182,3 +217,26 @@ func WithLockOptionRetryStrategy(retryStrategy RetryStrategy) LockOption {
 		lo.retryStrategy = retryStrategy
 	}
 }
+
+// WithLockOptionAutoRefresh refreshes the Lock in background every ttl/3 until it is released,
+// use Lost to get notified when the refresh fails.
+func WithLockOptionAutoRefresh() LockOption {
+	return func(lo *lockOption) {
+		lo.autoRefresh = true
+	}
+}
+
+// WithLockOptionOwner sets the owner of a ReentrantLock, obtaining the same key with the same owner re-enters the Lock.
+// Without it every ObtainReentrant call uses a new random owner, use ReentrantLock.Reenter to re-enter such a Lock.
+func WithLockOptionOwner(owner string) LockOption {
+	return func(lo *lockOption) {
+		lo.owner = owner
+	}
+}
+
+// WithLockOptionNodeTimeout sets the timeout of a single redis instance when obtaining or refreshing a RedLock.
+func WithLockOptionNodeTimeout(timeout time.Duration) LockOption {
+	return func(lo *lockOption) {
+		lo.nodeTimeout = timeout
+	}
+}
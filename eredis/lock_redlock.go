@@ -0,0 +1,280 @@
+package eredis
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redLockClockDriftFactor 时钟漂移因子，参考 https://redis.io/topics/distlock
+const redLockClockDriftFactor = 0.01
+
+// Locker is implemented by *Lock and *RedLock.
+type Locker interface {
+	// Key returns the redis key used by the Lock.
+	Key() string
+	// Token returns the token value set by the Lock.
+	Token() string
+	// Metadata returns the metadata of the Lock.
+	Metadata() string
+	// TTL returns the remaining time-to-live. Returns 0 if the Lock has expired.
+	TTL(ctx context.Context) (time.Duration, error)
+	// Refresh extends the Lock with a new TTL.
+	Refresh(ctx context.Context, ttl time.Duration, opts ...LockOption) error
+	// Release manually releases the Lock.
+	Release(ctx context.Context) error
+	// Lost returns a channel which is closed when the automatic refresh fails.
+	Lost() <-chan struct{}
+}
+
+var (
+	_ Locker = (*Lock)(nil)
+	_ Locker = (*RedLock)(nil)
+)
+
+// RedLockClient obtains locks on a majority of several independent redis instances (Redlock algorithm).
+type RedLockClient struct {
+	clients []*lockClient
+	quorum  int
+}
+
+// NewRedLockClient creates a RedLockClient with the given components.
+// Components should be independent redis masters, a lock is obtained when a majority of them accept it.
+func NewRedLockClient(components ...*Component) *RedLockClient {
+	clients := make([]*lockClient, 0, len(components))
+	for _, comp := range components {
+		clients = append(clients, comp.lockClient)
+	}
+	return &RedLockClient{
+		clients: clients,
+		quorum:  len(clients)/2 + 1,
+	}
+}
+
+// Obtain tries to obtain a new RedLock using a key with the given TTL.
+// May return ErrNotObtained if not successful.
+func (c *RedLockClient) Obtain(ctx context.Context, key string, ttl time.Duration, opts ...LockOption) (*RedLock, error) {
+	if len(c.clients) == 0 {
+		return nil, ErrInvalidParams
+	}
+	// Create a random token
+	token, err := c.clients[0].randomToken()
+	if err != nil {
+		return nil, err
+	}
+	opt := &lockOption{}
+	for _, o := range opts {
+		o(opt)
+	}
+	if opt.retryStrategy == nil {
+		opt.retryStrategy = NoRetry()
+	}
+	if opt.nodeTimeout <= 0 {
+		opt.nodeTimeout = ttl / 10
+	}
+
+	lock := &RedLock{client: c, key: key, value: token + opt.metadata}
+	retry := opt.retryStrategy
+
+	deadlineCtx, cancel := context.WithDeadline(ctx, time.Now().Add(ttl))
+	defer cancel()
+
+	var timer *time.Timer
+	for {
+		start := time.Now()
+		n, err := lock.each(deadlineCtx, opt.nodeTimeout, func(ctx context.Context, client *lockClient) (bool, error) {
+			return client.obtain(ctx, key, lock.value, ttl)
+		})
+		if n >= c.quorum && validity(ttl, time.Since(start)) > 0 {
+			if opt.autoRefresh {
+				lock.watchdog = newWatchdog(ttl, func(ctx context.Context) error {
+					return lock.Refresh(ctx, ttl, opts...)
+				})
+			}
+			return lock, nil
+		}
+		// 未拿到多数派，或者有效期已耗尽，释放已获取的节点
+		_ = lock.release(context.Background(), opt.nodeTimeout)
+		if n == 0 && err != nil {
+			return nil, err
+		}
+
+		backoff := retry.NextBackoff()
+		if backoff < 1 {
+			return nil, ErrNotObtained
+		}
+
+		if timer == nil {
+			timer = time.NewTimer(backoff)
+			defer timer.Stop()
+		} else {
+			timer.Reset(backoff)
+		}
+
+		select {
+		case <-deadlineCtx.Done():
+			return nil, ErrNotObtained
+		case <-timer.C:
+		}
+	}
+}
+
+// validity returns the remaining validity of a lock after elapsed time, taking clock drift into account.
+func validity(ttl, elapsed time.Duration) time.Duration {
+	drift := time.Duration(float64(ttl)*redLockClockDriftFactor) + 2*time.Millisecond
+	return ttl - elapsed - drift
+}
+
+// RedLock represents a distributed Lock obtained on a majority of redis instances.
+type RedLock struct {
+	client   *RedLockClient
+	key      string
+	value    string
+	watchdog *watchdog
+}
+
+// Key returns the redis key used by the Lock.
+func (l *RedLock) Key() string {
+	return l.key
+}
+
+// Token returns the token value set by the Lock.
+func (l *RedLock) Token() string {
+	return l.value[:22]
+}
+
+// Metadata returns the metadata of the Lock.
+func (l *RedLock) Metadata() string {
+	return l.value[22:]
+}
+
+// Lost returns a channel which is closed when the automatic refresh fails.
+// Returns nil if WithLockOptionAutoRefresh is not used.
+func (l *RedLock) Lost() <-chan struct{} {
+	return l.watchdog.Lost()
+}
+
+// TTL returns the remaining time-to-live of the quorum. Returns 0 if the Lock has expired.
+func (l *RedLock) TTL(ctx context.Context) (time.Duration, error) {
+	var (
+		mu   sync.Mutex
+		ttls = make([]time.Duration, 0, len(l.client.clients))
+	)
+	_, err := l.each(ctx, 0, func(ctx context.Context, client *lockClient) (bool, error) {
+		res, err := luaPTTL.Run(ctx, client.client, []string{l.key}, l.value).Result()
+		if err == redis.Nil {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if num := res.(int64); num > 0 {
+			mu.Lock()
+			ttls = append(ttls, time.Duration(num)*time.Millisecond)
+			mu.Unlock()
+			return true, nil
+		}
+		return false, nil
+	})
+	if len(ttls) < l.client.quorum {
+		if len(ttls) == 0 && err != nil {
+			return 0, err
+		}
+		return 0, nil
+	}
+	// 多数派中最先过期的那个节点决定了锁的剩余时间
+	sort.Slice(ttls, func(i, j int) bool { return ttls[i] > ttls[j] })
+	return ttls[l.client.quorum-1], nil
+}
+
+// Refresh extends the Lock with a new TTL on every instance.
+// May return ErrNotObtained if refresh is unsuccessful on a majority.
+func (l *RedLock) Refresh(ctx context.Context, ttl time.Duration, opts ...LockOption) error {
+	opt := &lockOption{}
+	for _, o := range opts {
+		o(opt)
+	}
+	if opt.nodeTimeout <= 0 {
+		opt.nodeTimeout = ttl / 10
+	}
+
+	ttlVal := strconv.FormatInt(int64(ttl/time.Millisecond), 10)
+	start := time.Now()
+	n, err := l.each(ctx, opt.nodeTimeout, func(ctx context.Context, client *lockClient) (bool, error) {
+		status, err := luaRefresh.Run(ctx, client.client, []string{l.key}, l.value, ttlVal).Result()
+		if err != nil {
+			return false, err
+		}
+		return status == int64(1), nil
+	})
+	if n >= l.client.quorum && validity(ttl, time.Since(start)) > 0 {
+		return nil
+	}
+	if n == 0 && err != nil {
+		return err
+	}
+	return ErrNotObtained
+}
+
+// Release manually releases the Lock on every instance, the automatic refresh is stopped.
+// May return ErrLockNotHeld if the Lock is not held by a majority.
+func (l *RedLock) Release(ctx context.Context) error {
+	l.watchdog.Stop()
+	return l.release(ctx, 0)
+}
+
+func (l *RedLock) release(ctx context.Context, nodeTimeout time.Duration) error {
+	n, err := l.each(ctx, nodeTimeout, func(ctx context.Context, client *lockClient) (bool, error) {
+		res, err := luaRelease.Run(ctx, client.client, []string{l.key}, l.value).Result()
+		if err == redis.Nil {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		i, ok := res.(int64)
+		return ok && i == 1, nil
+	})
+	if n >= l.client.quorum {
+		return nil
+	}
+	if n == 0 && err != nil {
+		return err
+	}
+	return ErrLockNotHeld
+}
+
+// each runs fn concurrently against all instances, returns the number of successes and the last error.
+func (l *RedLock) each(ctx context.Context, nodeTimeout time.Duration, fn func(ctx context.Context, client *lockClient) (bool, error)) (int, error) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		n       int
+		lastErr error
+	)
+	for _, client := range l.client.clients {
+		wg.Add(1)
+		go func(client *lockClient) {
+			defer wg.Done()
+			nodeCtx := ctx
+			if nodeTimeout > 0 {
+				var cancel context.CancelFunc
+				nodeCtx, cancel = context.WithTimeout(ctx, nodeTimeout)
+				defer cancel()
+			}
+			ok, err := fn(nodeCtx, client)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				lastErr = err
+			}
+			if ok {
+				n++
+			}
+		}(client)
+	}
+	wg.Wait()
+	return n, lastErr
+}
@@ -0,0 +1,235 @@
+package eredis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// lockModeField 记录锁模式的 hash field，其余 field 为持有者及其重入次数
+	lockModeField = "__mode"
+	lockModeRead  = "read"
+	lockModeWrite = "write"
+)
+
+var (
+	// luaReentrantObtain 返回持有者当前的重入次数，0 表示未获取
+	luaReentrantObtain = redis.NewScript(`
+local mode = redis.call("hget", KEYS[1], "` + lockModeField + `")
+if mode == false then
+	redis.call("hset", KEYS[1], "` + lockModeField + `", ARGV[3])
+	redis.call("hset", KEYS[1], ARGV[1], 1)
+	redis.call("pexpire", KEYS[1], ARGV[2])
+	return 1
+end
+if mode == ARGV[3] and (mode == "` + lockModeRead + `" or redis.call("hexists", KEYS[1], ARGV[1]) == 1) then
+	local n = redis.call("hincrby", KEYS[1], ARGV[1], 1)
+	if redis.call("pttl", KEYS[1]) < tonumber(ARGV[2]) then
+		redis.call("pexpire", KEYS[1], ARGV[2])
+	end
+	return n
+end
+return 0`)
+	// luaReentrantRefresh 共享锁只会延长过期时间，避免缩短其他读者的租期
+	luaReentrantRefresh = redis.NewScript(`
+if redis.call("hexists", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+if redis.call("hget", KEYS[1], "` + lockModeField + `") == "` + lockModeWrite + `" or redis.call("pttl", KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 1`)
+	// luaReentrantRelease 返回持有者剩余的重入次数，-1 表示未持有
+	luaReentrantRelease = redis.NewScript(`
+if redis.call("hexists", KEYS[1], ARGV[1]) == 0 then
+	return -1
+end
+local n = redis.call("hincrby", KEYS[1], ARGV[1], -1)
+if n > 0 then
+	return n
+end
+redis.call("hdel", KEYS[1], ARGV[1])
+if redis.call("hlen", KEYS[1]) <= 1 then
+	redis.call("del", KEYS[1])
+end
+return 0`)
+	luaReentrantPTTL = redis.NewScript(`if redis.call("hexists", KEYS[1], ARGV[1]) == 1 then return redis.call("pttl", KEYS[1]) else return -3 end`)
+)
+
+var _ Locker = (*ReentrantLock)(nil)
+
+// ObtainReentrant tries to obtain an exclusive reentrant Lock using a key with the given TTL.
+// Obtaining the same key again with the same owner (see WithLockOptionOwner) or through ReentrantLock.Reenter
+// increments the hold count instead of blocking, the Lock is freed after the same number of Release calls.
+// Without WithLockOptionOwner a new random owner is used, so separate calls exclude each other.
+// May return ErrNotObtained if not successful.
+func (c *lockClient) ObtainReentrant(ctx context.Context, key string, ttl time.Duration, opts ...LockOption) (*ReentrantLock, error) {
+	return c.obtainReentrant(ctx, key, lockModeWrite, ttl, opts...)
+}
+
+// RWLock returns a shared/exclusive Lock using the key.
+func (c *lockClient) RWLock(key string) *RWLock {
+	return &RWLock{client: c, key: key}
+}
+
+func (c *lockClient) obtainReentrant(ctx context.Context, key, mode string, ttl time.Duration, opts ...LockOption) (*ReentrantLock, error) {
+	opt := &lockOption{}
+	for _, o := range opts {
+		o(opt)
+	}
+	if opt.retryStrategy == nil {
+		opt.retryStrategy = NoRetry()
+	}
+	if opt.owner == "" {
+		owner, err := c.randomToken()
+		if err != nil {
+			return nil, err
+		}
+		opt.owner = owner
+	}
+
+	lock := &ReentrantLock{client: c, key: key, mode: mode, owner: opt.owner, metadata: opt.metadata}
+	ttlVal := strconv.FormatInt(int64(ttl/time.Millisecond), 10)
+	err := retryObtain(ctx, ttl, opt.retryStrategy, func(ctx context.Context) (bool, error) {
+		n, err := luaReentrantObtain.Run(ctx, c.client, []string{key}, lock.field(), ttlVal, mode).Int64()
+		if err != nil {
+			return false, err
+		}
+		return n > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opt.autoRefresh {
+		lock.watchdog = newWatchdog(ttl, func(ctx context.Context) error {
+			return lock.Refresh(ctx, ttl)
+		})
+	}
+	return lock, nil
+}
+
+// RWLock is a shared/exclusive distributed Lock, many readers or a single writer may hold it at the same time.
+// Both sides are reentrant for the same owner.
+type RWLock struct {
+	client *lockClient
+	key    string
+}
+
+// Key returns the redis key used by the RWLock.
+func (rw *RWLock) Key() string {
+	return rw.key
+}
+
+// RLock tries to obtain the shared side of the RWLock with the given TTL.
+// May return ErrNotObtained if a writer holds the Lock.
+func (rw *RWLock) RLock(ctx context.Context, ttl time.Duration, opts ...LockOption) (*ReentrantLock, error) {
+	return rw.client.obtainReentrant(ctx, rw.key, lockModeRead, ttl, opts...)
+}
+
+// Lock tries to obtain the exclusive side of the RWLock with the given TTL.
+// May return ErrNotObtained if readers or another writer hold the Lock.
+func (rw *RWLock) Lock(ctx context.Context, ttl time.Duration, opts ...LockOption) (*ReentrantLock, error) {
+	return rw.client.obtainReentrant(ctx, rw.key, lockModeWrite, ttl, opts...)
+}
+
+// ReentrantLock represents one hold of a reentrant distributed Lock, the hold count is tracked in a redis hash.
+type ReentrantLock struct {
+	client   *lockClient
+	key      string
+	mode     string
+	owner    string
+	metadata string
+	watchdog *watchdog
+}
+
+// Key returns the redis key used by the Lock.
+func (l *ReentrantLock) Key() string {
+	return l.key
+}
+
+// Token returns the owner of the Lock.
+func (l *ReentrantLock) Token() string {
+	return l.owner
+}
+
+// Metadata returns the metadata of the Lock.
+func (l *ReentrantLock) Metadata() string {
+	return l.metadata
+}
+
+// Reenter obtains the Lock again with the same owner, mode and metadata, incrementing the hold count.
+// The returned hold is released independently, the owner and metadata options are ignored.
+// May return ErrNotObtained if the Lock is no longer held by the owner and cannot be obtained.
+func (l *ReentrantLock) Reenter(ctx context.Context, ttl time.Duration, opts ...LockOption) (*ReentrantLock, error) {
+	opts = append(opts, WithLockOptionOwner(l.owner), WithLockOptionMetadata(l.metadata))
+	return l.client.obtainReentrant(ctx, l.key, l.mode, ttl, opts...)
+}
+
+// HoldCount returns how many times the owner currently holds the Lock.
+func (l *ReentrantLock) HoldCount(ctx context.Context) (int64, error) {
+	n, err := l.client.client.HGet(ctx, l.key, l.field()).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return n, err
+}
+
+// Lost returns a channel which is closed when the automatic refresh fails.
+// Returns nil if WithLockOptionAutoRefresh is not used.
+func (l *ReentrantLock) Lost() <-chan struct{} {
+	return l.watchdog.Lost()
+}
+
+// TTL returns the remaining time-to-live. Returns 0 if the Lock has expired.
+func (l *ReentrantLock) TTL(ctx context.Context) (time.Duration, error) {
+	res, err := luaReentrantPTTL.Run(ctx, l.client.client, []string{l.key}, l.field()).Result()
+	if err == redis.Nil {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	if num := res.(int64); num > 0 {
+		return time.Duration(num) * time.Millisecond, nil
+	}
+	return 0, nil
+}
+
+// Refresh extends the Lock with a new TTL.
+// May return ErrNotObtained if refresh is unsuccessful.
+func (l *ReentrantLock) Refresh(ctx context.Context, ttl time.Duration, opts ...LockOption) error {
+	ttlVal := strconv.FormatInt(int64(ttl/time.Millisecond), 10)
+	status, err := luaReentrantRefresh.Run(ctx, l.client.client, []string{l.key}, l.field(), ttlVal).Result()
+	if err != nil {
+		return err
+	} else if status == int64(1) {
+		return nil
+	}
+	return ErrNotObtained
+}
+
+// Release releases one hold of the Lock, the Lock is freed when the hold count reaches zero.
+// The automatic refresh of this hold is stopped.
+// May return ErrLockNotHeld.
+func (l *ReentrantLock) Release(ctx context.Context) error {
+	l.watchdog.Stop()
+	res, err := luaReentrantRelease.Run(ctx, l.client.client, []string{l.key}, l.field()).Result()
+	if err == redis.Nil {
+		return ErrLockNotHeld
+	} else if err != nil {
+		return err
+	}
+
+	if i, ok := res.(int64); !ok || i < 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// field returns the hash field of the owner.
+func (l *ReentrantLock) field() string {
+	return l.owner + l.metadata
+}
@@ -48,6 +48,150 @@ func TestLock(t *testing.T) {
 	}
 }
 
+func TestLockAutoRefresh(t *testing.T) {
+	cmp := newCmpLock(t)
+	ctx := context.Background()
+
+	lock, err := cmp.LockClient().Obtain(ctx, "my-auto-refresh-key", 300*time.Millisecond, WithLockOptionAutoRefresh())
+	assert.NoError(t, err)
+
+	// the watchdog keeps the Lock alive longer than its ttl
+	time.Sleep(700 * time.Millisecond)
+	ttl, err := lock.TTL(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ttl > 0)
+
+	select {
+	case <-lock.Lost():
+		t.Fatal("lock should not be lost")
+	default:
+	}
+	assert.NoError(t, lock.Release(ctx))
+}
+
+func TestWatchdogLost(t *testing.T) {
+	var cnt int
+	w := newWatchdog(30*time.Millisecond, func(ctx context.Context) error {
+		cnt++
+		if cnt > 2 {
+			return ErrNotObtained
+		}
+		return nil
+	})
+	select {
+	case <-w.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("watchdog should report the lost Lock")
+	}
+	w.Stop()
+	assert.Equal(t, 3, cnt)
+}
+
+func TestRedLock(t *testing.T) {
+	cmps := newCmpRedLock(t)
+	client := NewRedLockClient(cmps...)
+	ctx := context.Background()
+
+	lock, err := client.Obtain(ctx, "my-redlock-key", time.Second, WithLockOptionMetadata("payment"))
+	assert.NoError(t, err)
+	assert.Equal(t, "payment", lock.Metadata())
+
+	// the key is held by the quorum, a second obtain must fail
+	_, err = client.Obtain(ctx, "my-redlock-key", time.Second)
+	assert.ErrorIs(t, err, ErrNotObtained)
+
+	ttl, err := lock.TTL(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ttl > 0 && ttl <= time.Second)
+
+	assert.NoError(t, lock.Refresh(ctx, 2*time.Second))
+	ttl, err = lock.TTL(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ttl > time.Second)
+
+	assert.NoError(t, lock.Release(ctx))
+	assert.ErrorIs(t, lock.Release(ctx), ErrLockNotHeld)
+}
+
+func TestReentrantLock(t *testing.T) {
+	cmp := newCmpLock(t)
+	lockClient := cmp.LockClient()
+	ctx := context.Background()
+
+	lock, err := lockClient.ObtainReentrant(ctx, "my-reentrant-key", time.Second, WithLockOptionOwner("job-1"))
+	assert.NoError(t, err)
+	// the same owner re-enters
+	_, err = lockClient.ObtainReentrant(ctx, "my-reentrant-key", time.Second, WithLockOptionOwner("job-1"))
+	assert.NoError(t, err)
+	cnt, err := lock.HoldCount(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), cnt)
+
+	// another owner is rejected
+	_, err = lockClient.ObtainReentrant(ctx, "my-reentrant-key", time.Second, WithLockOptionOwner("job-2"))
+	assert.ErrorIs(t, err, ErrNotObtained)
+
+	assert.NoError(t, lock.Release(ctx))
+	assert.NoError(t, lock.Release(ctx))
+	assert.ErrorIs(t, lock.Release(ctx), ErrLockNotHeld)
+
+	// locks without an owner exclude each other, re-enter through the obtained lock
+	lock, err = lockClient.ObtainReentrant(ctx, "my-reentrant-key", time.Second)
+	assert.NoError(t, err)
+	_, err = lockClient.ObtainReentrant(ctx, "my-reentrant-key", time.Second)
+	assert.ErrorIs(t, err, ErrNotObtained)
+	hold, err := lock.Reenter(ctx, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, lock.Token(), hold.Token())
+	cnt, err = lock.HoldCount(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), cnt)
+	assert.NoError(t, hold.Release(ctx))
+	assert.NoError(t, lock.Release(ctx))
+	assert.ErrorIs(t, lock.Release(ctx), ErrLockNotHeld)
+}
+
+func TestRWLock(t *testing.T) {
+	cmp := newCmpLock(t)
+	rw := cmp.LockClient().RWLock("my-rw-key")
+	ctx := context.Background()
+
+	r1, err := rw.RLock(ctx, time.Second)
+	assert.NoError(t, err)
+	r2, err := rw.RLock(ctx, time.Second)
+	assert.NoError(t, err)
+
+	// writers wait for all readers
+	_, err = rw.Lock(ctx, time.Second)
+	assert.ErrorIs(t, err, ErrNotObtained)
+
+	assert.NoError(t, r1.Release(ctx))
+	assert.NoError(t, r2.Release(ctx))
+
+	w, err := rw.Lock(ctx, time.Second)
+	assert.NoError(t, err)
+	_, err = rw.RLock(ctx, time.Second)
+	assert.ErrorIs(t, err, ErrNotObtained)
+	assert.NoError(t, w.Release(ctx))
+}
+
+func newCmpRedLock(t *testing.T) []*Component {
+	conf := `
+[redis0]
+	addr="localhost:6379"
+	db=0
+[redis1]
+	addr="localhost:6379"
+	db=1
+[redis2]
+	addr="localhost:6379"
+	db=2
+`
+	err := econf.LoadFromReader(strings.NewReader(conf), toml.Unmarshal)
+	assert.NoError(t, err)
+	return []*Component{Load("redis0").Build(), Load("redis1").Build(), Load("redis2").Build()}
+}
+
 func newCmpLock(t *testing.T) *Component {
 	conf := `
 [redis]
@@ -0,0 +1,79 @@
+package eredis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// lockWatchdogRatio 看门狗按照 ttl/lockWatchdogRatio 的间隔续租
+const lockWatchdogRatio = 3
+
+// watchdog renews the lease of an obtained Lock in background until it is stopped or the renewal fails.
+type watchdog struct {
+	lost     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func newWatchdog(ttl time.Duration, refresh func(ctx context.Context) error) *watchdog {
+	w := &watchdog{
+		lost: make(chan struct{}),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go w.run(ttl, refresh)
+	return w
+}
+
+func (w *watchdog) run(ttl time.Duration, refresh func(ctx context.Context) error) {
+	defer close(w.done)
+
+	interval := ttl / lockWatchdogRatio
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	expireAt := time.Now().Add(ttl)
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := refresh(ctx)
+		cancel()
+		if err == nil {
+			expireAt = start.Add(ttl)
+			continue
+		}
+		// 锁已被他人持有，或者网络错误持续到租期结束，都认为锁已丢失
+		if errors.Is(err, ErrNotObtained) || !time.Now().Before(expireAt) {
+			close(w.lost)
+			return
+		}
+	}
+}
+
+// Stop stops the renewal and waits for the background goroutine to exit.
+func (w *watchdog) Stop() {
+	if w == nil {
+		return
+	}
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
+
+// Lost returns a channel which is closed when the renewal fails.
+func (w *watchdog) Lost() <-chan struct{} {
+	if w == nil {
+		return nil
+	}
+	return w.lost
+}
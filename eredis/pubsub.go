@@ -0,0 +1,174 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gotomicro/ego/core/elog"
+)
+
+const (
+	pubSubKindMessage  = "message"
+	pubSubKindPMessage = "pmessage"
+	pubSubKindSMessage = "smessage"
+)
+
+// SubscribeHandler handles a message received from subscribed channels.
+type SubscribeHandler = func(ctx context.Context, message *redis.Message) error
+
+// Subscriber receives messages of subscribed channels until it is closed or the ctx of the subscription is done.
+// Lost connections are re-established and channels are re-subscribed automatically,
+// handlers run under the access, metric and trace interceptors of the component, and handler errors are logged.
+type Subscriber struct {
+	cmp       *Component
+	handler   SubscribeHandler
+	ctx       context.Context
+	cancel    context.CancelFunc
+	pubSub    *redis.PubSub
+	closeOnce sync.Once
+	closeErr  error
+	wg        sync.WaitGroup
+}
+
+// Subscribe subscribes the channels, messages are handled by handler in a background goroutine.
+func (r *Component) Subscribe(ctx context.Context, handler SubscribeHandler, channels ...string) (*Subscriber, error) {
+	return r.subscribe(ctx, handler, false, channels...)
+}
+
+// PSubscribe subscribes the channels matching the patterns, messages are handled by handler in a background goroutine.
+func (r *Component) PSubscribe(ctx context.Context, handler SubscribeHandler, patterns ...string) (*Subscriber, error) {
+	return r.subscribe(ctx, handler, true, patterns...)
+}
+
+func (r *Component) subscribe(ctx context.Context, handler SubscribeHandler, pattern bool, channels ...string) (*Subscriber, error) {
+	if len(channels) == 0 || handler == nil {
+		return nil, fmt.Errorf("eredis subscribe error %w", ErrInvalidParams)
+	}
+	var pubSub *redis.PubSub
+	switch c := r.client.(type) {
+	case *redis.ClusterClient:
+		if pattern {
+			pubSub = c.PSubscribe(ctx, channels...)
+		} else {
+			pubSub = c.Subscribe(ctx, channels...)
+		}
+	case *redis.Client:
+		if pattern {
+			pubSub = c.PSubscribe(ctx, channels...)
+		} else {
+			pubSub = c.Subscribe(ctx, channels...)
+		}
+	default:
+		return nil, fmt.Errorf("eredis subscribe error, unsupported client %T", r.client)
+	}
+	// 等待订阅确认
+	if _, err := pubSub.Receive(ctx); err != nil {
+		_ = pubSub.Close()
+		return nil, fmt.Errorf("eredis subscribe error %w", err)
+	}
+
+	s := newSubscriber(ctx, r, handler)
+	s.pubSub = pubSub
+	s.wg.Add(1)
+	go s.receive(pubSub.Channel())
+	// 与 SSubscribe 一致，ctx 结束时取消订阅
+	go func() {
+		<-s.ctx.Done()
+		_ = s.closePubSub()
+	}()
+	return s, nil
+}
+
+func newSubscriber(ctx context.Context, cmp *Component, handler SubscribeHandler) *Subscriber {
+	s := &Subscriber{
+		cmp:     cmp,
+		handler: handler,
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	return s
+}
+
+// receive dispatches messages, go-redis pings the connection periodically and re-subscribes after reconnection.
+func (s *Subscriber) receive(ch <-chan *redis.Message) {
+	defer s.wg.Done()
+	for msg := range ch {
+		kind := pubSubKindMessage
+		if msg.Pattern != "" {
+			kind = pubSubKindPMessage
+		}
+		s.handle(kind, msg)
+	}
+}
+
+// handle runs the handler under the interceptors of the component, the message is seen as a command named by its kind.
+func (s *Subscriber) handle(kind string, msg *redis.Message) {
+	cmd := redis.NewCmd(s.ctx, kind, msg.Channel, msg.Payload)
+	err := s.cmp.processHooks(s.ctx, cmd, func(ctx context.Context, cmd redis.Cmder) (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = fmt.Errorf("eredis subscribe handler panic: %v", rec)
+			}
+		}()
+		return s.handler(ctx, msg)
+	})
+	if err != nil {
+		s.cmp.logger.Error("subscribe handler error", elog.FieldErr(err), elog.String("channel", msg.Channel))
+	}
+}
+
+// Close unsubscribes the channels and waits for the in-flight handler to finish.
+func (s *Subscriber) Close() error {
+	s.cancel()
+	err := s.closePubSub()
+	s.wg.Wait()
+	return err
+}
+
+// closePubSub closes the non-sharded subscription once.
+func (s *Subscriber) closePubSub() error {
+	s.closeOnce.Do(func() {
+		if s.pubSub != nil {
+			s.closeErr = s.pubSub.Close()
+		}
+	})
+	return s.closeErr
+}
+
+// processHooks runs fn like go-redis does for a command, so that fn is observed by the interceptors.
+func (r *Component) processHooks(ctx context.Context, cmd redis.Cmder, fn func(ctx context.Context, cmd redis.Cmder) error) error {
+	hooks := r.config.interceptors
+	var (
+		hookIndex int
+		retErr    error
+	)
+	for ; hookIndex < len(hooks) && retErr == nil; hookIndex++ {
+		ctx, retErr = hooks[hookIndex].BeforeProcess(ctx, cmd)
+		if retErr != nil {
+			cmd.SetErr(retErr)
+		}
+	}
+	if retErr == nil {
+		retErr = fn(ctx, cmd)
+		cmd.SetErr(retErr)
+	}
+	for hookIndex--; hookIndex >= 0; hookIndex-- {
+		if err := hooks[hookIndex].AfterProcess(ctx, cmd); err != nil {
+			retErr = err
+			cmd.SetErr(retErr)
+		}
+	}
+	return retErr
+}
+
+// sleepContext sleeps for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
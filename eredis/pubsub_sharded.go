@@ -0,0 +1,241 @@
+package eredis
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gotomicro/ego/core/elog"
+)
+
+const (
+	// shardedPingInterval 分片订阅连接的心跳间隔，超过两倍间隔没有收到任何数据认为连接已断开
+	shardedPingInterval = 10 * time.Second
+	// shardedReconnectBackoff 分片订阅重连间隔
+	shardedReconnectBackoff = time.Second
+)
+
+// SSubscribe subscribes the sharded channels (redis 7.0+), messages are handled by handler in background goroutines.
+// go-redis v8 does not support SSUBSCRIBE, so every channel uses a dedicated connection to the master owning its slot.
+// The connection is re-established, and the owner is resolved again, after connection loss or slot migration.
+func (r *Component) SSubscribe(ctx context.Context, handler SubscribeHandler, channels ...string) (*Subscriber, error) {
+	if len(channels) == 0 || handler == nil {
+		return nil, fmt.Errorf("eredis ssubscribe error %w", ErrInvalidParams)
+	}
+	s := newSubscriber(ctx, r, handler)
+	for _, channel := range channels {
+		s.wg.Add(1)
+		go s.receiveSharded(channel)
+	}
+	return s, nil
+}
+
+// receiveSharded keeps a sharded subscription of channel alive until the Subscriber is closed.
+func (s *Subscriber) receiveSharded(channel string) {
+	defer s.wg.Done()
+	for s.ctx.Err() == nil {
+		err := s.subscribeSharded(channel)
+		if s.ctx.Err() != nil {
+			return
+		}
+		s.cmp.logger.Error("ssubscribe connection lost, reconnecting", elog.FieldErr(err), elog.String("channel", channel))
+		sleepContext(s.ctx, shardedReconnectBackoff)
+	}
+}
+
+func (s *Subscriber) subscribeSharded(channel string) error {
+	node, err := s.cmp.shardNode(s.ctx, channel)
+	if err != nil {
+		return err
+	}
+	opt := node.Options()
+	netConn, err := dialSharded(s.ctx, opt)
+	if err != nil {
+		return err
+	}
+	conn := newRespConn(netConn)
+	// 关闭 Subscriber 时中断阻塞的读取
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-s.ctx.Done():
+		case <-stop:
+		}
+		_ = netConn.Close()
+	}()
+
+	if opt.Password != "" {
+		args := []string{"auth", opt.Password}
+		if opt.Username != "" {
+			args = []string{"auth", opt.Username, opt.Password}
+		}
+		if _, err := conn.do(args...); err != nil {
+			return err
+		}
+	}
+	if err := conn.write("ssubscribe", channel); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(shardedPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := conn.write("ping"); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		_ = netConn.SetReadDeadline(time.Now().Add(2 * shardedPingInterval))
+		reply, err := conn.read()
+		if err != nil {
+			return err
+		}
+		values, ok := reply.([]interface{})
+		if !ok || len(values) == 0 {
+			continue
+		}
+		kind, _ := values[0].(string)
+		switch strings.ToLower(kind) {
+		case pubSubKindSMessage:
+			if len(values) < 3 {
+				continue
+			}
+			ch, _ := values[1].(string)
+			payload, _ := values[2].(string)
+			s.handle(pubSubKindSMessage, &redis.Message{Channel: ch, Payload: payload})
+		case "sunsubscribe":
+			// 槽位迁移后服务端会取消订阅，重新定位节点后再订阅
+			return fmt.Errorf("channel %s unsubscribed by server", channel)
+		}
+	}
+}
+
+// shardNode returns the client of the node owning the channel.
+func (r *Component) shardNode(ctx context.Context, channel string) (*redis.Client, error) {
+	switch c := r.client.(type) {
+	case *redis.ClusterClient:
+		return c.MasterForKey(ctx, channel)
+	case *redis.Client:
+		return c, nil
+	default:
+		return nil, fmt.Errorf("eredis ssubscribe error, unsupported client %T", r.client)
+	}
+}
+
+// dialSharded dials the node with the dialer of the go-redis client.
+// The default dialer of go-redis already uses TLSConfig, a custom dialer returning a plain connection is wrapped with TLS.
+func dialSharded(ctx context.Context, opt *redis.Options) (net.Conn, error) {
+	netConn, err := opt.Dialer(ctx, opt.Network, opt.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if opt.TLSConfig == nil {
+		return netConn, nil
+	}
+	if _, ok := netConn.(*tls.Conn); ok {
+		return netConn, nil
+	}
+	tlsConfig := opt.TLSConfig.Clone()
+	if tlsConfig.ServerName == "" && !tlsConfig.InsecureSkipVerify {
+		if host, _, err := net.SplitHostPort(opt.Addr); err == nil {
+			tlsConfig.ServerName = host
+		}
+	}
+	tlsConn := tls.Client(netConn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// respConn is a minimal RESP2 connection used by sharded subscriptions.
+type respConn struct {
+	mu sync.Mutex
+	w  io.Writer
+	r  *bufio.Reader
+}
+
+func newRespConn(conn net.Conn) *respConn {
+	return &respConn{w: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *respConn) do(args ...string) (interface{}, error) {
+	if err := c.write(args...); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+func (c *respConn) write(args ...string) error {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := io.WriteString(c.w, b.String())
+	return err
+}
+
+func (c *respConn) read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("eredis resp: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Err(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]interface{}, n)
+		for i := range values {
+			if values[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("eredis resp: unsupported reply %q", line)
+	}
+}
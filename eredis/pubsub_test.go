@@ -0,0 +1,103 @@
+package eredis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespConn(t *testing.T) {
+	var w bytes.Buffer
+	reply := "*3\r\n$8\r\nsmessage\r\n$2\r\nch\r\n$5\r\nhello\r\n+PONG\r\n:1\r\n-ERR unknown\r\n"
+	conn := &respConn{w: &w, r: bufio.NewReader(bytes.NewBufferString(reply))}
+
+	assert.NoError(t, conn.write("ssubscribe", "ch"))
+	assert.Equal(t, "*2\r\n$10\r\nssubscribe\r\n$2\r\nch\r\n", w.String())
+
+	v, err := conn.read()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"smessage", "ch", "hello"}, v)
+	v, err = conn.read()
+	assert.NoError(t, err)
+	assert.Equal(t, "PONG", v)
+	v, err = conn.read()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+	_, err = conn.read()
+	assert.EqualError(t, err, "ERR unknown")
+}
+
+func TestDialShardedTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	mr, err := miniredis.RunTLS(&tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{cert}, PrivateKey: key}}})
+	require.NoError(t, err)
+	defer mr.Close()
+
+	// a custom dialer returning a plain connection is wrapped with TLS
+	opt := &redis.Options{
+		Network: "tcp",
+		Addr:    mr.Addr(),
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	netConn, err := dialSharded(context.Background(), opt)
+	require.NoError(t, err)
+	defer netConn.Close()
+	assert.IsType(t, &tls.Conn{}, netConn)
+	v, err := newRespConn(netConn).do("ping")
+	assert.NoError(t, err)
+	assert.Equal(t, "PONG", v)
+}
+
+func TestSubscribeContext(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cmp := DefaultContainer().Build(WithStub(), WithAddr(mr.Addr()))
+	defer cmp.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan string, 1)
+	subscriber, err := cmp.Subscribe(ctx, func(ctx context.Context, message *redis.Message) error {
+		received <- message.Payload
+		return errors.New("handle fail")
+	}, "orders")
+	require.NoError(t, err)
+	require.NoError(t, cmp.Client().Publish(context.Background(), "orders", "1").Err())
+	select {
+	case payload := <-received:
+		assert.Equal(t, "1", payload)
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+
+	// 取消 ctx 后与 SSubscribe 一样取消订阅
+	cancel()
+	assert.Eventually(t, func() bool {
+		return len(mr.PubSubChannels("")) == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, subscriber.Close())
+}
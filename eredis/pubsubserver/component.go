@@ -0,0 +1,158 @@
+package pubsubserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/gotomicro/ego/core/constant"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/server"
+
+	"github.com/gotomicro/ego-component/eredis"
+)
+
+// Interface check
+var _ server.Server = (*Component)(nil)
+
+// PackageName is the name of this component.
+const PackageName = "component.eredis.pubsubserver"
+
+// Component starts an Ego server subscribing redis channels.
+type Component struct {
+	ServerCtx            context.Context
+	stopServer           context.CancelFunc
+	name                 string
+	config               *config
+	eredisComponent      *eredis.Component
+	logger               *elog.Component
+	onEachMessageHandler OnEachMessageHandler
+	subscribers          []*eredis.Subscriber
+	// mu 保证 Start 与停止不会同时进行，停止之后 Start 不再订阅
+	mu sync.Mutex
+	wg sync.WaitGroup
+}
+
+func newComponent(name string, config *config, eredisComponent *eredis.Component, logger *elog.Component) *Component {
+	serverCtx, stopServer := context.WithCancel(context.Background())
+	return &Component{
+		ServerCtx:       serverCtx,
+		stopServer:      stopServer,
+		name:            name,
+		config:          config,
+		eredisComponent: eredisComponent,
+		logger:          logger,
+	}
+}
+
+// PackageName returns the package name.
+func (cmp *Component) PackageName() string {
+	return PackageName
+}
+
+// Info returns server info, used by governor and consumer balancer.
+func (cmp *Component) Info() *server.ServiceInfo {
+	info := server.ApplyOptions(
+		server.WithKind(constant.ServiceProvider),
+	)
+	return &info
+}
+
+// GracefulStop unsubscribes and waits for in-flight messages to be handled.
+func (cmp *Component) GracefulStop(ctx context.Context) error {
+	cmp.stop()
+	done := make(chan struct{})
+	go func() {
+		cmp.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop stops the server.
+func (cmp *Component) Stop() error {
+	cmp.stop()
+	return nil
+}
+
+func (cmp *Component) stop() {
+	cmp.mu.Lock()
+	defer cmp.mu.Unlock()
+	cmp.stopServer()
+}
+
+// Init ...
+func (cmp *Component) Init() error {
+	return nil
+}
+
+// Name returns the name of this instance.
+func (cmp *Component) Name() string {
+	return cmp.name
+}
+
+// OnEachMessage registers the message handler.
+func (cmp *Component) OnEachMessage(handler OnEachMessageHandler) error {
+	cmp.onEachMessageHandler = handler
+	return nil
+}
+
+// Start subscribes the channels and blocks until the server is stopped.
+func (cmp *Component) Start() error {
+	if cmp.onEachMessageHandler == nil {
+		return errors.New("you must define a MessageHandler first")
+	}
+	// 在锁内登记，GracefulStop 一定会等待已经开始的 Start
+	cmp.mu.Lock()
+	if cmp.ServerCtx.Err() != nil {
+		cmp.mu.Unlock()
+		return nil
+	}
+	cmp.wg.Add(1)
+	cmp.mu.Unlock()
+	defer cmp.wg.Done()
+	err := cmp.subscribe()
+	if err == nil {
+		<-cmp.ServerCtx.Done()
+	}
+	// 关闭时等待正在处理的消息
+	for _, subscriber := range cmp.subscribers {
+		if err := subscriber.Close(); err != nil {
+			cmp.logger.Warn("close subscriber fail", elog.FieldErr(err))
+		}
+	}
+	cmp.logger.Info("pubsub server stopped")
+	return err
+}
+
+func (cmp *Component) subscribe() error {
+	// 订阅的生命周期由 Subscriber.Close 控制，不使用 ServerCtx，以便停止时处理完已收到的消息
+	ctx := context.Background()
+	if len(cmp.config.Channels) > 0 {
+		subscriber, err := cmp.eredisComponent.Subscribe(ctx, cmp.onEachMessageHandler, cmp.config.Channels...)
+		if err != nil {
+			return err
+		}
+		cmp.subscribers = append(cmp.subscribers, subscriber)
+	}
+	if len(cmp.config.Patterns) > 0 {
+		subscriber, err := cmp.eredisComponent.PSubscribe(ctx, cmp.onEachMessageHandler, cmp.config.Patterns...)
+		if err != nil {
+			return err
+		}
+		cmp.subscribers = append(cmp.subscribers, subscriber)
+	}
+	if len(cmp.config.ShardedChannels) > 0 {
+		subscriber, err := cmp.eredisComponent.SSubscribe(ctx, cmp.onEachMessageHandler, cmp.config.ShardedChannels...)
+		if err != nil {
+			return err
+		}
+		cmp.subscribers = append(cmp.subscribers, subscriber)
+	}
+	return nil
+}
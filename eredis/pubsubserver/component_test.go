@@ -0,0 +1,94 @@
+package pubsubserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotomicro/ego-component/eredis"
+)
+
+func TestComponent(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisCmp := eredis.DefaultContainer().Build(eredis.WithStub(), eredis.WithAddr(mr.Addr()))
+	defer redisCmp.Close()
+
+	cmp := DefaultContainer().Build(WithEredis(redisCmp), WithChannels("orders"), WithPatterns("users.*"))
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	require.NoError(t, cmp.OnEachMessage(func(ctx context.Context, message *redis.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, message.Channel+":"+message.Payload)
+		// 处理失败只记录日志，不影响后续消息
+		if message.Payload == "fail" {
+			return errors.New("handle fail")
+		}
+		return nil
+	}))
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cmp.Start()
+	}()
+	// 等待订阅完成
+	require.Eventually(t, func() bool {
+		return len(mr.PubSubChannels("")) == 1 && mr.PubSubNumPat() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	for channel, payloads := range map[string][]string{"orders": {"fail", "1"}, "users.1": {"2"}, "others": {"3"}} {
+		for _, payload := range payloads {
+			require.NoError(t, redisCmp.Client().Publish(context.Background(), channel, payload).Err())
+		}
+	}
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 3
+	}, time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{"orders:fail", "orders:1", "users.1:2"}, received)
+
+	require.NoError(t, cmp.GracefulStop(context.Background()))
+	select {
+	case err := <-errCh:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server not stopped")
+	}
+	// 停止后取消订阅
+	assert.Eventually(t, func() bool {
+		return len(mr.PubSubChannels("")) == 0 && mr.PubSubNumPat() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestComponentStart(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisCmp := eredis.DefaultContainer().Build(eredis.WithStub(), eredis.WithAddr(mr.Addr()))
+	defer redisCmp.Close()
+
+	cmp := DefaultContainer().Build(WithEredis(redisCmp), WithChannels("orders"))
+	assert.EqualError(t, cmp.Start(), "you must define a MessageHandler first")
+}
+
+func TestComponentStopBeforeStart(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisCmp := eredis.DefaultContainer().Build(eredis.WithStub(), eredis.WithAddr(mr.Addr()))
+	defer redisCmp.Close()
+
+	cmp := DefaultContainer().Build(WithEredis(redisCmp), WithChannels("orders"))
+	require.NoError(t, cmp.OnEachMessage(func(ctx context.Context, message *redis.Message) error {
+		return nil
+	}))
+	// 停止之后 Start 不再订阅，直接返回
+	require.NoError(t, cmp.GracefulStop(context.Background()))
+	assert.NoError(t, cmp.Start())
+	assert.Empty(t, mr.PubSubChannels(""))
+}
@@ -0,0 +1,17 @@
+package pubsubserver
+
+import (
+	"github.com/gotomicro/ego-component/eredis"
+)
+
+type config struct {
+	Channels        []string `json:"channels" toml:"channels"`               // Channels 订阅的 channel 列表
+	Patterns        []string `json:"patterns" toml:"patterns"`               // Patterns 订阅的 channel 模式列表
+	ShardedChannels []string `json:"shardedChannels" toml:"shardedChannels"` // ShardedChannels 订阅的分片 channel 列表，需要 redis 7.0+
+	eredisComponent *eredis.Component
+}
+
+// DefaultConfig returns a default config.
+func DefaultConfig() *config {
+	return &config{}
+}
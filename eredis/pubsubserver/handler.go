@@ -0,0 +1,8 @@
+package pubsubserver
+
+import (
+	"github.com/gotomicro/ego-component/eredis"
+)
+
+// OnEachMessageHandler 处理单条消息，Pub/Sub 没有确认机制，返回的错误只会被记录
+type OnEachMessageHandler = eredis.SubscribeHandler
@@ -0,0 +1,33 @@
+package pubsubserver
+
+import (
+	"github.com/gotomicro/ego-component/eredis"
+)
+
+// WithEredis 设置 redis 组件
+func WithEredis(eredisComponent *eredis.Component) Option {
+	return func(c *Container) {
+		c.config.eredisComponent = eredisComponent
+	}
+}
+
+// WithChannels 设置订阅的 channel 列表
+func WithChannels(channels ...string) Option {
+	return func(c *Container) {
+		c.config.Channels = channels
+	}
+}
+
+// WithPatterns 设置订阅的 channel 模式列表
+func WithPatterns(patterns ...string) Option {
+	return func(c *Container) {
+		c.config.Patterns = patterns
+	}
+}
+
+// WithShardedChannels 设置订阅的分片 channel 列表
+func WithShardedChannels(channels ...string) Option {
+	return func(c *Container) {
+		c.config.ShardedChannels = channels
+	}
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// PackageName is the name of this component.
+const PackageName = "component.eredis.ratelimit"
+
+// Limit describes Rate requests per Period, with at most Burst requests at once.
+type Limit struct {
+	Rate   int64
+	Period time.Duration
+	Burst  int64
+}
+
+// PerSecond allows rate requests per second.
+func PerSecond(rate int64) Limit {
+	return Limit{Rate: rate, Period: time.Second, Burst: rate}
+}
+
+// PerMinute allows rate requests per minute.
+func PerMinute(rate int64) Limit {
+	return Limit{Rate: rate, Period: time.Minute, Burst: rate}
+}
+
+// PerHour allows rate requests per hour.
+func PerHour(rate int64) Limit {
+	return Limit{Rate: rate, Period: time.Hour, Burst: rate}
+}
+
+// Result is the result of a rate limit check.
+type Result struct {
+	// Limit is the limit used by the check.
+	Limit Limit
+	// Allowed reports whether the requests are allowed.
+	Allowed bool
+	// Remaining is the number of requests that can still be made immediately.
+	Remaining int64
+	// RetryAfter is the time to wait before the requests will be allowed, -1 if they never will, e.g. n exceeds the burst.
+	RetryAfter time.Duration
+	// ResetAfter is the time until the limiter gets back to its initial state.
+	ResetAfter time.Duration
+}
+
+// Limiter checks whether requests identified by a key are allowed.
+type Limiter interface {
+	// Allow is shorthand for AllowN(ctx, key, 1).
+	Allow(ctx context.Context, key string) (*Result, error)
+	// AllowN reports whether n requests may happen now, the requests are only counted when allowed.
+	AllowN(ctx context.Context, key string, n int64) (*Result, error)
+}
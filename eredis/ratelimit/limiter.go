@@ -0,0 +1,205 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/gotomicro/ego-component/eredis"
+)
+
+// Every script returns {allowed, remaining, retry_after_ms, reset_after_ms}.
+// Redis TIME is used as the clock so that the result does not depend on the clock of clients,
+// redis.replicate_commands is required for writes after TIME before redis 5.
+var (
+	// luaGCRA implements the generic cell rate algorithm, the key stores the theoretical arrival time in ms.
+	luaGCRA = redis.NewScript(`
+redis.replicate_commands()
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+local t = redis.call("TIME")
+local now = (t[1] - 1600000000) * 1000 + t[2] / 1000
+local interval = period / rate
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if tat == nil or tat < now then
+	tat = now
+end
+local new_tat = tat + interval * cost
+local diff = now - (new_tat - interval * burst)
+if diff < 0 then
+	local retry_after = -diff
+	if cost > burst then
+		retry_after = -1
+	end
+	return {0, math.floor((now - (tat - interval * burst)) / interval), math.ceil(retry_after), math.ceil(tat - now)}
+end
+local reset_after = new_tat - now
+if reset_after > 0 then
+	redis.call("SET", KEYS[1], new_tat, "PX", math.ceil(reset_after))
+end
+return {1, math.floor(diff / interval), 0, math.ceil(reset_after)}
+`)
+
+	// luaSlidingWindow implements the sliding window log algorithm, the key is a sorted set of request timestamps in ms.
+	luaSlidingWindow = redis.NewScript(`
+redis.replicate_commands()
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local t = redis.call("TIME")
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+if count + cost > limit then
+	if cost > limit then
+		return {0, limit - count, -1, window}
+	end
+	local idx = count + cost - limit - 1
+	local entry = redis.call("ZRANGE", KEYS[1], idx, idx, "WITHSCORES")
+	local newest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+	return {0, limit - count, tonumber(entry[2]) + window - now, tonumber(newest[2]) + window - now}
+end
+for i = 1, cost do
+	redis.call("ZADD", KEYS[1], now, ARGV[4] .. ":" .. i)
+end
+redis.call("PEXPIRE", KEYS[1], window)
+return {1, limit - count - cost, 0, window}
+`)
+
+	// luaTokenBucket implements the token bucket algorithm, the key is a hash of the tokens left and the last refill time in ms.
+	luaTokenBucket = redis.NewScript(`
+redis.replicate_commands()
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+local t = redis.call("TIME")
+local now = (t[1] - 1600000000) * 1000 + t[2] / 1000
+local fill = rate / period
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * fill)
+if tokens < cost then
+	local retry_after = math.ceil((cost - tokens) / fill)
+	if cost > capacity then
+		retry_after = -1
+	end
+	return {0, math.floor(tokens), retry_after, math.ceil((capacity - tokens) / fill)}
+end
+tokens = tokens - cost
+local reset_after = math.ceil((capacity - tokens) / fill)
+redis.call("HSET", KEYS[1], "tokens", tokens, "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.max(reset_after, 1))
+return {1, math.floor(tokens), 0, reset_after}
+`)
+)
+
+type limiter struct {
+	cmp    *eredis.Component
+	limit  Limit
+	opt    *option
+	script *redis.Script
+	args   func(n int64) []interface{}
+}
+
+// NewGCRA creates a Limiter with the generic cell rate algorithm, requests are spaced evenly at Rate per Period,
+// up to Burst requests are allowed at once.
+func NewGCRA(cmp *eredis.Component, limit Limit, opts ...Option) Limiter {
+	l := newLimiter(cmp, limit, luaGCRA, opts...)
+	l.args = func(n int64) []interface{} {
+		return []interface{}{limit.Burst, limit.Rate, limit.Period.Milliseconds(), n}
+	}
+	return l
+}
+
+// NewSlidingWindow creates a Limiter with the sliding window log algorithm, at most Rate requests are allowed in any Period.
+// Burst is ignored, every request is recorded, so it is not suitable for a large Rate.
+func NewSlidingWindow(cmp *eredis.Component, limit Limit, opts ...Option) Limiter {
+	l := newLimiter(cmp, limit, luaSlidingWindow, opts...)
+	l.args = func(n int64) []interface{} {
+		// 有序集合的成员需要唯一
+		member := fmt.Sprintf("%d-%d", time.Now().UnixNano(), rand.Int63())
+		return []interface{}{limit.Rate, limit.Period.Milliseconds(), n, member}
+	}
+	return l
+}
+
+// NewTokenBucket creates a Limiter with the token bucket algorithm, the bucket holds Burst tokens and is refilled with Rate tokens per Period.
+func NewTokenBucket(cmp *eredis.Component, limit Limit, opts ...Option) Limiter {
+	l := newLimiter(cmp, limit, luaTokenBucket, opts...)
+	l.args = func(n int64) []interface{} {
+		return []interface{}{limit.Burst, limit.Rate, limit.Period.Milliseconds(), n}
+	}
+	return l
+}
+
+func newLimiter(cmp *eredis.Component, limit Limit, script *redis.Script, opts ...Option) *limiter {
+	opt := &option{
+		keyPrefix: "ratelimit:",
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = limit.Rate
+	}
+	return &limiter{
+		cmp:    cmp,
+		limit:  limit,
+		opt:    opt,
+		script: script,
+	}
+}
+
+// Allow ...
+func (l *limiter) Allow(ctx context.Context, key string) (*Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN ...
+func (l *limiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	if n <= 0 || l.limit.Rate <= 0 || l.limit.Period <= 0 {
+		return nil, fmt.Errorf("ratelimit allow error %w", eredis.ErrInvalidParams)
+	}
+	values, err := l.script.Run(ctx, l.cmp.Client(), []string{l.key(key)}, l.args(n)...).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("ratelimit allow error, unexpected reply %v", values)
+	}
+	res := &Result{
+		Limit:      l.limit,
+		Allowed:    values[0] == 1,
+		Remaining:  values[1],
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		ResetAfter: time.Duration(values[3]) * time.Millisecond,
+	}
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	if values[2] < 0 {
+		res.RetryAfter = -1
+	}
+	return res, nil
+}
+
+// key wraps the key with a hash tag, so that the cluster slot only depends on the key, not on the prefix.
+// Keys with their own hash tag are kept as is.
+func (l *limiter) key(key string) string {
+	if strings.Contains(key, "{") && strings.Contains(key, "}") {
+		return l.opt.keyPrefix + key
+	}
+	return l.opt.keyPrefix + "{" + key + "}"
+}
@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gin-gonic/gin"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/gotomicro/ego-component/eredis"
+)
+
+func newCmp(t *testing.T) *eredis.Component {
+	conf := `
+[redis]
+	addr = "localhost:6379"
+`
+	if err := econf.LoadFromReader(strings.NewReader(conf), toml.Unmarshal); err != nil {
+		t.Fatal("load conf fail", err)
+	}
+	return eredis.Load("redis").Build()
+}
+
+func TestLimiters(t *testing.T) {
+	cmp := newCmp(t)
+	ctx := context.Background()
+	limiters := map[string]Limiter{
+		"gcra":           NewGCRA(cmp, PerSecond(5)),
+		"sliding_window": NewSlidingWindow(cmp, PerSecond(5)),
+		"token_bucket":   NewTokenBucket(cmp, PerSecond(5)),
+	}
+	for name, limiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			key := "test:" + name + ":" + time.Now().String()
+			for i := int64(0); i < 5; i++ {
+				res, err := limiter.Allow(ctx, key)
+				assert.NoError(t, err)
+				assert.True(t, res.Allowed)
+				assert.Equal(t, 4-i, res.Remaining)
+			}
+			res, err := limiter.Allow(ctx, key)
+			assert.NoError(t, err)
+			assert.False(t, res.Allowed)
+			assert.True(t, res.RetryAfter > 0 && res.RetryAfter <= time.Second)
+
+			res, err = limiter.AllowN(ctx, key, 6)
+			assert.NoError(t, err)
+			assert.False(t, res.Allowed)
+			assert.Equal(t, time.Duration(-1), res.RetryAfter)
+
+			time.Sleep(res.ResetAfter + 10*time.Millisecond)
+			res, err = limiter.Allow(ctx, key)
+			assert.NoError(t, err)
+			assert.True(t, res.Allowed)
+		})
+	}
+}
+
+func TestLimiterKey(t *testing.T) {
+	l := newLimiter(nil, PerSecond(1), luaGCRA)
+	assert.Equal(t, "ratelimit:{user:1}", l.key("user:1"))
+	assert.Equal(t, "ratelimit:user:{1}", l.key("user:{1}"))
+	assert.Equal(t, int64(1), l.limit.Burst)
+}
+
+type fakeLimiter struct {
+	res *Result
+}
+
+func (f fakeLimiter) Allow(ctx context.Context, key string) (*Result, error) {
+	return f.res, nil
+}
+
+func (f fakeLimiter) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	return f.res, nil
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := fakeLimiter{res: &Result{Limit: PerSecond(10), Allowed: false, RetryAfter: 1500 * time.Millisecond, ResetAfter: 2 * time.Second}}
+	r := gin.New()
+	r.Use(GinMiddleware(limiter, nil))
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.Equal(t, "10", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	limiter.res.Allowed = true
+	limiter.res.Remaining = 9
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "", w.Header().Get("Retry-After"))
+	assert.Equal(t, "9", w.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	limiter := fakeLimiter{res: &Result{Limit: PerSecond(10), Allowed: false, RetryAfter: time.Second}}
+	interceptor := UnaryServerInterceptor(limiter, nil)
+	info := &grpc.UnaryServerInfo{FullMethod: "/helloworld.Greeter/SayHello"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	_, err := interceptor(context.Background(), nil, info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	limiter.res.Allowed = true
+	resp, err := interceptor(context.Background(), nil, info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+}
+
+func TestGrpcKeyFunc(t *testing.T) {
+	keyFunc := grpcKeyFunc(nil)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}})
+	assert.Equal(t, "10.0.0.1", keyFunc(ctx, "/helloworld.Greeter/SayHello"))
+	// 没有对端地址时使用方法名
+	assert.Equal(t, "/helloworld.Greeter/SayHello", keyFunc(context.Background(), "/helloworld.Greeter/SayHello"))
+}
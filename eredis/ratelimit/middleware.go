@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotomicro/ego/core/elog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var logger = elog.EgoLogger.With(elog.FieldComponent(PackageName))
+
+// GinKeyFunc returns the rate limit key of a http request.
+type GinKeyFunc = func(c *gin.Context) string
+
+// GrpcKeyFunc returns the rate limit key of a grpc request.
+type GrpcKeyFunc = func(ctx context.Context, fullMethod string) string
+
+// GinMiddleware limits http requests by the key returned by keyFunc, the client ip is used when keyFunc is nil.
+// Rejected requests get 429, requests pass when the limiter fails.
+// Use it with egin: server.Use(ratelimit.GinMiddleware(limiter, nil))
+func GinMiddleware(limiter Limiter, keyFunc GinKeyFunc) gin.HandlerFunc {
+	if keyFunc == nil {
+		keyFunc = func(c *gin.Context) string {
+			return c.ClientIP()
+		}
+	}
+	return func(c *gin.Context) {
+		res, err := limiter.Allow(c.Request.Context(), keyFunc(c))
+		if err != nil {
+			logger.Error("rate limit fail", elog.FieldErr(err), elog.FieldMethod(c.Request.Method+"."+c.FullPath()))
+			c.Next()
+			return
+		}
+		for k, v := range headers(res) {
+			c.Header(k, v)
+		}
+		if !res.Allowed {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+		c.Next()
+	}
+}
+
+// UnaryServerInterceptor limits unary grpc requests by the key returned by keyFunc, the peer ip is used when keyFunc is nil,
+// requests without a peer are limited by the full method name.
+// Rejected requests get codes.ResourceExhausted, requests pass when the limiter fails.
+// Use it with egrpc: egrpc.WithUnaryInterceptor(ratelimit.UnaryServerInterceptor(limiter, nil))
+func UnaryServerInterceptor(limiter Limiter, keyFunc GrpcKeyFunc) grpc.UnaryServerInterceptor {
+	keyFunc = grpcKeyFunc(keyFunc)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := allowGrpc(ctx, limiter, keyFunc(ctx, info.FullMethod), info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor limits grpc streams by the key returned by keyFunc, the peer ip is used when keyFunc is nil,
+// streams without a peer are limited by the full method name.
+// Use it with egrpc: egrpc.WithStreamInterceptor(ratelimit.StreamServerInterceptor(limiter, nil))
+func StreamServerInterceptor(limiter Limiter, keyFunc GrpcKeyFunc) grpc.StreamServerInterceptor {
+	keyFunc = grpcKeyFunc(keyFunc)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		if err := allowGrpc(ctx, limiter, keyFunc(ctx, info.FullMethod), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func grpcKeyFunc(keyFunc GrpcKeyFunc) GrpcKeyFunc {
+	if keyFunc != nil {
+		return keyFunc
+	}
+	return func(ctx context.Context, fullMethod string) string {
+		// 没有对端地址时按方法限流，避免所有请求共用一个空 key
+		p, ok := peer.FromContext(ctx)
+		if !ok || p.Addr == nil {
+			return fullMethod
+		}
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			return p.Addr.String()
+		}
+		return host
+	}
+}
+
+func allowGrpc(ctx context.Context, limiter Limiter, key, fullMethod string) error {
+	res, err := limiter.Allow(ctx, key)
+	if err != nil {
+		logger.Error("rate limit fail", elog.FieldErr(err), elog.FieldMethod(fullMethod))
+		return nil
+	}
+	md := metadata.MD{}
+	for k, v := range headers(res) {
+		md.Set(k, v)
+	}
+	_ = grpc.SetHeader(ctx, md)
+	if !res.Allowed {
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %s", res.RetryAfter)
+	}
+	return nil
+}
+
+// headers returns the conventional rate limit headers of the result.
+func headers(res *Result) map[string]string {
+	h := map[string]string{
+		"X-RateLimit-Limit":     strconv.FormatInt(res.Limit.Burst, 10),
+		"X-RateLimit-Remaining": strconv.FormatInt(res.Remaining, 10),
+		"X-RateLimit-Reset":     strconv.FormatInt(ceilSeconds(res.ResetAfter), 10),
+	}
+	if !res.Allowed && res.RetryAfter >= 0 {
+		h["Retry-After"] = strconv.FormatInt(ceilSeconds(res.RetryAfter), 10)
+	}
+	return h
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
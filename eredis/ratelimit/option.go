@@ -0,0 +1,16 @@
+package ratelimit
+
+type Option func(o *option)
+
+type option struct {
+	// keyPrefix is prepended to every key.
+	// Default: "ratelimit:"
+	keyPrefix string
+}
+
+// WithKeyPrefix 设置 key 前缀
+func WithKeyPrefix(prefix string) Option {
+	return func(o *option) {
+		o.keyPrefix = prefix
+	}
+}
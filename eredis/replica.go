@@ -0,0 +1,279 @@
+package eredis
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gotomicro/ego/core/elog"
+)
+
+const (
+	// ReplicaPolicyRandom 随机选择从节点
+	ReplicaPolicyRandom = "random"
+	// ReplicaPolicyLatency 选择延迟最低的从节点
+	ReplicaPolicyLatency = "latency"
+)
+
+type readFromMasterKeyType struct{}
+
+var readFromMasterKey = readFromMasterKeyType{}
+
+type nodeContextKeyType struct{}
+
+var ctxNodeKey = nodeContextKeyType{}
+
+// WithReadFromMaster forces the read-only commands of ctx to be served by the master, e.g. to read your own writes.
+func WithReadFromMaster(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readFromMasterKey, true)
+}
+
+func isReadFromMaster(ctx context.Context) bool {
+	v, _ := ctx.Value(readFromMasterKey).(bool)
+	return v
+}
+
+// reader returns the client serving read-only commands, a replica is picked when replica routing is enabled.
+func (r *Component) reader(ctx context.Context) redis.Cmdable {
+	if r.replicas == nil || isReadFromMaster(ctx) {
+		return r.client
+	}
+	if node := r.replicas.pick(); node != nil {
+		return node.client
+	}
+	return r.client
+}
+
+// nodeInterceptor records the address of the node serving the command, so that the access log shows it.
+func nodeInterceptor(addr func() string) *interceptor {
+	return newInterceptor("", nil, nil).setBeforeProcess(func(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+		return context.WithValue(ctx, ctxNodeKey, addr()), nil
+	})
+}
+
+type replicaNode struct {
+	addr    string
+	client  *redis.Client
+	probe   *redis.Client
+	latency int64 // 探测延迟的滑动平均值，单位ns
+	healthy int32
+}
+
+// replicaRouter routes read-only commands of stub and sentinel modes to replicas.
+// Replicas are the Replicas config in stub mode, or discovered from sentinels in sentinel mode.
+// Replicas are probed periodically, failed replicas are skipped until they recover.
+type replicaRouter struct {
+	config     *config
+	logger     *elog.Component
+	newClient  func(addr string) *redis.Client // newClient 创建不带拦截器的从节点客户端
+	sentinels  []*redis.SentinelClient
+	masterAddr atomic.Value
+	mu         sync.RWMutex
+	nodes      []*replicaNode
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+func newReplicaRouter(config *config, logger *elog.Component, newClient func(addr string) *redis.Client) *replicaRouter {
+	rr := &replicaRouter{
+		config:    config,
+		logger:    logger,
+		newClient: newClient,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	rr.masterAddr.Store(config.Addr)
+	if config.Mode == SentinelMode {
+		for _, addr := range config.Addrs {
+			rr.sentinels = append(rr.sentinels, redis.NewSentinelClient(&redis.Options{
+				Addr:         addr,
+				DialTimeout:  config.DialTimeout,
+				ReadTimeout:  config.ReadTimeout,
+				WriteTimeout: config.WriteTimeout,
+				PoolSize:     1,
+			}))
+		}
+		rr.discover()
+	} else {
+		rr.setNodes(config.Replicas)
+	}
+	rr.probeNodes()
+	go rr.run()
+	return rr
+}
+
+// master returns the address of the master, used by the access log.
+func (rr *replicaRouter) master() string {
+	return rr.masterAddr.Load().(string)
+}
+
+// pick returns a healthy replica by the policy, nil if there is none.
+func (rr *replicaRouter) pick() *replicaNode {
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+	var (
+		best    *replicaNode
+		healthy = make([]*replicaNode, 0, len(rr.nodes))
+	)
+	for _, node := range rr.nodes {
+		if atomic.LoadInt32(&node.healthy) == 0 {
+			continue
+		}
+		healthy = append(healthy, node)
+		if best == nil || atomic.LoadInt64(&node.latency) < atomic.LoadInt64(&best.latency) {
+			best = node
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	if rr.config.ReplicaPolicy == ReplicaPolicyLatency {
+		return best
+	}
+	return healthy[rand.Intn(len(healthy))]
+}
+
+func (rr *replicaRouter) run() {
+	defer close(rr.done)
+	ticker := time.NewTicker(rr.config.ReplicaRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rr.stop:
+			return
+		case <-ticker.C:
+		}
+		if len(rr.sentinels) > 0 {
+			rr.discover()
+		}
+		rr.probeNodes()
+	}
+}
+
+// discover refreshes the master and replicas from the first available sentinel.
+func (rr *replicaRouter) discover() {
+	ctx, cancel := context.WithTimeout(context.Background(), rr.config.ReadTimeout)
+	defer cancel()
+	var err error
+	for _, sentinel := range rr.sentinels {
+		var master []string
+		master, err = sentinel.GetMasterAddrByName(ctx, rr.config.MasterName).Result()
+		if err != nil {
+			continue
+		}
+		var replicas []interface{}
+		replicas, err = sentinel.Slaves(ctx, rr.config.MasterName).Result()
+		if err != nil {
+			continue
+		}
+		if len(master) == 2 {
+			rr.masterAddr.Store(net.JoinHostPort(master[0], master[1]))
+		}
+		rr.setNodes(parseSentinelReplicas(replicas))
+		return
+	}
+	rr.logger.Error("discover replicas fail", elog.FieldErr(err))
+}
+
+// parseSentinelReplicas returns the addresses of the replicas which are not down or disconnected.
+func parseSentinelReplicas(replicas []interface{}) []string {
+	addrs := make([]string, 0, len(replicas))
+	for _, replica := range replicas {
+		fields, ok := replica.([]interface{})
+		if !ok {
+			continue
+		}
+		info := make(map[string]string, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			k, _ := fields[i].(string)
+			v, _ := fields[i+1].(string)
+			info[k] = v
+		}
+		flags := info["flags"]
+		if strings.Contains(flags, "s_down") || strings.Contains(flags, "o_down") || strings.Contains(flags, "disconnected") {
+			continue
+		}
+		addrs = append(addrs, net.JoinHostPort(info["ip"], info["port"]))
+	}
+	return addrs
+}
+
+// setNodes replaces the replicas, clients of existing replicas are reused.
+func (rr *replicaRouter) setNodes(addrs []string) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	current := make(map[string]*replicaNode, len(rr.nodes))
+	for _, node := range rr.nodes {
+		current[node.addr] = node
+	}
+	nodes := make([]*replicaNode, 0, len(addrs))
+	for _, addr := range addrs {
+		if node, ok := current[addr]; ok {
+			nodes = append(nodes, node)
+			delete(current, addr)
+			continue
+		}
+		client := rr.newClient(addr)
+		node := &replicaNode{
+			addr: addr,
+			// 探测使用不带拦截器的副本，避免探测请求出现在日志和监控中，两者共用连接池
+			probe:   client.WithContext(context.Background()),
+			client:  client,
+			healthy: 1,
+		}
+		client.AddHook(nodeInterceptor(func() string { return addr }))
+		for _, incpt := range rr.config.interceptors {
+			client.AddHook(incpt)
+		}
+		nodes = append(nodes, node)
+	}
+	rr.nodes = nodes
+	for _, node := range current {
+		_ = node.client.Close()
+	}
+}
+
+// probeNodes pings every replica, marks the health and updates the latency moving average.
+func (rr *replicaRouter) probeNodes() {
+	rr.mu.RLock()
+	nodes := rr.nodes
+	rr.mu.RUnlock()
+	for _, node := range nodes {
+		ctx, cancel := context.WithTimeout(context.Background(), rr.config.ReadTimeout)
+		start := time.Now()
+		err := node.probe.Ping(ctx).Err()
+		cancel()
+		if err != nil {
+			if atomic.SwapInt32(&node.healthy, 0) == 1 {
+				rr.logger.Warn("replica unhealthy", elog.FieldAddr(node.addr), elog.FieldErr(err))
+			}
+			continue
+		}
+		cost := int64(time.Since(start))
+		if old := atomic.LoadInt64(&node.latency); old > 0 {
+			cost = (old*7 + cost*3) / 10
+		}
+		atomic.StoreInt64(&node.latency, cost)
+		atomic.StoreInt32(&node.healthy, 1)
+	}
+}
+
+func (rr *replicaRouter) Close() error {
+	close(rr.stop)
+	<-rr.done
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	for _, node := range rr.nodes {
+		_ = node.client.Close()
+	}
+	for _, sentinel := range rr.sentinels {
+		_ = sentinel.Close()
+	}
+	rr.nodes = nil
+	return nil
+}
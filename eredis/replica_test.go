@@ -0,0 +1,78 @@
+package eredis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicaRouting(t *testing.T) {
+	master, replica := miniredis.RunT(t), miniredis.RunT(t)
+	load := func(name, routing string) *Component {
+		conf := fmt.Sprintf(`
+[%s]
+	addr = "%s"
+	readOnly = true
+	%s
+	replicas = ["%s"]
+	replicaPolicy = "latency"
+	enableAccessInterceptor = true
+`, name, master.Addr(), routing, replica.Addr())
+		if err := econf.LoadFromReader(strings.NewReader(conf), toml.Unmarshal); err != nil {
+			t.Fatal("load conf fail", err)
+		}
+		return Load(name).Build()
+	}
+	ctx := context.Background()
+
+	// 写入主节点和从节点不同的值，区分读取的节点
+	assert.NoError(t, master.Set("replica-key", "master"))
+	assert.NoError(t, replica.Set("replica-key", "replica"))
+
+	// 只配置 readOnly 时不路由到从节点
+	cmp := load("redisReadOnly", "")
+	defer cmp.Close()
+	val, err := cmp.Get(ctx, "replica-key")
+	assert.NoError(t, err)
+	assert.Equal(t, "master", val)
+
+	cmp = load("redisReplica", "enableReplicaRouting = true")
+	defer cmp.Close()
+	val, err = cmp.Get(ctx, "replica-key")
+	assert.NoError(t, err)
+	assert.Equal(t, "replica", val)
+
+	val, err = cmp.Get(WithReadFromMaster(ctx), "replica-key")
+	assert.NoError(t, err)
+	assert.Equal(t, "master", val)
+}
+
+func TestReplicaPick(t *testing.T) {
+	rr := &replicaRouter{config: DefaultConfig()}
+	assert.Nil(t, rr.pick())
+
+	rr.nodes = []*replicaNode{
+		{addr: "a", latency: 30, healthy: 1},
+		{addr: "b", latency: 10, healthy: 0},
+		{addr: "c", latency: 20, healthy: 1},
+	}
+	for i := 0; i < 10; i++ {
+		assert.NotEqual(t, "b", rr.pick().addr)
+	}
+	rr.config.ReplicaPolicy = ReplicaPolicyLatency
+	assert.Equal(t, "c", rr.pick().addr)
+}
+
+func TestParseSentinelReplicas(t *testing.T) {
+	replicas := []interface{}{
+		[]interface{}{"name", "10.0.0.2:6379", "ip", "10.0.0.2", "port", "6379", "flags", "slave"},
+		[]interface{}{"name", "10.0.0.3:6379", "ip", "10.0.0.3", "port", "6379", "flags", "s_down,slave,disconnected"},
+	}
+	assert.Equal(t, []string{"10.0.0.2:6379"}, parseSentinelReplicas(replicas))
+}
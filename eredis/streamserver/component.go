@@ -0,0 +1,330 @@
+package streamserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gotomicro/ego/core/constant"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/gotomicro/ego/server"
+
+	"github.com/gotomicro/ego-component/eredis"
+)
+
+// Interface check
+var _ server.Server = (*Component)(nil)
+
+// PackageName is the name of this component.
+const PackageName = "component.eredis.streamserver"
+
+// Component starts an Ego server consuming redis streams with a consumer group.
+type Component struct {
+	ServerCtx            context.Context
+	stopServer           context.CancelFunc
+	name                 string
+	config               *config
+	eredisComponent      *eredis.Component
+	logger               *elog.Component
+	onEachMessageHandler OnEachMessageHandler
+	wg                   sync.WaitGroup
+}
+
+func newComponent(name string, config *config, eredisComponent *eredis.Component, logger *elog.Component) *Component {
+	serverCtx, stopServer := context.WithCancel(context.Background())
+	return &Component{
+		ServerCtx:       serverCtx,
+		stopServer:      stopServer,
+		name:            name,
+		config:          config,
+		eredisComponent: eredisComponent,
+		logger:          logger,
+	}
+}
+
+// PackageName returns the package name.
+func (cmp *Component) PackageName() string {
+	return PackageName
+}
+
+// Info returns server info, used by governor and consumer balancer.
+func (cmp *Component) Info() *server.ServiceInfo {
+	info := server.ApplyOptions(
+		server.WithKind(constant.ServiceProvider),
+	)
+	return &info
+}
+
+// GracefulStop stops reading and waits for in-flight messages to be handled.
+// The ctx of the handler is not cancelled, so in-flight messages can still be handled and acked.
+func (cmp *Component) GracefulStop(ctx context.Context) error {
+	cmp.stopServer()
+	done := make(chan struct{})
+	go func() {
+		cmp.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop stops the server.
+func (cmp *Component) Stop() error {
+	cmp.stopServer()
+	return nil
+}
+
+// Init ...
+func (cmp *Component) Init() error {
+	return nil
+}
+
+// Name returns the name of this instance.
+func (cmp *Component) Name() string {
+	return cmp.name
+}
+
+// OnEachMessage registers the message handler.
+func (cmp *Component) OnEachMessage(handler OnEachMessageHandler) error {
+	cmp.onEachMessageHandler = handler
+	return nil
+}
+
+// Start creates the consumer group if needed and starts consuming.
+func (cmp *Component) Start() error {
+	if cmp.onEachMessageHandler == nil {
+		return errors.New("you must define a MessageHandler first")
+	}
+	for _, stream := range cmp.config.Streams {
+		err := cmp.eredisComponent.XGroupCreateMkStream(cmp.ServerCtx, stream, cmp.config.Group, cmp.config.StartID)
+		// 消费组已存在
+		if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+			return err
+		}
+	}
+
+	cmp.wg.Add(1)
+	go cmp.consume()
+	if cmp.config.ClaimMinIdle > 0 {
+		cmp.wg.Add(1)
+		go cmp.reclaim()
+	}
+
+	<-cmp.ServerCtx.Done()
+	cmp.wg.Wait()
+	cmp.logger.Info("stream consumer stopped")
+	return nil
+}
+
+func (cmp *Component) consume() {
+	defer cmp.wg.Done()
+
+	// 先处理本消费者在重启前已读取但未确认的消息
+	for _, stream := range cmp.config.Streams {
+		cmp.consumePending(stream)
+	}
+
+	streams := make([]string, 0, 2*len(cmp.config.Streams))
+	streams = append(streams, cmp.config.Streams...)
+	for range cmp.config.Streams {
+		streams = append(streams, ">")
+	}
+	for cmp.ServerCtx.Err() == nil {
+		res, err := cmp.eredisComponent.XReadGroup(cmp.ServerCtx, &redis.XReadGroupArgs{
+			Group:    cmp.config.Group,
+			Consumer: cmp.config.Consumer,
+			Streams:  streams,
+			Count:    cmp.config.Count,
+			Block:    cmp.config.Block,
+		})
+		if err != nil {
+			if errors.Is(err, redis.Nil) || cmp.ServerCtx.Err() != nil {
+				continue
+			}
+			cmp.logger.Error("encountered an error while reading stream", elog.FieldErr(err))
+			cmp.sleep(time.Second)
+			continue
+		}
+		for _, xStream := range res {
+			for _, message := range xStream.Messages {
+				cmp.handle(xStream.Stream, message)
+			}
+		}
+	}
+}
+
+// consumePending handles the pending messages of this consumer once.
+func (cmp *Component) consumePending(stream string) {
+	id := "0"
+	for cmp.ServerCtx.Err() == nil {
+		res, err := cmp.eredisComponent.XReadGroup(cmp.ServerCtx, &redis.XReadGroupArgs{
+			Group:    cmp.config.Group,
+			Consumer: cmp.config.Consumer,
+			Streams:  []string{stream, id},
+			Count:    cmp.config.Count,
+			Block:    -1,
+		})
+		if err != nil {
+			if !errors.Is(err, redis.Nil) {
+				cmp.logger.Error("encountered an error while reading pending messages", elog.FieldErr(err), elog.String("stream", stream))
+			}
+			return
+		}
+		if len(res) == 0 || len(res[0].Messages) == 0 {
+			return
+		}
+		for _, message := range res[0].Messages {
+			cmp.handle(stream, message)
+			id = message.ID
+		}
+	}
+}
+
+// reclaim periodically claims messages which stay pending on other consumers longer than ClaimMinIdle.
+func (cmp *Component) reclaim() {
+	defer cmp.wg.Done()
+
+	ticker := time.NewTicker(cmp.config.ClaimInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cmp.ServerCtx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, stream := range cmp.config.Streams {
+			cmp.reclaimStream(stream)
+		}
+	}
+}
+
+// reclaimStream claims idle pending messages page by page.
+// go-redis v8 can not parse the XAUTOCLAIM reply of redis 7, so XPENDING IDLE and XCLAIM are used instead.
+func (cmp *Component) reclaimStream(stream string) {
+	for cmp.ServerCtx.Err() == nil {
+		pending, err := cmp.eredisComponent.XPendingExt(cmp.ServerCtx, &redis.XPendingExtArgs{
+			Stream: stream,
+			Group:  cmp.config.Group,
+			Idle:   cmp.config.ClaimMinIdle,
+			Start:  "-",
+			End:    "+",
+			Count:  cmp.config.Count,
+		})
+		if err != nil {
+			cmp.logger.Error("encountered an error while reading pending messages", elog.FieldErr(err), elog.String("stream", stream))
+			return
+		}
+		if len(pending) == 0 {
+			return
+		}
+		ids := make([]string, 0, len(pending))
+		var deadIDs []string
+		for _, p := range pending {
+			// 超过最大投递次数的消息不再处理
+			if cmp.config.MaxDeliveries > 0 && p.RetryCount >= cmp.config.MaxDeliveries {
+				deadIDs = append(deadIDs, p.ID)
+				continue
+			}
+			ids = append(ids, p.ID)
+		}
+		messages, ok := cmp.claim(stream, ids)
+		if !ok {
+			return
+		}
+		for _, message := range messages {
+			cmp.handle(stream, message)
+		}
+		deadMessages, ok := cmp.claim(stream, deadIDs)
+		if !ok {
+			return
+		}
+		for _, message := range deadMessages {
+			cmp.deadLetter(stream, message)
+		}
+		if len(messages)+len(deadMessages) == 0 || int64(len(pending)) < cmp.config.Count {
+			return
+		}
+	}
+}
+
+// claim claims the idle pending messages, XCLAIM checks the idle time again to avoid claiming with other consumers.
+func (cmp *Component) claim(stream string, ids []string) ([]redis.XMessage, bool) {
+	if len(ids) == 0 {
+		return nil, true
+	}
+	messages, err := cmp.eredisComponent.XClaim(cmp.ServerCtx, &redis.XClaimArgs{
+		Stream:   stream,
+		Group:    cmp.config.Group,
+		Consumer: cmp.config.Consumer,
+		MinIdle:  cmp.config.ClaimMinIdle,
+		Messages: ids,
+	})
+	if err != nil {
+		cmp.logger.Error("encountered an error while claiming pending messages", elog.FieldErr(err), elog.String("stream", stream))
+		return nil, false
+	}
+	return messages, true
+}
+
+// deadLetter writes a message exceeding MaxDeliveries to DeadLetterStream and acks it.
+// The source stream and message id are recorded in the sourceStream and sourceID fields.
+func (cmp *Component) deadLetter(stream string, message redis.XMessage) {
+	if message.Values != nil && cmp.config.DeadLetterStream != "" {
+		values := make(map[string]interface{}, len(message.Values)+2)
+		for k, v := range message.Values {
+			values[k] = v
+		}
+		values["sourceStream"] = stream
+		values["sourceID"] = message.ID
+		if _, err := cmp.eredisComponent.XAdd(context.Background(), &redis.XAddArgs{Stream: cmp.config.DeadLetterStream, Values: values}); err != nil {
+			// 写入失败时不确认，等待下次认领
+			cmp.logger.Error("encountered an error while writing dead letter", elog.FieldErr(err), elog.String("stream", stream), elog.String("id", message.ID))
+			return
+		}
+	}
+	emetric.ServerHandleCounter.Inc(emetric.TypeRedis, stream, cmp.config.Group, "DeadLetter", "")
+	cmp.logger.Error("message exceeded max deliveries", elog.String("stream", stream), elog.String("id", message.ID), elog.String("deadLetterStream", cmp.config.DeadLetterStream))
+	cmp.ack(stream, message.ID)
+}
+
+func (cmp *Component) handle(stream string, message redis.XMessage) {
+	// 消息已被删除，只需要确认
+	if message.Values == nil {
+		cmp.ack(stream, message.ID)
+		return
+	}
+
+	now := time.Now()
+	// GracefulStop 等待处理中的消息，处理消息不使用 ServerCtx
+	err := cmp.onEachMessageHandler(context.Background(), stream, message)
+	emetric.ServerHandleHistogram.WithLabelValues(emetric.TypeRedis, stream, cmp.config.Group).Observe(time.Since(now).Seconds())
+	if err != nil {
+		emetric.ServerHandleCounter.Inc(emetric.TypeRedis, stream, cmp.config.Group, "Error", "")
+		cmp.logger.Error("encountered an error while handling message", elog.FieldErr(err), elog.String("stream", stream), elog.String("id", message.ID))
+		return
+	}
+	emetric.ServerHandleCounter.Inc(emetric.TypeRedis, stream, cmp.config.Group, "OK", "")
+	cmp.ack(stream, message.ID)
+}
+
+func (cmp *Component) ack(stream, id string) {
+	// 服务停止时已处理完的消息仍需确认，不使用 ServerCtx
+	if _, err := cmp.eredisComponent.XAck(context.Background(), stream, cmp.config.Group, id); err != nil {
+		cmp.logger.Error("encountered an error while acking message", elog.FieldErr(err), elog.String("stream", stream), elog.String("id", id))
+	}
+}
+
+func (cmp *Component) sleep(d time.Duration) {
+	select {
+	case <-cmp.ServerCtx.Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,200 @@
+package streamserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotomicro/ego-component/eredis"
+)
+
+func newTestComponent(t *testing.T, options ...Option) (*Component, *eredis.Component) {
+	mr := miniredis.RunT(t)
+	redisCmp := eredis.DefaultContainer().Build(eredis.WithStub(), eredis.WithAddr(mr.Addr()))
+	t.Cleanup(func() { _ = redisCmp.Close() })
+
+	c := DefaultContainer()
+	c.config.Block = 50 * time.Millisecond
+	c.config.ClaimMinIdle = 0
+	return c.Build(append([]Option{WithEredis(redisCmp), WithStreams("orders"), WithGroup("billing"), WithConsumer("c1")}, options...)...), redisCmp
+}
+
+func start(t *testing.T, cmp *Component) <-chan error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cmp.Start()
+	}()
+	// 等待消费组创建完成
+	require.Eventually(t, func() bool {
+		_, err := cmp.eredisComponent.Client().XPending(context.Background(), "orders", "billing").Result()
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	return errCh
+}
+
+func pending(t *testing.T, redisCmp *eredis.Component) int64 {
+	res, err := redisCmp.Client().XPending(context.Background(), "orders", "billing").Result()
+	require.NoError(t, err)
+	return res.Count
+}
+
+func TestComponentHandle(t *testing.T) {
+	cmp, redisCmp := newTestComponent(t)
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	require.NoError(t, cmp.OnEachMessage(func(ctx context.Context, stream string, message redis.XMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, stream+":"+message.Values["id"].(string))
+		return nil
+	}))
+	errCh := start(t, cmp)
+
+	for _, id := range []string{"1", "2"} {
+		_, err := redisCmp.Client().XAdd(context.Background(), &redis.XAddArgs{Stream: "orders", Values: map[string]interface{}{"id": id}}).Result()
+		require.NoError(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"orders:1", "orders:2"}, received)
+	// 处理成功的消息已确认
+	assert.Equal(t, int64(0), pending(t, redisCmp))
+
+	require.NoError(t, cmp.GracefulStop(context.Background()))
+	select {
+	case err := <-errCh:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server not stopped")
+	}
+}
+
+func TestComponentHandleError(t *testing.T) {
+	cmp, redisCmp := newTestComponent(t)
+	handled := make(chan struct{}, 1)
+	require.NoError(t, cmp.OnEachMessage(func(ctx context.Context, stream string, message redis.XMessage) error {
+		handled <- struct{}{}
+		return errors.New("handle fail")
+	}))
+	start(t, cmp)
+	defer cmp.Stop()
+
+	_, err := redisCmp.Client().XAdd(context.Background(), &redis.XAddArgs{Stream: "orders", Values: map[string]interface{}{"id": "1"}}).Result()
+	require.NoError(t, err)
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("message not handled")
+	}
+	// 处理失败的消息不确认，保留在待确认列表中
+	assert.Never(t, func() bool {
+		return pending(t, redisCmp) != 1
+	}, 200*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestComponentReclaim(t *testing.T) {
+	cmp, redisCmp := newTestComponent(t)
+	cmp.config.ClaimMinIdle = 50 * time.Millisecond
+	cmp.config.ClaimInterval = 50 * time.Millisecond
+	var (
+		mu    sync.Mutex
+		tries int
+	)
+	require.NoError(t, cmp.OnEachMessage(func(ctx context.Context, stream string, message redis.XMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		tries++
+		if tries == 1 {
+			return errors.New("handle fail")
+		}
+		return nil
+	}))
+	start(t, cmp)
+	defer cmp.Stop()
+
+	_, err := redisCmp.Client().XAdd(context.Background(), &redis.XAddArgs{Stream: "orders", Values: map[string]interface{}{"id": "1"}}).Result()
+	require.NoError(t, err)
+	// 失败的消息空闲超过 ClaimMinIdle 后被重新认领处理
+	assert.Eventually(t, func() bool {
+		return pending(t, redisCmp) == 0
+	}, 2*time.Second, 20*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, tries)
+}
+
+func TestComponentDeadLetter(t *testing.T) {
+	cmp, redisCmp := newTestComponent(t, WithMaxDeliveries(2), WithDeadLetterStream("orders-dead"))
+	cmp.config.ClaimMinIdle = 50 * time.Millisecond
+	cmp.config.ClaimInterval = 50 * time.Millisecond
+	var (
+		mu    sync.Mutex
+		tries int
+	)
+	require.NoError(t, cmp.OnEachMessage(func(ctx context.Context, stream string, message redis.XMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		tries++
+		return errors.New("handle fail")
+	}))
+	start(t, cmp)
+	defer cmp.Stop()
+
+	id, err := redisCmp.Client().XAdd(context.Background(), &redis.XAddArgs{Stream: "orders", Values: map[string]interface{}{"id": "1"}}).Result()
+	require.NoError(t, err)
+	// 超过最大投递次数后写入死信 stream 并确认
+	assert.Eventually(t, func() bool {
+		return pending(t, redisCmp) == 0
+	}, 2*time.Second, 20*time.Millisecond)
+	messages, err := redisCmp.Client().XRange(context.Background(), "orders-dead", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, map[string]interface{}{"id": "1", "sourceStream": "orders", "sourceID": id}, messages[0].Values)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, tries)
+}
+
+func TestComponentGracefulStop(t *testing.T) {
+	cmp, redisCmp := newTestComponent(t)
+	handling := make(chan struct{})
+	ctxErr := make(chan error, 1)
+	require.NoError(t, cmp.OnEachMessage(func(ctx context.Context, stream string, message redis.XMessage) error {
+		close(handling)
+		// 等待 GracefulStop 开始
+		<-cmp.ServerCtx.Done()
+		ctxErr <- ctx.Err()
+		return nil
+	}))
+	errCh := start(t, cmp)
+
+	_, err := redisCmp.Client().XAdd(context.Background(), &redis.XAddArgs{Stream: "orders", Values: map[string]interface{}{"id": "1"}}).Result()
+	require.NoError(t, err)
+	select {
+	case <-handling:
+	case <-time.After(time.Second):
+		t.Fatal("message not handled")
+	}
+	require.NoError(t, cmp.GracefulStop(context.Background()))
+	// 处理中的消息的 ctx 不会因停止而取消，处理完成后确认
+	assert.NoError(t, <-ctxErr)
+	assert.Equal(t, int64(0), pending(t, redisCmp))
+	assert.NoError(t, <-errCh)
+}
+
+func TestComponentStart(t *testing.T) {
+	cmp, _ := newTestComponent(t)
+	assert.EqualError(t, cmp.Start(), "you must define a MessageHandler first")
+}
@@ -0,0 +1,36 @@
+package streamserver
+
+import (
+	"time"
+
+	"github.com/gotomicro/ego/core/eapp"
+	"github.com/gotomicro/ego/core/util/xtime"
+
+	"github.com/gotomicro/ego-component/eredis"
+)
+
+type config struct {
+	Streams          []string      `json:"streams" toml:"streams"`                   // Streams 消费的 stream 列表
+	Group            string        `json:"group" toml:"group"`                       // Group 消费组名称
+	Consumer         string        `json:"consumer" toml:"consumer"`                 // Consumer 消费者名称，默认为实例名称
+	StartID          string        `json:"startID" toml:"startID"`                   // StartID 创建消费组时的起始消息ID，默认 "$" 只消费新消息
+	Count            int64         `json:"count" toml:"count"`                       // Count 每次读取的最大消息数，默认 10
+	Block            time.Duration `json:"block" toml:"block"`                       // Block 读取消息的最大阻塞时间，默认 2s
+	ClaimMinIdle     time.Duration `json:"claimMinIdle" toml:"claimMinIdle"`         // ClaimMinIdle 待确认消息空闲超过该时间后会被当前消费者认领重新处理，默认 1m，为 0 时不认领
+	ClaimInterval    time.Duration `json:"claimInterval" toml:"claimInterval"`       // ClaimInterval 扫描待确认消息的间隔，默认 30s
+	MaxDeliveries    int64         `json:"maxDeliveries" toml:"maxDeliveries"`       // MaxDeliveries 消息的最大投递次数，超过后不再认领，写入死信 stream 并确认，默认 0 不限制
+	DeadLetterStream string        `json:"deadLetterStream" toml:"deadLetterStream"` // DeadLetterStream 死信 stream，为空时超过最大投递次数的消息只记录日志并确认
+	eredisComponent  *eredis.Component
+}
+
+// DefaultConfig returns a default config.
+func DefaultConfig() *config {
+	return &config{
+		Consumer:      eapp.AppInstance(),
+		StartID:       "$",
+		Count:         10,
+		Block:         xtime.Duration("2s"),
+		ClaimMinIdle:  xtime.Duration("1m"),
+		ClaimInterval: xtime.Duration("30s"),
+	}
+}
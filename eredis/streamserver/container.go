@@ -0,0 +1,53 @@
+package streamserver
+
+import (
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/gotomicro/ego/core/elog"
+)
+
+type Option func(c *Container)
+
+type Container struct {
+	name   string
+	config *config
+	logger *elog.Component
+}
+
+// DefaultContainer 返回默认Container
+func DefaultContainer() *Container {
+	return &Container{
+		config: DefaultConfig(),
+		logger: elog.EgoLogger.With(elog.FieldComponent(PackageName)),
+	}
+}
+
+// Load 载入配置，初始化Container
+func Load(key string) *Container {
+	c := DefaultContainer()
+	if err := econf.UnmarshalKey(key, &c.config); err != nil {
+		c.logger.Panic("parse config error", elog.FieldErr(err), elog.FieldKey(key))
+		return c
+	}
+
+	c.logger = c.logger.With(elog.FieldComponentName(key))
+	c.name = key
+	return c
+}
+
+// Build 构建Component
+func (c *Container) Build(options ...Option) *Component {
+	for _, option := range options {
+		option(c)
+	}
+	if c.config.eredisComponent == nil {
+		c.logger.Panic("eredis component nil", elog.FieldKey("use WithEredis method"))
+	}
+	if len(c.config.Streams) == 0 {
+		c.logger.Panic(`invalid "streams" config, "streams" is empty`)
+	}
+	if c.config.Group == "" {
+		c.logger.Panic(`invalid "group" config, "group" is empty`)
+	}
+
+	return newComponent(c.name, c.config, c.config.eredisComponent, c.logger)
+}
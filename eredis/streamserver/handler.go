@@ -0,0 +1,11 @@
+package streamserver
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// OnEachMessageHandler 处理单条消息，返回 nil 时消息会被确认，返回错误时消息保留在待确认列表中，等待重新认领。
+// ctx 不会因服务停止而取消，GracefulStop 会等待处理完成
+type OnEachMessageHandler = func(ctx context.Context, stream string, message redis.XMessage) error
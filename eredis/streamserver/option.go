@@ -0,0 +1,47 @@
+package streamserver
+
+import (
+	"github.com/gotomicro/ego-component/eredis"
+)
+
+// WithEredis 设置 redis 组件
+func WithEredis(eredisComponent *eredis.Component) Option {
+	return func(c *Container) {
+		c.config.eredisComponent = eredisComponent
+	}
+}
+
+// WithStreams 设置消费的 stream 列表
+func WithStreams(streams ...string) Option {
+	return func(c *Container) {
+		c.config.Streams = streams
+	}
+}
+
+// WithGroup 设置消费组名称
+func WithGroup(group string) Option {
+	return func(c *Container) {
+		c.config.Group = group
+	}
+}
+
+// WithConsumer 设置消费者名称
+func WithConsumer(consumer string) Option {
+	return func(c *Container) {
+		c.config.Consumer = consumer
+	}
+}
+
+// WithMaxDeliveries 设置消息的最大投递次数
+func WithMaxDeliveries(maxDeliveries int64) Option {
+	return func(c *Container) {
+		c.config.MaxDeliveries = maxDeliveries
+	}
+}
+
+// WithDeadLetterStream 设置死信 stream
+func WithDeadLetterStream(stream string) Option {
+	return func(c *Container) {
+		c.config.DeadLetterStream = stream
+	}
+}